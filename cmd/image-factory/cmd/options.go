@@ -13,23 +13,80 @@ type Options struct { //nolint:govet
 
 	// Asset builder options: minimum supported Talos version.
 	MinTalosVersion string
+	// Asset builder options: maximum supported Talos version, leave empty for no limit.
+	MaxTalosVersion string
+	// Asset builder options: comma-separated list of allowed Talos versions, leave empty to allow any version.
+	AllowedTalosVersions string
 	// Image registry for source images: imager, extensions, etc..
 	ImageRegistry string
 	// Allow insecure connection to the image registry
 	InsecureImageRegistry bool
+	// Repository prefix (namespace) for source images in the image registry.
+	ImageRegistryRepositoryPrefix string
+	// Network to dial the image registry with: tcp, tcp4 or tcp6.
+	ImageRegistryDialNetwork string
+	// Connection pool settings for the image registry transport.
+	//
+	// Set to zero to use the defaults.
+	ImageRegistryMaxIdleConns        int
+	ImageRegistryMaxIdleConnsPerHost int
+	ImageRegistryMaxConnsPerHost     int
+	ImageRegistryIdleConnTimeout     time.Duration
+	// Proxy URL and the PEM-encoded CA bundle for the image registry requests redirected to another host
+	// (e.g. blob downloads redirected to cloud storage).
+	//
+	// Leave empty to use the proxy from the environment and the system CAs.
+	ImageRegistryRedirectProxy  string
+	ImageRegistryRedirectCAPath string
+	// Number of retries for image downloads interrupted by the image registry.
+	ImageRegistryFetchRetries int
+	// Duration to cache resolved image tags for, zero caches them until restart.
+	ImageRegistryDigestCacheTTL time.Duration
+	// Circuit breaker for the image registry: number of consecutive failures within the window
+	// to stop accessing the registry for the cooldown period.
+	//
+	// Set threshold to zero to disable.
+	ImageRegistryCircuitBreakerThreshold int
+	ImageRegistryCircuitBreakerWindow    time.Duration
+	ImageRegistryCircuitBreakerCooldown  time.Duration
 
 	// Options to verify container signatures for imager, extensions, etc.
 	ContainerSignatureSubjectRegExp string
 	ContainerSignatureIssuerRegExp  string
 	ContainerSignatureIssuer        string
+	// ContainerSignaturePolicy controls handling of signature verification errors: Enforce or WarnOnly.
+	//
+	// Leave empty to disable signature verification.
+	ContainerSignaturePolicy string
+
+	// RequireExtensionSignatures rejects extension images without a valid signature.
+	RequireExtensionSignatures bool
+	// ExtensionSignatureExceptions is a comma-separated list of registries allowed to serve unsigned extension images.
+	ExtensionSignatureExceptions string
+	// RequiredExtensions is a comma-separated list of official extensions every schematic must include.
+	RequiredExtensions string
+
+	// RegistryOverrides is a comma-separated list of registries which can be selected per request
+	// with the RegistryOverrideHeader instead of ImageRegistry.
+	RegistryOverrides string
+	// RegistryOverrideHeader is the HTTP request header which selects the registry override, empty disables the overrides.
+	RegistryOverrideHeader string
 
 	// Maximum number of concurrent asset builds.
 	AssetBuildMaxConcurrency int
 
 	// External URL of the image factory HTTP frontend.
+	//
+	// The path of the URL (if any) is used as a prefix of the links in the UI and image URLs,
+	// e.g. when the frontend is served by a reverse proxy under a path prefix.
 	ExternalURL string
 	// External URL of the image factory PXE frontend.
 	ExternalPXEURL string
+	// Public base URL of the links in the UI and image URLs, if it differs from ExternalURL,
+	// e.g. when a reverse proxy rewrites the path prefix.
+	//
+	// ExternalURL is still used as the registry host of the installer images.
+	PublicBaseURL string
 
 	// Schematic service OCI registry prefix.
 	// It stores schematics for the image factory as blobs under that path.
@@ -48,6 +105,66 @@ type Options struct { //nolint:govet
 	// TalosVersionRecheckInterval is the interval for rechecking Talos versions.
 	TalosVersionRecheckInterval time.Duration
 
+	// TalosVersionsSortAscending lists Talos versions oldest first (default is newest first).
+	TalosVersionsSortAscending bool
+
+	// TalosVersionsResolveLatestPatch expands partial Talos versions like "1.8" to the latest patch release.
+	TalosVersionsResolveLatestPatch bool
+
+	// ImagerPullPolicy controls re-pulling of the imager image: IfNotPresent or Always.
+	ImagerPullPolicy string
+
+	// ImagerValidateOutputs enables validation of extracted artifacts against the outputs declared by the imager.
+	ImagerValidateOutputs bool
+
+	// ImagerFollowSymlinks enables extraction of symlinks (pointing within the imager output) from the imager image.
+	ImagerFollowSymlinks bool
+
+	// ArtifactsEvictOnDiskFull evicts other extracted Talos versions when the artifacts storage runs out of space.
+	ArtifactsEvictOnDiskFull bool
+
+	// ArtifactsOnIncompleteExtraction controls the handling of incomplete extractions: Retry or Fail.
+	ArtifactsOnIncompleteExtraction string
+
+	// ArtifactsMinFreeDiskBytes is the free space to keep on the artifacts storage before fetching images.
+	//
+	// Set to zero to disable.
+	ArtifactsMinFreeDiskBytes int64
+
+	// ArtifactsServedRootFrom and ArtifactsServedRootTo rewrite the prefix of the artifact paths handed
+	// to an external server, e.g. when the storage is mounted into a web server at another path.
+	//
+	// Leave empty to disable.
+	ArtifactsServedRootFrom string
+	ArtifactsServedRootTo   string
+
+	// ArtifactsIntegrityScanInterval is the interval for verifying extracted Talos artifacts.
+	//
+	// Set to zero to disable.
+	ArtifactsIntegrityScanInterval time.Duration
+
+	// ArtifactsPersistentCacheDir is the directory to keep extracted Talos artifacts in across restarts.
+	//
+	// Leave empty to use a temporary directory.
+	ArtifactsPersistentCacheDir string
+
+	// ArtifactsEvictionGracePeriod is the maximum time to keep evicted Talos artifacts while they are being downloaded.
+	ArtifactsEvictionGracePeriod time.Duration
+
+	// ArtifactsRetainPatchesPerMinor is the number of the newest extracted patch releases to keep per Talos minor line.
+	ArtifactsRetainPatchesPerMinor int
+
+	// ArtifactsPrefetchLatestPatch enables background fetching of the latest patch release when an older one is requested.
+	ArtifactsPrefetchLatestPatch bool
+
+	// ArtifactsStreamUncached streams the artifacts of the Talos versions which are not extracted yet, without storing them.
+	ArtifactsStreamUncached bool
+
+	// ArtifactsPrefetchNewVersions enables background fetching of the Talos versions as soon as they are released,
+	// with at most ArtifactsPrefetchNewVersionsConcurrency versions fetched at a time.
+	ArtifactsPrefetchNewVersions            bool
+	ArtifactsPrefetchNewVersionsConcurrency int
+
 	// CacheSigningKeyPath is the path to the signing key for the cache.
 	//
 	// Best choice is to use ECDSA key.
@@ -87,8 +204,17 @@ type SecureBootOptions struct { //nolint:govet
 var DefaultOptions = Options{
 	HTTPListenAddr: ":8080",
 
-	MinTalosVersion: "1.2.0",
-	ImageRegistry:   "ghcr.io",
+	MinTalosVersion:          "1.2.0",
+	ImageRegistry:            "ghcr.io",
+	ImageRegistryDialNetwork: "tcp",
+
+	ImageRegistryMaxIdleConns:        100,
+	ImageRegistryMaxIdleConnsPerHost: 50,
+	ImageRegistryIdleConnTimeout:     90 * time.Second,
+	ImageRegistryFetchRetries:        3,
+
+	ImageRegistryCircuitBreakerWindow:   time.Minute,
+	ImageRegistryCircuitBreakerCooldown: 30 * time.Second,
 
 	ContainerSignatureSubjectRegExp: `@siderolabs\.com$`,
 	ContainerSignatureIssuerRegExp:  "",
@@ -105,6 +231,12 @@ var DefaultOptions = Options{
 
 	TalosVersionRecheckInterval: 15 * time.Minute,
 
+	ImagerPullPolicy: "IfNotPresent",
+
+	ArtifactsEvictionGracePeriod:            5 * time.Minute,
+	ArtifactsOnIncompleteExtraction:         "Retry",
+	ArtifactsPrefetchNewVersionsConcurrency: 1,
+
 	CacheRepository: "ghcr.io/siderolabs/image-factory/cache",
 
 	MetricsListenAddr: ":2122",
@@ -8,6 +8,7 @@ package cmd
 import (
 	"context"
 	"crypto"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
@@ -80,11 +81,20 @@ func RunFactory(ctx context.Context, logger *zap.Logger, opts Options) error {
 		return fmt.Errorf("failed to parse self URL: %w", err)
 	}
 
+	if opts.PublicBaseURL != "" {
+		frontendOptions.PublicBaseURL, err = url.Parse(opts.PublicBaseURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse public base URL: %w", err)
+		}
+	}
+
 	if opts.ExternalPXEURL != "" {
 		frontendOptions.ExternalPXEURL, err = url.Parse(opts.ExternalPXEURL)
 		if err != nil {
 			return fmt.Errorf("failed to parse self PXE URL: %w", err)
 		}
+	} else if frontendOptions.PublicBaseURL != nil {
+		frontendOptions.ExternalPXEURL = frontendOptions.PublicBaseURL
 	} else {
 		frontendOptions.ExternalPXEURL = frontendOptions.ExternalURL
 	}
@@ -106,6 +116,7 @@ func RunFactory(ctx context.Context, logger *zap.Logger, opts Options) error {
 	}
 
 	frontendOptions.RemoteOptions = append(frontendOptions.RemoteOptions, remoteOptions()...)
+	frontendOptions.RegistryOverrideHeader = opts.RegistryOverrideHeader
 
 	frontendHTTP, err := frontendhttp.NewFrontend(logger, configFactory, assetBuilder, artifactsManager, secureBootService, frontendOptions)
 	if err != nil {
@@ -203,6 +214,15 @@ func buildArtifactsManager(ctx context.Context, logger *zap.Logger, opts Options
 		return nil, fmt.Errorf("failed to parse minimum Talos version: %w", err)
 	}
 
+	var maxVersion semver.Version
+
+	if opts.MaxTalosVersion != "" {
+		maxVersion, err = semver.Parse(opts.MaxTalosVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse maximum Talos version: %w", err)
+		}
+	}
+
 	// Prefer opts.ContainerSignatureIssuerRegExp if set as this is more flexible
 	cosignIdentities := []cosign.Identity{
 		{
@@ -215,10 +235,71 @@ func buildArtifactsManager(ctx context.Context, logger *zap.Logger, opts Options
 		cosignIdentities[0].Issuer = opts.ContainerSignatureIssuer
 	}
 
+	var versionResolver func(string, []semver.Version) (semver.Version, bool)
+
+	if opts.TalosVersionsResolveLatestPatch {
+		versionResolver = artifacts.ResolveLatestPatch
+	}
+
+	var allowedVersions []string
+
+	if opts.AllowedTalosVersions != "" {
+		allowedVersions = strings.Split(opts.AllowedTalosVersions, ",")
+	}
+
+	var extensionSignatureExceptions []string
+
+	if opts.ExtensionSignatureExceptions != "" {
+		extensionSignatureExceptions = strings.Split(opts.ExtensionSignatureExceptions, ",")
+	}
+
+	var requiredExtensions []string
+
+	if opts.RequiredExtensions != "" {
+		requiredExtensions = strings.Split(opts.RequiredExtensions, ",")
+	}
+
+	var registryOverrides []string
+
+	if opts.RegistryOverrides != "" {
+		registryOverrides = strings.Split(opts.RegistryOverrides, ",")
+	}
+
+	redirectRootCAs, err := loadCertPool(opts.ImageRegistryRedirectCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image registry redirect CAs: %w", err)
+	}
+
+	var prefetchNewVersions *artifacts.PrefetchSelector
+
+	if opts.ArtifactsPrefetchNewVersions {
+		prefetchNewVersions = &artifacts.PrefetchSelector{
+			Concurrency: opts.ArtifactsPrefetchNewVersionsConcurrency,
+		}
+	}
+
 	artifactsManager, err := artifacts.NewManager(logger, artifacts.Options{
 		MinVersion:            minVersion,
+		MaxVersion:            maxVersion,
+		AllowedVersions:       allowedVersions,
 		ImageRegistry:         opts.ImageRegistry,
 		InsecureImageRegistry: opts.InsecureImageRegistry,
+		RepositoryPrefix:      opts.ImageRegistryRepositoryPrefix,
+		DialNetwork:           opts.ImageRegistryDialNetwork,
+
+		TransportMaxIdleConns:        opts.ImageRegistryMaxIdleConns,
+		TransportMaxIdleConnsPerHost: opts.ImageRegistryMaxIdleConnsPerHost,
+		TransportMaxConnsPerHost:     opts.ImageRegistryMaxConnsPerHost,
+		TransportIdleConnTimeout:     opts.ImageRegistryIdleConnTimeout,
+		RedirectProxyURL:             opts.ImageRegistryRedirectProxy,
+		RedirectRootCAs:              redirectRootCAs,
+		FetchRetries:                 opts.ImageRegistryFetchRetries,
+		DigestCacheTTL:               opts.ImageRegistryDigestCacheTTL,
+
+		CircuitBreakerThreshold: opts.ImageRegistryCircuitBreakerThreshold,
+		CircuitBreakerWindow:    opts.ImageRegistryCircuitBreakerWindow,
+		CircuitBreakerCooldown:  opts.ImageRegistryCircuitBreakerCooldown,
+
 		ImageVerifyOptions: cosign.CheckOpts{
 			Identities:        cosignIdentities,
 			RootCerts:         rootCerts,
@@ -226,13 +307,36 @@ func buildArtifactsManager(ctx context.Context, logger *zap.Logger, opts Options
 			RekorPubKeys:      rekorPubKeys,
 			CTLogPubKeys:      ctLogPubKeys,
 		},
-		TalosVersionRecheckInterval: opts.TalosVersionRecheckInterval,
-		RemoteOptions:               remoteOptions(),
+		SignaturePolicy:              artifacts.SignaturePolicy(opts.ContainerSignaturePolicy),
+		RequireExtensionSignatures:   opts.RequireExtensionSignatures,
+		ExtensionSignatureExceptions: extensionSignatureExceptions,
+		RequiredExtensions:           requiredExtensions,
+		RegistryOverrides:            registryOverrides,
+		TalosVersionRecheckInterval:  opts.TalosVersionRecheckInterval,
+		VersionSortAscending:         opts.TalosVersionsSortAscending,
+		VersionResolver:              versionResolver,
+		IntegrityScanInterval:        opts.ArtifactsIntegrityScanInterval,
+		EvictionGracePeriod:          opts.ArtifactsEvictionGracePeriod,
+		RetainPatchesPerMinor:        opts.ArtifactsRetainPatchesPerMinor,
+		PersistentCacheDir:           opts.ArtifactsPersistentCacheDir,
+		PrefetchLatestPatch:          opts.ArtifactsPrefetchLatestPatch,
+		StreamUncachedArtifacts:      opts.ArtifactsStreamUncached,
+		PrefetchNewVersions:          prefetchNewVersions,
+		ImagerPullPolicy:             artifacts.PullPolicy(opts.ImagerPullPolicy),
+		ValidateImagerOutputs:        opts.ImagerValidateOutputs,
+		FollowSymlinks:               opts.ImagerFollowSymlinks,
+		EvictOnDiskFull:              opts.ArtifactsEvictOnDiskFull,
+		OnIncompleteExtraction:       artifacts.IncompleteExtractionMode(opts.ArtifactsOnIncompleteExtraction),
+		MinFreeDiskBytes:             opts.ArtifactsMinFreeDiskBytes,
+		ServedRootRewrite:            artifacts.PathRewrite{From: opts.ArtifactsServedRootFrom, To: opts.ArtifactsServedRootTo},
+		RemoteOptions:                remoteOptions(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize artifacts manager: %w", err)
 	}
 
+	prometheus.MustRegister(artifactsManager)
+
 	return artifactsManager, nil
 }
 
@@ -305,6 +409,26 @@ func remoteOptions() []remote.Option {
 	}
 }
 
+// loadCertPool loads the PEM-encoded CA bundle, nil pool is returned if the path is empty.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(fileBytes) {
+		return nil, fmt.Errorf("no certificates found in %q", path)
+	}
+
+	return pool, nil
+}
+
 func loadPrivateKey(keyPath string) (crypto.PrivateKey, error) {
 	fileBytes, err := os.ReadFile(keyPath)
 	if err != nil {
@@ -17,17 +17,114 @@ func initFlags() cmd.Options {
 	flag.StringVar(&opts.HTTPListenAddr, "http-port", cmd.DefaultOptions.HTTPListenAddr, "HTTP listen address")
 
 	flag.StringVar(&opts.MinTalosVersion, "min-talos-version", cmd.DefaultOptions.MinTalosVersion, "minimum Talos version")
+	flag.StringVar(&opts.MaxTalosVersion, "max-talos-version", cmd.DefaultOptions.MaxTalosVersion, "maximum Talos version (empty for no limit)")
+	flag.StringVar(&opts.AllowedTalosVersions, "allowed-talos-versions", cmd.DefaultOptions.AllowedTalosVersions, "comma-separated list of allowed Talos versions (empty to allow any version)")
 	flag.StringVar(&opts.ImageRegistry, "image-registry", cmd.DefaultOptions.ImageRegistry, "image registry for imager, extensions, etc.")
 	flag.BoolVar(&opts.InsecureImageRegistry, "insecure-image-registry", cmd.DefaultOptions.InsecureImageRegistry, "allow an insecure connection to the image registry")
+	flag.StringVar(
+		&opts.ImageRegistryRepositoryPrefix,
+		"image-registry-repository-prefix",
+		cmd.DefaultOptions.ImageRegistryRepositoryPrefix,
+		"repository prefix (namespace) for imager, extensions, etc. in the image registry",
+	)
+	flag.IntVar(
+		&opts.ImageRegistryCircuitBreakerThreshold,
+		"image-registry-circuit-breaker-threshold",
+		cmd.DefaultOptions.ImageRegistryCircuitBreakerThreshold,
+		"number of consecutive image registry failures to open the circuit breaker (set to zero to disable)",
+	)
+	flag.DurationVar(
+		&opts.ImageRegistryCircuitBreakerWindow,
+		"image-registry-circuit-breaker-window",
+		cmd.DefaultOptions.ImageRegistryCircuitBreakerWindow,
+		"window to count consecutive image registry failures in",
+	)
+	flag.DurationVar(
+		&opts.ImageRegistryCircuitBreakerCooldown,
+		"image-registry-circuit-breaker-cooldown",
+		cmd.DefaultOptions.ImageRegistryCircuitBreakerCooldown,
+		"duration to keep the image registry circuit breaker open before probing the registry again",
+	)
+	flag.StringVar(&opts.ImageRegistryDialNetwork, "image-registry-dial-network", cmd.DefaultOptions.ImageRegistryDialNetwork, "network to dial the image registry with (tcp, tcp4 or tcp6)")
+	flag.StringVar(
+		&opts.ImageRegistryRedirectProxy,
+		"image-registry-redirect-proxy",
+		cmd.DefaultOptions.ImageRegistryRedirectProxy,
+		"proxy URL for the image registry requests redirected to another host, e.g. cloud storage (defaults to the proxy from the environment)",
+	)
+	flag.StringVar(
+		&opts.ImageRegistryRedirectCAPath,
+		"image-registry-redirect-ca-path",
+		cmd.DefaultOptions.ImageRegistryRedirectCAPath,
+		"path to the PEM-encoded CA bundle for the image registry requests redirected to another host (defaults to the system CAs)",
+	)
+	flag.IntVar(&opts.ImageRegistryMaxIdleConns, "image-registry-max-idle-conns", cmd.DefaultOptions.ImageRegistryMaxIdleConns, "maximum number of idle connections to the image registry")
+	flag.IntVar(
+		&opts.ImageRegistryMaxIdleConnsPerHost,
+		"image-registry-max-idle-conns-per-host",
+		cmd.DefaultOptions.ImageRegistryMaxIdleConnsPerHost,
+		"maximum number of idle connections to the image registry per host",
+	)
+	flag.IntVar(
+		&opts.ImageRegistryMaxConnsPerHost,
+		"image-registry-max-conns-per-host",
+		cmd.DefaultOptions.ImageRegistryMaxConnsPerHost,
+		"maximum number of connections to the image registry per host (set to zero for no limit)",
+	)
+	flag.DurationVar(
+		&opts.ImageRegistryIdleConnTimeout,
+		"image-registry-idle-conn-timeout",
+		cmd.DefaultOptions.ImageRegistryIdleConnTimeout,
+		"duration to keep idle connections to the image registry open",
+	)
+	flag.IntVar(&opts.ImageRegistryFetchRetries, "image-registry-fetch-retries", cmd.DefaultOptions.ImageRegistryFetchRetries, "number of retries for interrupted image downloads")
+	flag.DurationVar(
+		&opts.ImageRegistryDigestCacheTTL,
+		"image-registry-digest-cache-ttl",
+		cmd.DefaultOptions.ImageRegistryDigestCacheTTL,
+		"duration to cache resolved image tags for (set to zero to cache until restart)",
+	)
 
 	flag.StringVar(&opts.ContainerSignatureSubjectRegExp, "container-signature-subject-regexp", cmd.DefaultOptions.ContainerSignatureSubjectRegExp, "container signature subject regexp")
 	flag.StringVar(&opts.ContainerSignatureIssuerRegExp, "container-signature-issuer-regexp", cmd.DefaultOptions.ContainerSignatureIssuerRegExp, "container signature issuer regexp")
 	flag.StringVar(&opts.ContainerSignatureIssuer, "container-signature-issuer", cmd.DefaultOptions.ContainerSignatureIssuer, "container signature issuer")
+	flag.StringVar(
+		&opts.ContainerSignaturePolicy,
+		"container-signature-policy",
+		cmd.DefaultOptions.ContainerSignaturePolicy,
+		"container signature verification policy (Enforce or WarnOnly, empty to disable verification)",
+	)
+	flag.BoolVar(&opts.RequireExtensionSignatures, "require-extension-signatures", cmd.DefaultOptions.RequireExtensionSignatures, "reject extension images without a valid signature")
+	flag.StringVar(
+		&opts.ExtensionSignatureExceptions,
+		"extension-signature-exceptions",
+		cmd.DefaultOptions.ExtensionSignatureExceptions,
+		"comma-separated list of registries allowed to serve unsigned extension images with --require-extension-signatures",
+	)
+	flag.StringVar(
+		&opts.RequiredExtensions,
+		"required-extensions",
+		cmd.DefaultOptions.RequiredExtensions,
+		"comma-separated list of official extensions every schematic must include (e.g. siderolabs/gvisor)",
+	)
+	flag.StringVar(
+		&opts.RegistryOverrides,
+		"registry-overrides",
+		cmd.DefaultOptions.RegistryOverrides,
+		"comma-separated list of registries which can be selected per request with --registry-override-header",
+	)
+	flag.StringVar(
+		&opts.RegistryOverrideHeader,
+		"registry-override-header",
+		cmd.DefaultOptions.RegistryOverrideHeader,
+		"HTTP request header which selects the registry to pull the source images from (empty disables the overrides)",
+	)
 
 	flag.IntVar(&opts.AssetBuildMaxConcurrency, "asset-builder-max-concurrency", cmd.DefaultOptions.AssetBuildMaxConcurrency, "maximum concurrency for asset builder")
 
 	flag.StringVar(&opts.ExternalURL, "external-url", cmd.DefaultOptions.ExternalURL, "factory external endpoint URL")
-	flag.StringVar(&opts.ExternalPXEURL, "external-pxe-url", cmd.DefaultOptions.ExternalPXEURL, "factory external PXE endpoint URL, if not set defaults to --external-url")
+	flag.StringVar(&opts.ExternalPXEURL, "external-pxe-url", cmd.DefaultOptions.ExternalPXEURL, "factory external PXE endpoint URL, if not set defaults to --public-base-url")
+	flag.StringVar(&opts.PublicBaseURL, "public-base-url", cmd.DefaultOptions.PublicBaseURL, "base URL of the links in the UI and image URLs, if not set defaults to --external-url")
 
 	flag.StringVar(&opts.SchematicServiceRepository, "schematic-service-repository", cmd.DefaultOptions.SchematicServiceRepository, "image repository for the schematic service")
 	flag.BoolVar(
@@ -47,6 +144,89 @@ func initFlags() cmd.Options {
 	)
 
 	flag.DurationVar(&opts.TalosVersionRecheckInterval, "talos-versions-recheck-interval", cmd.DefaultOptions.TalosVersionRecheckInterval, "interval to recheck Talos versions")
+	flag.BoolVar(&opts.TalosVersionsSortAscending, "talos-versions-sort-ascending", cmd.DefaultOptions.TalosVersionsSortAscending, "list Talos versions oldest first")
+	flag.BoolVar(
+		&opts.TalosVersionsResolveLatestPatch,
+		"talos-versions-resolve-latest-patch",
+		cmd.DefaultOptions.TalosVersionsResolveLatestPatch,
+		`resolve partial Talos versions like "1.8" or "1.8.x" to the latest patch release`,
+	)
+	flag.StringVar(&opts.ImagerPullPolicy, "imager-pull-policy", cmd.DefaultOptions.ImagerPullPolicy, "imager image pull policy (IfNotPresent or Always)")
+	flag.BoolVar(&opts.ImagerValidateOutputs, "imager-validate-outputs", cmd.DefaultOptions.ImagerValidateOutputs, "validate extracted artifacts against the outputs declared by the imager")
+	flag.BoolVar(&opts.ImagerFollowSymlinks, "imager-follow-symlinks", cmd.DefaultOptions.ImagerFollowSymlinks, "extract symlinks from the imager image (symlinks pointing outside are rejected)")
+	flag.BoolVar(&opts.ArtifactsEvictOnDiskFull, "artifacts-evict-on-disk-full", cmd.DefaultOptions.ArtifactsEvictOnDiskFull, "evict other extracted Talos versions and retry when the artifacts storage runs out of space")
+	flag.StringVar(
+		&opts.ArtifactsOnIncompleteExtraction,
+		"artifacts-on-incomplete-extraction",
+		cmd.DefaultOptions.ArtifactsOnIncompleteExtraction,
+		"handling of incomplete extractions of Talos versions (Retry or Fail)",
+	)
+	flag.Int64Var(
+		&opts.ArtifactsMinFreeDiskBytes,
+		"artifacts-min-free-disk-bytes",
+		cmd.DefaultOptions.ArtifactsMinFreeDiskBytes,
+		"free space to keep on the artifacts storage, other extracted Talos versions are evicted before fetching if below (0 to disable)",
+	)
+	flag.StringVar(
+		&opts.ArtifactsServedRootFrom,
+		"artifacts-served-root-from",
+		cmd.DefaultOptions.ArtifactsServedRootFrom,
+		"path prefix of the artifacts storage to rewrite in the artifact paths handed to an external server (empty to disable)",
+	)
+	flag.StringVar(
+		&opts.ArtifactsServedRootTo,
+		"artifacts-served-root-to",
+		cmd.DefaultOptions.ArtifactsServedRootTo,
+		"path prefix to rewrite --artifacts-served-root-from to, e.g. the storage mount path in the web server",
+	)
+	flag.DurationVar(
+		&opts.ArtifactsIntegrityScanInterval,
+		"artifacts-integrity-scan-interval",
+		cmd.DefaultOptions.ArtifactsIntegrityScanInterval,
+		"interval to verify extracted Talos artifacts against their checksums (set to zero to disable)",
+	)
+	flag.StringVar(
+		&opts.ArtifactsPersistentCacheDir,
+		"artifacts-persistent-cache-dir",
+		cmd.DefaultOptions.ArtifactsPersistentCacheDir,
+		"directory to keep extracted Talos artifacts in across restarts (empty to use a temporary directory)",
+	)
+	flag.DurationVar(
+		&opts.ArtifactsEvictionGracePeriod,
+		"artifacts-eviction-grace-period",
+		cmd.DefaultOptions.ArtifactsEvictionGracePeriod,
+		"maximum duration to keep evicted Talos artifacts while they are being downloaded",
+	)
+	flag.IntVar(
+		&opts.ArtifactsRetainPatchesPerMinor,
+		"artifacts-retain-patches-per-minor",
+		cmd.DefaultOptions.ArtifactsRetainPatchesPerMinor,
+		"number of the newest extracted patch releases to keep per Talos minor line (0 to keep all)",
+	)
+	flag.BoolVar(
+		&opts.ArtifactsPrefetchLatestPatch,
+		"artifacts-prefetch-latest-patch",
+		cmd.DefaultOptions.ArtifactsPrefetchLatestPatch,
+		"fetch the latest patch release of the Talos minor line in the background when an older one is requested",
+	)
+	flag.BoolVar(
+		&opts.ArtifactsStreamUncached,
+		"artifacts-stream-uncached",
+		cmd.DefaultOptions.ArtifactsStreamUncached,
+		"stream the artifacts of the Talos versions which are not extracted yet from the imager image, without storing them",
+	)
+	flag.BoolVar(
+		&opts.ArtifactsPrefetchNewVersions,
+		"artifacts-prefetch-new-versions",
+		cmd.DefaultOptions.ArtifactsPrefetchNewVersions,
+		"fetch the new Talos versions in the background as soon as they appear on the version refresh",
+	)
+	flag.IntVar(
+		&opts.ArtifactsPrefetchNewVersionsConcurrency,
+		"artifacts-prefetch-new-versions-concurrency",
+		cmd.DefaultOptions.ArtifactsPrefetchNewVersionsConcurrency,
+		"maximum number of the new Talos versions fetched in the background at a time",
+	)
 
 	flag.StringVar(&opts.CacheSigningKeyPath, "cache-signing-key-path", cmd.DefaultOptions.CacheSigningKeyPath, "path to the default cache signing key (PEM-encoded, ECDSA private key)")
 
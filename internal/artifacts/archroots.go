@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// prepareArchRoots creates the per-architecture storage roots from Options.StoragePathPerArch.
+//
+// Persistent roots are used as is, otherwise a temporary directory is created under each root.
+func prepareArchRoots(logger *zap.Logger, options Options) (map[Arch]string, error) {
+	if len(options.StoragePathPerArch) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	roots := make(map[Arch]string, len(options.StoragePathPerArch))
+
+	for arch, path := range options.StoragePathPerArch {
+		if err := validateArch(arch); err != nil {
+			return nil, fmt.Errorf("invalid storage path: %w", err)
+		}
+
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage path for %s: %w", arch, err)
+		}
+
+		if err = os.MkdirAll(path, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create storage path for %s: %w", arch, err)
+		}
+
+		if options.PersistentCacheDir != "" {
+			removed, err := removeStaging(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to clean up storage path for %s: %w", arch, err)
+			}
+
+			if removed > 0 {
+				logger.Info("removed leftovers of interrupted fetches from storage path", zap.String("path", path), zap.Int("count", removed))
+			}
+
+			roots[arch] = path
+
+			continue
+		}
+
+		roots[arch], err = os.MkdirTemp(path, "image-factory")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory for %s: %w", arch, err)
+		}
+	}
+
+	return roots, nil
+}
+
+// linkArchDestinations links the architecture directories extracted to their own roots into the staging directory.
+func linkArchDestinations(staging string, archDestinations map[Arch]string) error {
+	for arch, destination := range archDestinations {
+		target := filepath.Join(destination, string(arch))
+
+		if _, err := os.Stat(target); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return err
+		}
+
+		if err := os.MkdirAll(staging, 0o755); err != nil {
+			return err
+		}
+
+		if err := os.Symlink(target, filepath.Join(staging, string(arch))); err != nil {
+			return fmt.Errorf("error linking %s storage path: %w", arch, err)
+		}
+	}
+
+	return nil
+}
+
+// finalizeArchRoot moves the staged architecture directory in place, and re-links it into the Talos version directory.
+func finalizeArchRoot(destinationPath, archRootTagPath string, arch Arch) error {
+	if _, err := os.Stat(archRootTagPath + tmpSuffix); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := os.Rename(archRootTagPath+tmpSuffix, archRootTagPath); err != nil {
+		return err
+	}
+
+	link := filepath.Join(destinationPath, string(arch))
+
+	if err := os.Remove(link); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Symlink(filepath.Join(archRootTagPath, string(arch)), link); err != nil {
+		return fmt.Errorf("error linking %s storage path: %w", arch, err)
+	}
+
+	return nil
+}
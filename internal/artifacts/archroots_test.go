@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestStoragePathPerArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	amd64Root := t.TempDir()
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		StoragePathPerArch:    map[artifacts.Arch]string{artifacts.ArchAmd64: amd64Root},
+		RetainPatchesPerMinor: 1,
+	})
+
+	for _, tag := range []string{"v1.7.0", "v1.7.1"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("amd64 kernel " + tag),
+			"usr/install/arm64/vmlinuz": []byte("arm64 kernel " + tag),
+		})
+	}
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	realPath, err := filepath.EvalSymlinks(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(realPath, amd64Root+string(filepath.Separator)), realPath)
+
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	contents, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "amd64 kernel v1.7.0", string(contents))
+
+	path, err = manager.Get(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	realPath, err = filepath.EvalSymlinks(path)
+	require.NoError(t, err)
+	assert.False(t, strings.HasPrefix(realPath, amd64Root+string(filepath.Separator)), realPath)
+
+	// extracting the next patch evicts v1.7.0 from all the storage roots
+	_, err = manager.Get(ctx, "1.7.1", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	matches, err := filepath.Glob(filepath.Join(amd64Root, "*", "v1.7.0"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	matches, err = filepath.Glob(filepath.Join(amd64Root, "*", "v1.7.1", "amd64"))
+	require.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
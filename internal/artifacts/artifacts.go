@@ -6,9 +6,13 @@
 package artifacts
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 )
@@ -21,16 +25,298 @@ type Options struct { //nolint:govet
 	ImageRegistry string
 	// Option to allow using an image registry without TLS.
 	InsecureImageRegistry bool
+	// RepositoryPrefix is prepended to the repositories of imager, installer, extensions, etc.
+	//
+	// For example, with prefix "mygroup", the imager is pulled from "<registry>/mygroup/siderolabs/imager".
+	RepositoryPrefix string
 	// MinVersion is the minimum version of Talos to use.
 	MinVersion semver.Version
+	// MaxVersion is the maximum version of Talos to use.
+	//
+	// Zero value means no limit.
+	MaxVersion semver.Version
+	// AllowedVersions restricts the Talos versions to the explicit list, on top of MinVersion and MaxVersion.
+	//
+	// Other versions are rejected with ErrVersionUnsupported. Empty list allows any version.
+	AllowedVersions []string
+	// TagParser maps the imager image tags to Talos versions.
+	//
+	// Tags rejected by the parser are skipped. If not set, tags are parsed as (tolerant) semantic versions.
+	TagParser func(tag string) (semver.Version, bool)
+	// VersionResolver expands the user-supplied Talos version (e.g. "1.8") against the available versions.
+	//
+	// If the resolver returns false, the version is parsed as is. See ResolveLatestPatch.
+	VersionResolver func(versionString string, available []semver.Version) (semver.Version, bool)
 	// ImageVerifyOptions are the options for verifying the image signature.
 	ImageVerifyOptions cosign.CheckOpts
 	// TalosVersionRecheckInterval is the interval for rechecking Talos versions.
 	TalosVersionRecheckInterval time.Duration
+	// VersionSortAscending sorts the Talos versions oldest first.
+	//
+	// By default, the versions are sorted newest first.
+	VersionSortAscending bool
+	// PrefetchLatestPatch enables fetching the latest patch release of the minor line in the background
+	// when an older patch release is requested with Get.
+	PrefetchLatestPatch bool
+	// StreamUncachedArtifacts makes Manager.StreamArtifact stream the artifacts of the Talos versions
+	// which are not extracted yet from the imager image, without storing them.
+	//
+	// By default, the Talos version is extracted as with Manager.Get. The artifacts are never streamed with
+	// PostExtractHook or UpstreamCache, as the streamed artifacts would skip them.
+	StreamUncachedArtifacts bool
+	// PrefetchNewVersions enables fetching the artifacts of the Talos versions in the background
+	// as soon as they appear on the refresh of the Talos versions.
+	//
+	// The versions available on the first listing are not prefetched. Prefetching is best-effort,
+	// failures are only logged.
+	PrefetchNewVersions *PrefetchSelector
+	// KindFilenames overrides the file names of the artifacts returned by Get, e.g. "vmlinuz-amd64" for KindKernel.
+	//
+	// The artifacts are linked under the configured names next to the extracted files, so the names must be unique,
+	// and must not be the name of another built-in artifact kind.
+	KindFilenames map[Kind]string
+	// KindCompression compresses the artifacts of the kind returned by Get, e.g. KindKernel with CompressionGzip.
+	//
+	// The compressed copy is stored next to the extracted artifact. Compression of the artifacts which are
+	// already compressed by the imager (KindInitramfs is xz-compressed) can't be changed.
+	KindCompression map[Kind]CompressionScheme
+	// FaultInjector is consulted before each registry operation (see RegistryOpHead, etc.),
+	// and the operation fails with the returned error, if any.
+	//
+	// For testing only: it is not exposed as a flag, and must not be set in production.
+	FaultInjector func(op string) error
+	// SupportPolicy reports the support status of Talos versions, see VersionSupportStatus.
+	SupportPolicy SupportPolicy
 	// RemoteOptions is the list of remote options for the puller.
 	RemoteOptions []remote.Option
+	// UseDockerConfig enables registry authentication with the credentials from the Docker config
+	// (~/.docker/config.json, or $DOCKER_CONFIG/config.json), as set up by 'docker login'.
+	//
+	// Authentication set in RemoteOptions takes precedence.
+	UseDockerConfig bool
+	// Auth is the registry authentication for all images, unless overridden for the purpose below.
+	//
+	// Credentials from the Docker config (UseDockerConfig) are only used for the images without credentials set.
+	Auth authn.Authenticator
+	// ImagerAuth is the registry authentication for the imager image.
+	ImagerAuth authn.Authenticator
+	// InstallerAuth is the registry authentication for the installer images.
+	InstallerAuth authn.Authenticator
+	// ExtensionsAuth is the registry authentication for the extension and overlay images, and their manifests.
+	ExtensionsAuth authn.Authenticator
+	// DialNetwork is the network used to connect to the image registry: "tcp", "tcp4" or "tcp6".
+	//
+	// Defaults to "tcp" (both IPv4 and IPv6).
+	DialNetwork string
+	// Connection pool settings of the registry transport.
+	//
+	// Zero values keep the defaults tuned for pulling from a small number of registry hosts:
+	// 100 idle connections, 50 idle connections per host, no limit on connections per host, 90s idle timeout.
+	TransportMaxIdleConns        int
+	TransportMaxIdleConnsPerHost int
+	TransportMaxConnsPerHost     int
+	TransportIdleConnTimeout     time.Duration
+	// RedirectProxyURL is the proxy for the requests redirected away from the registry host,
+	// e.g. blob downloads redirected to S3 or GCS.
+	//
+	// Defaults to the proxy from the environment, as for the registry requests.
+	RedirectProxyURL string
+	// RedirectRootCAs are the CAs trusted for the requests redirected away from the registry host.
+	//
+	// Defaults to the system CAs.
+	RedirectRootCAs *x509.CertPool
+	// IntegrityScanInterval is the interval for verifying extracted artifacts against their checksums.
+	//
+	// Corrupted artifacts are removed and fetched again. Zero disables the scanner.
+	IntegrityScanInterval time.Duration
+	// PersistentCacheDir is the directory to keep the artifacts in across restarts.
+	//
+	// If the directory was written with an incompatible storage layout, its contents are removed.
+	// Defaults to a temporary directory which is removed on Close.
+	PersistentCacheDir string
+	// StoragePathPerArch extracts the Talos version artifacts of the architecture to its own storage root.
+	//
+	// Architectures not listed are extracted to the main storage path.
+	StoragePathPerArch map[Arch]string
+	// UpstreamCache is a shared cache tier consulted for the imager and extension images before pulling their layers
+	// from the registry, and populated on miss.
+	//
+	// The registry is still consulted for the image manifests and signatures. The extension layouts are verified
+	// against the image digest, but the upstream cache must be trusted, as the imager contents are not verified.
+	UpstreamCache UpstreamCache
+	// PostExtractHook transforms the artifacts in place after the imager output is extracted, before they are served,
+	// e.g. to re-sign the UKI.
+	//
+	// The hook is called once per extracted artifact, and the transformed artifacts are cached as extracted.
+	// A hook error fails the extraction.
+	PostExtractHook func(ctx context.Context, tag string, arch Arch, kind Kind, path string) error
+	// MinFreeDiskBytes is the free space to keep on the storage filesystem, and on the filesystems of StoragePathPerArch.
+	//
+	// Before fetching the imager and extension images, other extracted Talos versions are evicted (oldest first)
+	// if the free space is below the threshold, and the fetch fails with ErrInsufficientDiskSpace if it's still not enough.
+	// Zero disables the check.
+	MinFreeDiskBytes int64
+	// EvictionGracePeriod is the maximum time to keep evicted artifacts while they are open with Manager.Open
+	// or held with Manager.Acquire.
+	//
+	// Zero removes evicted artifacts immediately.
+	EvictionGracePeriod time.Duration
+	// RetainPatchesPerMinor keeps only the newest patches of each Talos minor release extracted.
+	//
+	// Older patches are evicted once a new Talos version is extracted, unless they are being fetched or read.
+	// Zero disables the retention.
+	RetainPatchesPerMinor int
+	// InMemoryThreshold is the maximum size of the artifact which is kept in memory after the first Manager.Open.
+	//
+	// Only Manager.Open serves from memory, the paths returned by Manager.Get are always read from disk.
+	// Zero disables the in-memory cache.
+	InMemoryThreshold int64
+	// InMemoryCacheSize is the maximum total size of the artifacts kept in memory.
+	//
+	// Least recently used artifacts are dropped first. Defaults to 64 MiB.
+	InMemoryCacheSize int64
+	// CircuitBreakerThreshold is the number of consecutive registry failures within CircuitBreakerWindow
+	// which opens the circuit breaker.
+	//
+	// Each registry and operation (see RegistryOpPull, etc.) has its own circuit breaker. While the breaker is open,
+	// the operations fail with ErrRegistryUnavailable for CircuitBreakerCooldown.
+	// Zero disables the circuit breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerWindow    time.Duration
+	CircuitBreakerCooldown  time.Duration
+	// DigestCacheSize is the maximum number of resolved tag to digest mappings to keep.
+	//
+	// Defaults to 1024.
+	DigestCacheSize int
+	// DigestCacheTTL is the time after which a resolved tag is resolved again.
+	//
+	// Zero keeps resolved tags until they are evicted from the cache or Manager.Invalidate is called.
+	DigestCacheTTL time.Duration
+	// ImagerPullPolicy controls whether the imager image is checked for updates for already extracted Talos versions.
+	//
+	// Defaults to PullPolicyIfNotPresent.
+	ImagerPullPolicy PullPolicy
+	// OnIncompleteExtraction controls how an extracted Talos version is handled if its extraction hasn't been completed,
+	// e.g. if the process was interrupted before the checksums were written.
+	//
+	// Defaults to IncompleteExtractionRetry.
+	OnIncompleteExtraction IncompleteExtractionMode
+	// ValidateImagerOutputs enables validation of the extracted imager output against the manifest of outputs
+	// declared by the imager (if any).
+	//
+	// Extraction fails with ErrIncompleteExtraction if any declared file is missing or doesn't match.
+	ValidateImagerOutputs bool
+	// FetchRetries is the number of retries for image downloads interrupted with an unexpected EOF.
+	FetchRetries int
+	// MaxArtifactSize is the maximum size of a single extracted imager artifact.
+	//
+	// Extraction fails with ErrArtifactTooLarge if any artifact is larger. Zero means no limit.
+	MaxArtifactSize int64
+	// FollowSymlinks enables extraction of the symlinks in the imager output.
+	//
+	// Symlinks must point within the extracted Talos version, otherwise the extraction fails with ErrUnsafePath.
+	// By default, symlinks are skipped.
+	FollowSymlinks bool
+	// EvictOnDiskFull evicts other extracted Talos versions which are not in use, oldest first,
+	// when the storage runs out of space during the extraction, and retries the extraction after each eviction.
+	//
+	// Evicted artifacts with open readers are only removed after Options.EvictionGracePeriod.
+	EvictOnDiskFull bool
+	// FetchExtensionSBOMs enables fetching SBOM and attestation artifacts attached to the extension images.
+	FetchExtensionSBOMs bool
+	// SBOMNotAvailableTTL is the time for which Manager.GetSBOM keeps returning ErrSBOMNotAvailable
+	// for a Talos version without an SBOM, before looking up the SBOM again.
+	//
+	// Defaults to 1 hour.
+	SBOMNotAvailableTTL time.Duration
+	// ExtensionRefRewriter rewrites the extension ref before the extension image is pulled.
+	//
+	// It allows pulling extension images from a mirror: the repository (including the registry host)
+	// of the rewritten ref is used as is, while the digest of the original ref is kept,
+	// so that the cached extension images are shared between mirrored and direct fetches.
+	ExtensionRefRewriter func(ExtensionRef) ExtensionRef
+	// OnServe is called for every artifact served with Get or Open, e.g. for download analytics.
+	//
+	// The version is the resolved Talos version tag (e.g. "v1.7.0"), and cacheHit is false if the imager image
+	// had to be fetched for the request. It is called synchronously without holding any locks.
+	OnServe func(version string, arch Arch, kind Kind, cacheHit bool)
+	// SignaturePolicy controls the handling of image signature verification errors.
+	//
+	// Empty policy disables signature verification.
+	SignaturePolicy SignaturePolicy
+	// RequireExtensionSignatures rejects the extension images which don't have a valid signature with ErrExtensionUnsigned.
+	//
+	// Extension signatures are verified with ImageVerifyOptions, independently of SignaturePolicy.
+	RequireExtensionSignatures bool
+	// ExtensionSignatureExceptions is the list of registries (as in the extension refs) which are allowed to serve
+	// unsigned extension images when RequireExtensionSignatures is enabled.
+	ExtensionSignatureExceptions []string
+	// RequiredExtensions is the list of official extensions (e.g. "siderolabs/gvisor") every schematic must include.
+	//
+	// The names are resolved against the official extensions of the Talos version, schematics which omit
+	// a required extension are rejected with ErrMissingRequiredExtension.
+	RequiredExtensions []string
+	// ServedRootRewrite rewrites the prefix of the artifact paths handed to an external server with Manager.ServedPath,
+	// e.g. when the storage is bind-mounted into a web server at another path.
+	//
+	// The paths returned by the manager are not rewritten. Zero value disables the rewrite.
+	ServedRootRewrite PathRewrite
+	// RegistryOverrides is the list of registries which can be selected per request with WithRegistryOverride.
+	//
+	// Each registry is served by a separate manager with its own storage, overrides to other registries
+	// are rejected with ErrRegistryOverrideNotAllowed.
+	RegistryOverrides []string
+}
+
+// Stats describes the state of the artifacts manager.
+type Stats struct {
+	// CircuitBreakerState is the most severe state of the registry circuit breakers, see CircuitBreakers.
+	CircuitBreakerState CircuitBreakerState
+	// CircuitBreakers are the states of the circuit breakers of each registry and operation, keyed
+	// like "ghcr.io/pull" (see RegistryOpPull, etc.).
+	CircuitBreakers map[string]CircuitBreakerState
+	// DigestCacheHitRate is the ratio of tag resolutions served from the digest cache.
+	DigestCacheHitRate float64
+	// Coalescing is the effectiveness of deduplication of concurrent fetches per fetch group.
+	Coalescing map[FetchGroup]CoalescingStats
+	// FetchesInFlight is the number of fetches in progress.
+	FetchesInFlight int
 }
 
+// PullPolicy is the image pull policy.
+type PullPolicy string
+
+// Supported pull policies.
+const (
+	// PullPolicyIfNotPresent pulls the image only if it hasn't been pulled yet.
+	PullPolicyIfNotPresent PullPolicy = "IfNotPresent"
+	// PullPolicyAlways re-resolves the image tag on each access, and pulls the image again if the digest changed.
+	PullPolicyAlways PullPolicy = "Always"
+)
+
+// IncompleteExtractionMode is the handling of incomplete extractions.
+type IncompleteExtractionMode string
+
+// Supported incomplete extraction modes.
+const (
+	// IncompleteExtractionRetry extracts the Talos version again.
+	IncompleteExtractionRetry IncompleteExtractionMode = "Retry"
+	// IncompleteExtractionFail fails with ErrIncompleteExtraction.
+	IncompleteExtractionFail IncompleteExtractionMode = "Fail"
+)
+
+// SignaturePolicy is the image signature verification policy.
+type SignaturePolicy string
+
+// Signature policies.
+const (
+	// SignaturePolicyEnforce fails the fetch on any signature verification error.
+	SignaturePolicyEnforce SignaturePolicy = "Enforce"
+	// SignaturePolicyWarnOnly fails the fetch only if the signature is invalid or missing,
+	// while inconclusive verification (e.g. the transparency log is unreachable) is logged and ignored.
+	SignaturePolicyWarnOnly SignaturePolicy = "WarnOnly"
+)
+
 // Kind is the artifact kind.
 type Kind string
 
@@ -45,18 +331,105 @@ const (
 	KindRPiFirmware Kind = "raspberrypi-firmware"
 )
 
+// PathRewrite replaces the From prefix of the paths with To.
+type PathRewrite struct {
+	// From is the absolute path prefix to replace, e.g. the storage path.
+	From string
+	// To is the absolute path prefix to replace it with.
+	To string
+}
+
+// PrefetchSelector selects the artifacts prefetched with Options.PrefetchNewVersions.
+type PrefetchSelector struct {
+	// Arches are the architectures to prefetch the artifacts for, defaults to all architectures.
+	Arches []Arch
+	// Kinds are the artifact kinds to prepare (e.g. to compress as configured with Options.KindCompression).
+	//
+	// The imager image is extracted for all kinds anyway.
+	Kinds []Kind
+	// Concurrency is the maximum number of Talos versions prefetched at a time, defaults to 1.
+	Concurrency int
+	// MinFreeBytes is the free space to keep on the storage: the Talos version is not prefetched
+	// if the free space minus the size of the imager image falls below it, even after evicting
+	// the older Talos versions as with Options.MinFreeDiskBytes.
+	MinFreeBytes int64
+}
+
 // FetchTimeout controls overall timeout for fetching artifacts for a release.
 const FetchTimeout = 20 * time.Minute
 
 // Various images.
 const (
 	InstallerImage         = "pl4nty/installer"
+	InstallerBaseImage     = "pl4nty/installer-base"
 	ImagerImage            = "pl4nty/imager"
 	ExtensionManifestImage = "pl4nty/extensions"
 	OverlayManifestImage   = "siderolabs/overlays"
 )
 
+// InstallerVariant is the variant of the Talos installer image.
+type InstallerVariant string
+
+// Supported installer variants.
+const (
+	// InstallerVariantStandard is the installer image with the boot assets.
+	InstallerVariantStandard InstallerVariant = "standard"
+	// InstallerVariantBase is the base installer image for custom builds.
+	InstallerVariantBase InstallerVariant = "base"
+)
+
 const tmpSuffix = "-tmp"
 
+// provenanceSuffix is the suffix of the sidecar file which stores the digest of the imager image artifacts were extracted from.
+const provenanceSuffix = ".digest"
+
+// ErrArtifactTooLarge is returned when an imager artifact exceeds Options.MaxArtifactSize.
+var ErrArtifactTooLarge = errors.New("artifact is too large")
+
+// ErrUnsafePath is returned when the imager output contains a path or a symlink which points outside of the extraction root.
+var ErrUnsafePath = errors.New("unsafe path in imager output")
+
+// ErrIncompleteExtraction is returned when the extracted imager output doesn't match the outputs declared by the imager.
+var ErrIncompleteExtraction = errors.New("incomplete extraction")
+
+// ErrDiskFull is returned when the storage runs out of space while extracting the imager artifacts.
+var ErrDiskFull = errors.New("not enough disk space for the artifacts")
+
+// ErrInsufficientDiskSpace is returned when the free space on the storage is below Options.MinFreeDiskBytes.
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// ErrExtensionUnsigned is returned when the extension image doesn't have a valid signature, while signatures are required.
+var ErrExtensionUnsigned = errors.New("extension image is not signed")
+
+// ErrExtensionCorrupt is returned when the cached extension image doesn't match its digest.
+var ErrExtensionCorrupt = errors.New("extension image is corrupt")
+
+// ErrMissingRequiredExtension is returned when the schematic doesn't include an extension from Options.RequiredExtensions.
+var ErrMissingRequiredExtension = errors.New("required extension is missing")
+
+// ErrRegistryOverrideNotAllowed is returned when the registry override is not in Options.RegistryOverrides.
+var ErrRegistryOverrideNotAllowed = errors.New("registry override is not allowed")
+
+// ErrVersionUnsupported is returned when the Talos version is not in Options.AllowedVersions.
+var ErrVersionUnsupported = errors.New("talos version is not supported")
+
+// ErrNoVersionsAvailable is returned when there is no available Talos version close to the requested one.
+var ErrNoVersionsAvailable = errors.New("no Talos versions available")
+
+// ErrSBOMNotAvailable is returned when the Talos version has no SBOM attached to the imager image.
+var ErrSBOMNotAvailable = errors.New("SBOM is not available")
+
+// ErrInvalidArch is returned when the architecture is not supported.
+var ErrInvalidArch = errors.New("invalid architecture")
+
+// ErrInvalidKind is returned when the artifact kind is not supported.
+var ErrInvalidKind = errors.New("invalid artifact kind")
+
+// ErrManagerClosed is returned when the manager is used after Close.
+var ErrManagerClosed = errors.New("artifacts manager is closed")
+
 // ErrNotFoundTag tags the errors when the artifact is not found.
 type ErrNotFoundTag = struct{}
+
+// InvalidVersionErrorTag tags the errors when the Talos version is invalid or not supported.
+type InvalidVersionErrorTag struct{}
@@ -0,0 +1,77 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+)
+
+// VersionAnomaly is a Talos version tag which doesn't match the version of the imager image it points to.
+type VersionAnomaly struct {
+	// Tag is the imager image tag.
+	Tag string
+	// Version is the Talos version of the tag.
+	Version semver.Version
+	// BuildVersion is the version the imager image was built for, as embedded in the image label.
+	BuildVersion string
+}
+
+// AuditVersions checks that each available Talos version tag points to the imager image built for that version.
+//
+// The version of the imager image is read from the org.opencontainers.image.version label, images without the label
+// are not checked. Anomalies are returned for the tags which point to another version (e.g. v1.8.0 pointing
+// to a v1.9 build), or for which the label is not a valid version.
+func (m *Manager) AuditVersions(ctx context.Context) ([]VersionAnomaly, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	versions, err := m.GetTalosVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var anomalies []VersionAnomaly
+
+	for _, version := range versions {
+		tag, err := m.parseTag(ctx, version.String())
+		if err != nil {
+			return nil, err
+		}
+
+		_, img, err := m.imagerImage(ctx, version.String(), ArchArm64)
+		if err != nil {
+			return nil, fmt.Errorf("error auditing Talos version %s: %w", tag, err)
+		}
+
+		config, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("error reading imager image config for %s: %w", tag, err)
+		}
+
+		buildVersion := config.Config.Labels[labelVersion]
+		if buildVersion == "" {
+			continue
+		}
+
+		if parsed, err := semver.ParseTolerant(buildVersion); err == nil && parsed.Equals(version) {
+			continue
+		}
+
+		anomalies = append(anomalies, VersionAnomaly{
+			Tag:          tag,
+			Version:      version,
+			BuildVersion: buildVersion,
+		})
+	}
+
+	return anomalies, nil
+}
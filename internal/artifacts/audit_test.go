@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestAuditVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for tag, buildVersion := range map[string]string{
+		"v1.6.0": "",
+		"v1.7.0": "v1.7.0",
+		"v1.8.0": "v1.9.0",
+		"v1.8.1": "main",
+	} {
+		img, err := crane.Image(map[string][]byte{"usr/install/amd64/vmlinuz": []byte("kernel " + tag)})
+		require.NoError(t, err)
+
+		if buildVersion != "" {
+			img, err = mutate.Config(img, v1.Config{Labels: map[string]string{"org.opencontainers.image.version": buildVersion}})
+			require.NoError(t, err)
+		}
+
+		ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":"+tag, name.Insecure)
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img))
+	}
+
+	anomalies, err := manager.AuditVersions(ctx)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []artifacts.VersionAnomaly{
+		{Tag: "v1.8.0", Version: semver.MustParse("1.8.0"), BuildVersion: "v1.9.0"},
+		{Tag: "v1.8.1", Version: semver.MustParse("1.8.1"), BuildVersion: "main"},
+	}, anomalies)
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// purposeKeychain resolves the registry credentials by the purpose of the repository.
+//
+// The imager and the installer repositories are matched by name, any other repository
+// (extensions, overlays, and their manifests) is treated as an extension repository.
+// The credentials are only used for Options.ImageRegistry, any other registry is accessed anonymously.
+type purposeKeychain struct {
+	registry string
+
+	imagerRepositories    []string
+	installerRepositories []string
+
+	imager     authn.Authenticator
+	installer  authn.Authenticator
+	extensions authn.Authenticator
+}
+
+// newPurposeKeychain creates the keychain for Options.Auth and the per-purpose overrides.
+//
+// If no credentials are configured, nil is returned.
+func newPurposeKeychain(options Options, imageRegistry name.Registry) authn.Keychain {
+	if options.Auth == nil && options.ImagerAuth == nil && options.InstallerAuth == nil && options.ExtensionsAuth == nil {
+		return nil
+	}
+
+	orDefault := func(auth authn.Authenticator) authn.Authenticator {
+		if auth != nil {
+			return auth
+		}
+
+		if options.Auth != nil {
+			return options.Auth
+		}
+
+		return authn.Anonymous
+	}
+
+	repositoryName := func(repositoryPath string) string {
+		return repositoryFor(imageRegistry, options.RepositoryPrefix, repositoryPath).String()
+	}
+
+	return &purposeKeychain{
+		registry: imageRegistry.RegistryStr(),
+
+		imagerRepositories:    []string{repositoryName(ImagerImage)},
+		installerRepositories: []string{repositoryName(InstallerImage), repositoryName(InstallerBaseImage)},
+
+		imager:     orDefault(options.ImagerAuth),
+		installer:  orDefault(options.InstallerAuth),
+		extensions: orDefault(options.ExtensionsAuth),
+	}
+}
+
+// Resolve implements authn.Keychain.
+func (k *purposeKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	if resource.RegistryStr() != k.registry {
+		return authn.Anonymous, nil
+	}
+
+	switch repository := resource.String(); {
+	case slices.Contains(k.imagerRepositories, repository):
+		return k.imager, nil
+	case slices.Contains(k.installerRepositories, repository):
+		return k.installer, nil
+	default:
+		return k.extensions, nil
+	}
+}
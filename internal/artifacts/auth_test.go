@@ -0,0 +1,244 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestUseDockerConfig(t *testing.T) {
+	registryHandler := registry.New()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	img, err := crane.Image(map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(u.Host+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img, remote.WithAuth(&authn.Basic{Username: "user", Password: "secret"})))
+
+	dockerConfig := t.TempDir()
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dockerConfig, "config.json"),
+		[]byte(`{"auths":{"`+u.Host+`":{"username":"user","password":"secret"}}}`),
+		0o600,
+	))
+	t.Setenv("DOCKER_CONFIG", dockerConfig)
+
+	for _, useDockerConfig := range []bool{false, true} {
+		t.Run(strconv.FormatBool(useDockerConfig), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+				ImageRegistry:         u.Host,
+				InsecureImageRegistry: true,
+				UseDockerConfig:       useDockerConfig,
+			})
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, manager.Close())
+			})
+
+			_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			if !useDockerConfig {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPerPurposeAuth(t *testing.T) {
+	registryHandler := registry.New()
+
+	imagerAuth := &authn.Basic{Username: "imager", Password: "secret"}
+	extensionsAuth := &authn.Basic{Username: "extensions", Password: "secret"}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedUsername := extensionsAuth.Username
+
+		if strings.HasPrefix(r.URL.Path, "/v2/"+artifacts.ImagerImage+"/") {
+			expectedUsername = imagerAuth.Username
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || password != "secret" || (r.URL.Path != "/v2/" && username != expectedUsername) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	img, err := crane.Image(map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(u.Host+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img, remote.WithAuth(imagerAuth)))
+
+	extension, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, u.Host, "siderolabs/gvisor")
+	require.NoError(t, remote.Write(tag, extension, remote.WithAuth(extensionsAuth)))
+
+	for _, test := range []struct {
+		name              string
+		options           artifacts.Options
+		expectExtensionOK bool
+	}{
+		{
+			name:    "imager only",
+			options: artifacts.Options{ImagerAuth: imagerAuth},
+		},
+		{
+			name:              "shared fallback",
+			options:           artifacts.Options{ImagerAuth: imagerAuth, Auth: extensionsAuth},
+			expectExtensionOK: true,
+		},
+		{
+			name:              "per purpose",
+			options:           artifacts.Options{ImagerAuth: imagerAuth, ExtensionsAuth: extensionsAuth, Auth: &authn.Basic{Username: "other", Password: "secret"}},
+			expectExtensionOK: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			test.options.ImageRegistry = u.Host
+			test.options.InsecureImageRegistry = true
+
+			manager, err := artifacts.NewManager(zaptest.NewLogger(t), test.options)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, manager.Close())
+			})
+
+			_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			require.NoError(t, err)
+
+			_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag})
+
+			if test.expectExtensionOK {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthForeignRegistry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	_, registryHost := setupManager(t, artifacts.Options{})
+
+	registryHandler := registry.New()
+
+	var credentialsSent atomic.Bool
+
+	// the foreign registry asks for credentials, but serves anonymous pulls
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			credentialsSent.Store(true)
+		}
+
+		if r.URL.Path == "/v2/" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(foreign.Close)
+
+	u, err := url.Parse(foreign.URL)
+	require.NoError(t, err)
+
+	extension, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	foreignTag, err := name.NewTag(u.Host+"/siderolabs/gvisor:v1.0.0", name.Insecure)
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(foreignTag, extension))
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         registryHost,
+		InsecureImageRegistry: true,
+		Auth:                  &authn.Basic{Username: "factory", Password: "secret"},
+		ExtensionRefRewriter: func(artifacts.ExtensionRef) artifacts.ExtensionRef {
+			return artifacts.ExtensionRef{TaggedReference: foreignTag}
+		},
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: extensionTag(t, registryHost, "siderolabs/gvisor")})
+	require.NoError(t, err)
+
+	assert.False(t, credentialsSent.Load())
+}
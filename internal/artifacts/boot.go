@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siderolabs/talos/pkg/machinery/kernel"
+)
+
+// imagerCmdlineName is the name of the file with the kernel command line which might be embedded into the imager output.
+const imagerCmdlineName = "cmdline"
+
+// BootMeta describes the boot artifacts of a Talos version.
+type BootMeta struct {
+	// KernelPath is the path to the kernel image.
+	KernelPath string
+	// InitramfsPath is the path to the initramfs.
+	InitramfsPath string
+	// Cmdline is the kernel command line produced by the imager for the Talos version.
+	//
+	// It doesn't include platform-specific and schematic-specific arguments.
+	Cmdline string
+}
+
+// BootMetadata returns the boot artifacts and the kernel command line for the given version and arch.
+//
+// The kernel command line is read from the imager output (the file for the architecture is preferred over the one
+// for all architectures). If the imager output doesn't embed it, the default kernel arguments are returned.
+func (m *Manager) BootMetadata(ctx context.Context, versionString string, arch Arch) (BootMeta, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return BootMeta{}, err
+	}
+
+	defer release()
+
+	kernelPath, err := m.Get(ctx, versionString, arch, KindKernel)
+	if err != nil {
+		return BootMeta{}, err
+	}
+
+	initramfsPath, err := m.Get(ctx, versionString, arch, KindInitramfs)
+	if err != nil {
+		return BootMeta{}, err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return BootMeta{}, err
+	}
+
+	cmdline, err := m.imagerCmdline(tag, arch)
+	if err != nil {
+		return BootMeta{}, err
+	}
+
+	return BootMeta{
+		KernelPath:    kernelPath,
+		InitramfsPath: initramfsPath,
+		Cmdline:       cmdline,
+	}, nil
+}
+
+// imagerCmdline returns the kernel command line embedded into the extracted imager output.
+func (m *Manager) imagerCmdline(tag string, arch Arch) (string, error) {
+	for _, dir := range []string{filepath.Join(m.storagePath, tag, string(arch)), filepath.Join(m.storagePath, tag)} {
+		contents, err := os.ReadFile(filepath.Join(dir, imagerCmdlineName))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return "", fmt.Errorf("error reading kernel command line: %w", err)
+		}
+
+		return strings.Join(strings.Fields(string(contents)), " "), nil
+	}
+
+	return strings.Join(kernel.DefaultArgs, " "), nil
+}
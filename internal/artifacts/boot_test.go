@@ -0,0 +1,55 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/talos/pkg/machinery/kernel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestBootMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+		"usr/install/amd64/cmdline":      []byte("talos.platform=metal console=ttyS0\n"),
+		"usr/install/arm64/vmlinuz":      []byte("kernel"),
+		"usr/install/arm64/initramfs.xz": []byte("initramfs"),
+		"usr/install/cmdline":            []byte("talos.platform=metal\n"),
+	})
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.1", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+	})
+
+	meta, err := manager.BootMetadata(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	assert.Equal(t, "talos.platform=metal console=ttyS0", meta.Cmdline)
+	assert.Equal(t, "vmlinuz", filepath.Base(meta.KernelPath))
+	assert.Equal(t, "initramfs.xz", filepath.Base(meta.InitramfsPath))
+
+	// the command line for all architectures
+	meta, err = manager.BootMetadata(ctx, "1.7.0", artifacts.ArchArm64)
+	require.NoError(t, err)
+	assert.Equal(t, "talos.platform=metal", meta.Cmdline)
+
+	// the imager output doesn't embed the command line
+	meta, err = manager.BootMetadata(ctx, "1.7.1", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join(kernel.DefaultArgs, " "), meta.Cmdline)
+}
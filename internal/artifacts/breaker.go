@@ -0,0 +1,303 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ErrRegistryUnavailable is returned when the circuit breaker is open for the image registry operation.
+var ErrRegistryUnavailable = errors.New("image registry is unavailable")
+
+// CircuitBreakerState is the state of the registry circuit breaker.
+type CircuitBreakerState string
+
+// Circuit breaker states.
+const (
+	CircuitBreakerClosed   CircuitBreakerState = "closed"
+	CircuitBreakerOpen     CircuitBreakerState = "open"
+	CircuitBreakerHalfOpen CircuitBreakerState = "half-open"
+)
+
+// circuitBreaker short-circuits registry operations after consecutive failures.
+//
+// After the threshold of consecutive failures within the window is reached, the breaker opens,
+// and all operations fail with ErrRegistryUnavailable for the cooldown period.
+// After the cooldown, a single probe operation is allowed (half-open state): on success,
+// the breaker closes, on failure it opens again. Only the probe result changes the state of the breaker
+// which is not closed, and canceled operations are not recorded at all.
+//
+// A nil circuit breaker allows all operations.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state        CircuitBreakerState
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool
+}
+
+func newCircuitBreaker(threshold int, window, cooldown time.Duration, now func() time.Time) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &circuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       now,
+		state:     CircuitBreakerClosed,
+	}
+}
+
+// allow checks whether the operation is allowed to proceed, and returns true if the operation is the probe.
+func (b *circuitBreaker) allow() (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitBreakerClosed:
+		return false, nil
+	case CircuitBreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, ErrRegistryUnavailable
+		}
+
+		b.state = CircuitBreakerHalfOpen
+	case CircuitBreakerHalfOpen:
+	}
+
+	if b.probing {
+		return false, ErrRegistryUnavailable
+	}
+
+	b.probing = true
+
+	return true, nil
+}
+
+// record the result of the operation, probe is as returned by allow.
+func (b *circuitBreaker) record(probe bool, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// the canceled probe says nothing about the registry, so the next operation probes again
+	if errors.Is(err, context.Canceled) {
+		if probe {
+			b.probing = false
+		}
+
+		return
+	}
+
+	// the operations which started before the breaker opened don't change its state
+	if b.state != CircuitBreakerClosed && !probe {
+		return
+	}
+
+	if probe {
+		b.probing = false
+	}
+
+	if !isRegistryFailure(err) {
+		b.state = CircuitBreakerClosed
+		b.failures = 0
+
+		return
+	}
+
+	if probe {
+		b.state, b.openedAt = CircuitBreakerOpen, b.now()
+
+		return
+	}
+
+	if b.failures == 0 || b.now().Sub(b.firstFailure) > b.window {
+		b.failures, b.firstFailure = 0, b.now()
+	}
+
+	b.failures++
+
+	if b.failures >= b.threshold {
+		b.state, b.openedAt = CircuitBreakerOpen, b.now()
+		b.failures = 0
+	}
+}
+
+// State returns the current state of the circuit breaker.
+func (b *circuitBreaker) State() CircuitBreakerState {
+	if b == nil {
+		return CircuitBreakerClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// circuitBreakerKey is the registry operation guarded by a circuit breaker.
+type circuitBreakerKey struct {
+	registry string
+	op       string
+}
+
+// String returns the key as in Stats.CircuitBreakers, e.g. "ghcr.io/pull".
+func (k circuitBreakerKey) String() string {
+	return k.registry + "/" + k.op
+}
+
+// circuitBreakers keeps a circuit breaker per registry and operation, so that the failures of one registry
+// (e.g. the registry of the extensions) or of one operation don't short-circuit the others.
+//
+// Nil circuit breakers allow all operations.
+type circuitBreakers struct {
+	mu       sync.Mutex
+	breakers map[circuitBreakerKey]*circuitBreaker
+
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func newCircuitBreakers(threshold int, window, cooldown time.Duration, now func() time.Time) *circuitBreakers {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &circuitBreakers{
+		breakers:  map[circuitBreakerKey]*circuitBreaker{},
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       now,
+	}
+}
+
+// get returns the circuit breaker of the registry operation, creating it on first use.
+func (b *circuitBreakers) get(registry, op string) *circuitBreaker {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := circuitBreakerKey{registry: registry, op: op}
+
+	breaker, ok := b.breakers[key]
+	if !ok {
+		breaker = newCircuitBreaker(b.threshold, b.window, b.cooldown, b.now)
+		b.breakers[key] = breaker
+	}
+
+	return breaker
+}
+
+// States returns the states of the circuit breakers keyed by the registry operation.
+func (b *circuitBreakers) States() map[string]CircuitBreakerState {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make(map[string]CircuitBreakerState, len(b.breakers))
+
+	for key, breaker := range b.breakers {
+		states[key.String()] = breaker.State()
+	}
+
+	return states
+}
+
+// State returns the most severe state of the circuit breakers: open if any is open, then half-open.
+func (b *circuitBreakers) State() CircuitBreakerState {
+	state := CircuitBreakerClosed
+
+	for _, breakerState := range b.States() {
+		switch breakerState {
+		case CircuitBreakerOpen:
+			return CircuitBreakerOpen
+		case CircuitBreakerHalfOpen:
+			state = CircuitBreakerHalfOpen
+		case CircuitBreakerClosed:
+		}
+	}
+
+	return state
+}
+
+// isRegistryFailure returns true if the error indicates the registry is not healthy.
+//
+// Client-side errors (e.g. image not found) are not considered failures.
+func isRegistryFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var transportError *transport.Error
+
+	if errors.As(err, &transportError) {
+		return transportError.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// Registry operations, as passed to Options.FaultInjector.
+const (
+	RegistryOpHead = "head"
+	RegistryOpPull = "pull"
+	RegistryOpList = "list"
+)
+
+// guardRegistry runs the registry operation through the circuit breaker of the registry and the operation.
+//
+// Errors returned by Options.FaultInjector are handled as if the operation failed.
+func (m *Manager) guardRegistry(op, registry string, fn func() error) error {
+	breaker := m.breakers.get(registry, op)
+
+	probe, err := breaker.allow()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, circuitBreakerKey{registry: registry, op: op})
+	}
+
+	if m.options.FaultInjector != nil {
+		err = m.options.FaultInjector(op)
+	}
+
+	if err == nil {
+		err = fn()
+	}
+
+	breaker.record(probe, err)
+
+	return err
+}
@@ -0,0 +1,162 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestFaultInjector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	errInjected := errors.New("injected")
+
+	var injected atomic.Int32
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		TalosVersionRecheckInterval: time.Hour,
+		CircuitBreakerThreshold:     2,
+		CircuitBreakerWindow:        time.Minute,
+		CircuitBreakerCooldown:      time.Hour,
+		FaultInjector: func(op string) error {
+			if op != artifacts.RegistryOpHead {
+				return nil
+			}
+
+			injected.Add(1)
+
+			return errInjected
+		},
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	for range 2 {
+		_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		assert.ErrorIs(t, err, errInjected)
+	}
+
+	// injected failures open the circuit breaker
+	assert.Equal(t, artifacts.CircuitBreakerOpen, manager.Stats().CircuitBreakerState)
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, artifacts.ErrRegistryUnavailable)
+	assert.EqualValues(t, 2, injected.Load())
+
+	// only the failing operation is short-circuited
+	breakers := manager.Stats().CircuitBreakers
+	assert.Equal(t, artifacts.CircuitBreakerOpen, breakers[registryHost+"/"+artifacts.RegistryOpHead])
+	assert.Equal(t, artifacts.CircuitBreakerClosed, breakers[registryHost+"/"+artifacts.RegistryOpList])
+}
+
+func TestCircuitBreakerReset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	errInjected := errors.New("injected")
+
+	var (
+		failing  atomic.Bool
+		canceled atomic.Bool
+		nowMu    sync.Mutex
+	)
+
+	failing.Store(true)
+
+	now := time.Now()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		TalosVersionRecheckInterval: 24 * time.Hour,
+		CircuitBreakerThreshold:     2,
+		CircuitBreakerWindow:        time.Minute,
+		CircuitBreakerCooldown:      time.Minute,
+		FaultInjector: func(op string) error {
+			if op != artifacts.RegistryOpHead {
+				return nil
+			}
+
+			if canceled.Load() {
+				return context.Canceled
+			}
+
+			if failing.Load() {
+				return errInjected
+			}
+
+			return nil
+		},
+	}, artifacts.Dependencies{
+		Now: func() time.Time {
+			nowMu.Lock()
+			defer nowMu.Unlock()
+
+			return now
+		},
+	})
+
+	advance := func(d time.Duration) {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+
+		now = now.Add(d)
+	}
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	headBreaker := func() artifacts.CircuitBreakerState {
+		return manager.Stats().CircuitBreakers[registryHost+"/"+artifacts.RegistryOpHead]
+	}
+
+	// trip
+	for range 2 {
+		_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		assert.ErrorIs(t, err, errInjected)
+	}
+
+	assert.Equal(t, artifacts.CircuitBreakerOpen, headBreaker())
+	assert.Equal(t, artifacts.CircuitBreakerOpen, manager.Stats().CircuitBreakerState)
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, artifacts.ErrRegistryUnavailable)
+
+	// the canceled probe after the cooldown leaves the breaker half-open
+	advance(2 * time.Minute)
+	canceled.Store(true)
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, artifacts.CircuitBreakerHalfOpen, headBreaker())
+
+	canceled.Store(false)
+
+	// the failed probe opens the breaker again
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, errInjected)
+	assert.Equal(t, artifacts.CircuitBreakerOpen, headBreaker())
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, artifacts.ErrRegistryUnavailable)
+
+	// the successful probe resets the breaker
+	advance(2 * time.Minute)
+	failing.Store(false)
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	assert.Equal(t, artifacts.CircuitBreakerClosed, headBreaker())
+	assert.Equal(t, artifacts.CircuitBreakerClosed, manager.Stats().CircuitBreakerState)
+}
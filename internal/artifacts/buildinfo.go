@@ -0,0 +1,66 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OCI image annotation keys, used as imager image labels.
+const (
+	labelCreated  = "org.opencontainers.image.created"
+	labelRevision = "org.opencontainers.image.revision"
+	labelSource   = "org.opencontainers.image.source"
+	labelVersion  = "org.opencontainers.image.version"
+)
+
+// BuildInfo is the provenance of the imager image.
+//
+// Fields are empty if the image config doesn't have them.
+type BuildInfo struct {
+	// Created is the image creation timestamp.
+	Created time.Time
+	// Revision is the source revision (git commit) the image was built from.
+	Revision string
+	// Source is the URL of the image source repository.
+	Source string
+	// Version is the version of the packaged software.
+	Version string
+}
+
+// ImagerBuildInfo returns the build information of the imager image for the Talos version and architecture.
+func (m *Manager) ImagerBuildInfo(ctx context.Context, versionString string, arch Arch) (BuildInfo, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return BuildInfo{}, err
+	}
+
+	_, img, err := m.imagerImage(ctx, versionString, arch)
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return BuildInfo{}, fmt.Errorf("error reading imager image config: %w", err)
+	}
+
+	labels := config.Config.Labels
+
+	return BuildInfo{
+		Created:  config.Created.Time,
+		Revision: labels[labelRevision],
+		Source:   labels[labelSource],
+		Version:  labels[labelVersion],
+	}, nil
+}
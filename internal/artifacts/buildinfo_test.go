@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestImagerBuildInfo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	img, err := crane.Image(map[string][]byte{"usr/install/amd64/vmlinuz": []byte("kernel")})
+	require.NoError(t, err)
+
+	configFile, err := img.ConfigFile()
+	require.NoError(t, err)
+
+	configFile = configFile.DeepCopy()
+	configFile.Created = v1.Time{Time: created}
+	configFile.Config.Labels = map[string]string{
+		"org.opencontainers.image.revision": "0123456789abcdef",
+		"org.opencontainers.image.source":   "https://github.com/siderolabs/talos",
+	}
+
+	img, err = mutate.ConfigFile(img, configFile)
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	buildInfo, err := manager.ImagerBuildInfo(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+
+	assert.Equal(t, artifacts.BuildInfo{
+		Created:  created,
+		Revision: "0123456789abcdef",
+		Source:   "https://github.com/siderolabs/talos",
+	}, buildInfo)
+
+	_, err = manager.ImagerBuildInfo(ctx, "1.7.0", "riscv64")
+	assert.ErrorIs(t, err, artifacts.ErrInvalidArch)
+}
@@ -0,0 +1,85 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// CachedVersions returns the Talos versions extracted to the storage, without accessing the registry.
+//
+// Extractions in progress and evicted versions are skipped. The versions are sorted as in GetTalosVersions.
+func (m *Manager) CachedVersions() ([]semver.Version, error) {
+	release, err := m.enter()
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	entries, err := os.ReadDir(m.storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage directory: %w", err)
+	}
+
+	tagParser := m.options.TagParser
+	if tagParser == nil {
+		tagParser = parseTagTolerant
+	}
+
+	var versions []semver.Version
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasSuffix(entry.Name(), tmpSuffix) || strings.Contains(entry.Name(), "-evicted-") {
+			continue
+		}
+
+		version, ok := tagParser(entry.Name())
+		if !ok {
+			continue // extensions, schematics, etc.
+		}
+
+		versions = append(versions, version)
+	}
+
+	m.sortVersions(versions)
+
+	return versions, nil
+}
+
+// ExtensionCached returns true if the extension image for the architecture is cached, without accessing the registry.
+//
+// Fetches in progress are not considered cached. If the ref doesn't have the digest, the tag is only looked up
+// among the resolved digests, so the extension is reported as not cached if the tag hasn't been resolved yet.
+func (m *Manager) ExtensionCached(arch Arch, ref ExtensionRef) bool {
+	release, err := m.enter()
+	if err != nil {
+		return false
+	}
+
+	defer release()
+
+	if validateArch(arch) != nil {
+		return false
+	}
+
+	if ref.Digest == "" {
+		digest, ok := m.digests.get(m.extensionRepository(ref).Tag(ref.TaggedReference.TagStr()).String())
+		if !ok {
+			return false
+		}
+
+		ref.Digest = digest.String()
+	}
+
+	// extension images are moved in place once completely fetched
+	_, ok := m.cachedExtensionPath(arch, ref.Digest)
+
+	return ok
+}
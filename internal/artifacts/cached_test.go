@@ -0,0 +1,82 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestCachedVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{}, artifacts.Dependencies{StoragePath: storagePath})
+
+	versions, err := manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	for _, tag := range []string{"v1.7.0", "v1.8.0", "v1.9.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	for _, version := range []string{"1.7.0", "1.8.0"} {
+		_, err = manager.Get(ctx, version, artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+	}
+
+	// incomplete extraction, and other artifacts
+	require.NoError(t, os.MkdirAll(filepath.Join(storagePath, "v1.9.0-tmp"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(storagePath, "extensions"), 0o755))
+
+	versions, err = manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []semver.Version{semver.MustParse("1.8.0"), semver.MustParse("1.7.0")}, versions)
+}
+
+func TestExtensionCached(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/cached")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	tagRef := artifacts.ExtensionRef{TaggedReference: tag}
+	digestRef := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	assert.False(t, manager.ExtensionCached(artifacts.ArchAmd64, tagRef))
+	assert.False(t, manager.ExtensionCached(artifacts.ArchAmd64, digestRef))
+
+	_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, tagRef)
+	require.NoError(t, err)
+
+	assert.True(t, manager.ExtensionCached(artifacts.ArchAmd64, tagRef))
+	assert.True(t, manager.ExtensionCached(artifacts.ArchAmd64, digestRef))
+	assert.False(t, manager.ExtensionCached(artifacts.ArchArm64, digestRef))
+}
@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+)
+
+// Channel is the Talos release channel.
+type Channel string
+
+// Release channels.
+const (
+	// ChannelStable includes only final releases.
+	ChannelStable Channel = "stable"
+	// ChannelPreRelease includes only pre-releases (alpha, beta, rc, etc.).
+	ChannelPreRelease Channel = "pre-release"
+	// ChannelAll includes all releases.
+	ChannelAll Channel = "all"
+)
+
+// TalosVersion is the available Talos version.
+type TalosVersion struct {
+	Version semver.Version
+	// PreRelease is set for alpha, beta, rc, etc. versions.
+	PreRelease bool
+}
+
+// GetTalosVersionsByChannel returns a list of Talos versions available in the release channel.
+func (m *Manager) GetTalosVersionsByChannel(ctx context.Context, channel Channel) ([]TalosVersion, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	switch channel {
+	case ChannelStable, ChannelPreRelease, ChannelAll:
+	default:
+		return nil, fmt.Errorf("unsupported release channel %q", channel)
+	}
+
+	versions, err := m.GetTalosVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TalosVersion, 0, len(versions))
+
+	for _, version := range versions {
+		preRelease := len(version.Pre) > 0
+
+		switch {
+		case channel == ChannelStable && preRelease:
+			continue
+		case channel == ChannelPreRelease && !preRelease:
+			continue
+		}
+
+		result = append(result, TalosVersion{
+			Version:    version,
+			PreRelease: preRelease,
+		})
+	}
+
+	return result, nil
+}
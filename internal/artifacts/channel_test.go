@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetTalosVersionsByChannel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"v1.7.0-alpha.1", "v1.7.0", "v1.8.0-beta.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	for _, test := range []struct {
+		channel  artifacts.Channel
+		expected []string
+	}{
+		{channel: artifacts.ChannelStable, expected: []string{"1.7.0"}},
+		{channel: artifacts.ChannelPreRelease, expected: []string{"1.8.0-beta.0", "1.7.0-alpha.1"}},
+		{channel: artifacts.ChannelAll, expected: []string{"1.8.0-beta.0", "1.7.0", "1.7.0-alpha.1"}},
+	} {
+		t.Run(string(test.channel), func(t *testing.T) {
+			versions, err := manager.GetTalosVersionsByChannel(ctx, test.channel)
+			require.NoError(t, err)
+
+			actual := make([]string, 0, len(versions))
+
+			for _, version := range versions {
+				assert.Equal(t, len(version.Version.Pre) > 0, version.PreRelease)
+
+				actual = append(actual, version.Version.String())
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	_, err := manager.GetTalosVersionsByChannel(ctx, "nightly")
+	assert.Error(t, err)
+}
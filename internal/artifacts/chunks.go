@@ -0,0 +1,186 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// chunksDir is the directory in the storage which keeps the chunk lists, so that they are never mixed
+// with the extracted artifacts.
+const chunksDir = "chunks"
+
+// maxChunks is the maximum number of chunks GetChunks splits the artifact into.
+const maxChunks = 10000
+
+// ChunkRef describes a chunk of the artifact, as returned by GetChunks.
+type ChunkRef struct {
+	// Offset is the offset of the chunk in the artifact.
+	Offset int64 `json:"offset"`
+	// Length is the length of the chunk in bytes.
+	Length int64 `json:"length"`
+	// SHA256 is the hex-encoded SHA256 checksum of the chunk contents.
+	SHA256 string `json:"sha256"`
+}
+
+// GetChunks fetches the artifact like Get, and splits it into chunks of chunkSize bytes, e.g. for multipart downloads.
+//
+// The chunks are ordered by offset, the last chunk might be shorter. The artifact is split into at most 10000 chunks,
+// smaller chunk sizes are rejected. The chunk list is computed once for each chunk size, and cached in the storage
+// until the Talos version is evicted.
+func (m *Manager) GetChunks(ctx context.Context, versionString string, arch Arch, kind Kind, chunkSize int64) ([]ChunkRef, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	path, err := m.Get(ctx, versionString, arch, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find artifact: %w", err)
+	}
+
+	if (st.Size()+chunkSize-1)/chunkSize > maxChunks {
+		return nil, fmt.Errorf("invalid chunk size %d: more than %d chunks", chunkSize, maxChunks)
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return nil, err
+	}
+
+	chunksPath := filepath.Join(m.storagePath, chunksDir, tag, string(arch)+"-"+string(kind)+"-"+strconv.FormatInt(chunkSize, 10)+".json")
+
+	chunks, err := readChunks(chunksPath)
+	if err == nil {
+		return chunks, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		m.logger.Warn("recomputing chunk list", zap.String("path", chunksPath), zap.Error(err))
+	}
+
+	chunks, err = computeChunks(ctx, path, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(chunksPath), 0o755); err != nil {
+		return nil, fmt.Errorf("error writing chunk list: %w", err)
+	}
+
+	if err = writeChunks(chunksPath, chunks); err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
+
+// computeChunks hashes the file in chunks of chunkSize bytes.
+func computeChunks(ctx context.Context, path string, chunkSize int64) ([]ChunkRef, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	chunks := []ChunkRef{}
+
+	for offset := int64(0); ; offset += chunkSize {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		hash := sha256.New()
+
+		n, err := io.CopyN(hash, f, chunkSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error reading %q: %w", path, err)
+		}
+
+		if n == 0 {
+			return chunks, nil
+		}
+
+		chunks = append(chunks, ChunkRef{
+			Offset: offset,
+			Length: n,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		})
+
+		if n < chunkSize {
+			return chunks, nil
+		}
+	}
+}
+
+// readChunks reads the cached chunk list.
+func readChunks(path string) ([]ChunkRef, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []ChunkRef
+
+	if err = json.Unmarshal(contents, &chunks); err != nil {
+		return nil, fmt.Errorf("error parsing chunk list: %w", err)
+	}
+
+	return chunks, nil
+}
+
+// writeChunks caches the chunk list, concurrent requests might compute the same chunk list, the last rename wins.
+func writeChunks(path string, chunks []ChunkRef) error {
+	contents, err := json.Marshal(chunks)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"*"+tmpSuffix)
+	if err != nil {
+		return fmt.Errorf("error writing chunk list: %w", err)
+	}
+
+	defer os.Remove(f.Name()) //nolint:errcheck
+
+	if _, err = f.Write(contents); err != nil {
+		f.Close() //nolint:errcheck
+
+		return fmt.Errorf("error writing chunk list: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("error writing chunk list: %w", err)
+	}
+
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("error writing chunk list: %w", err)
+	}
+
+	return nil
+}
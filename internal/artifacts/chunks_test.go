@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetChunks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("0123456789"),
+	})
+
+	chunkHash := func(contents string) string {
+		checksum := sha256.Sum256([]byte(contents))
+
+		return hex.EncodeToString(checksum[:])
+	}
+
+	chunks, err := manager.GetChunks(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, []artifacts.ChunkRef{
+		{Offset: 0, Length: 4, SHA256: chunkHash("0123")},
+		{Offset: 4, Length: 4, SHA256: chunkHash("4567")},
+		{Offset: 8, Length: 2, SHA256: chunkHash("89")},
+	}, chunks)
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	storagePath := filepath.Dir(filepath.Dir(filepath.Dir(path)))
+
+	// the chunk list is cached outside of the extracted artifacts
+	_, err = os.Stat(filepath.Join(storagePath, "chunks", "v1.7.0", "amd64-vmlinuz-4.json"))
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	var inventory bytes.Buffer
+
+	require.NoError(t, manager.StreamInventory(ctx, &inventory))
+	assert.Equal(t, 1, strings.Count(inventory.String(), "\n"))
+
+	cached, err := manager.GetChunks(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, 4)
+	require.NoError(t, err)
+	assert.Equal(t, chunks, cached)
+
+	chunks, err = manager.GetChunks(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []artifacts.ChunkRef{{Offset: 0, Length: 10, SHA256: chunkHash("0123456789")}}, chunks)
+
+	_, err = manager.GetChunks(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, 0)
+	require.ErrorContains(t, err, "invalid chunk size")
+
+	// too many chunks
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.8.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": bytes.Repeat([]byte("0"), 10001),
+	})
+
+	_, err = manager.GetChunks(ctx, "1.8.0", artifacts.ArchAmd64, artifacts.KindKernel, 1)
+	require.ErrorContains(t, err, "more than 10000 chunks")
+
+	_, err = manager.GetChunks(ctx, "1.8.0", artifacts.ArchAmd64, artifacts.KindKernel, 2)
+	require.NoError(t, err)
+}
@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xerrors"
+)
+
+// ClosestVersion returns the available Talos version closest to the requested one, e.g. to suggest it for an unavailable patch.
+//
+// The highest available patch up to the requested version within the same minor release is returned, otherwise the latest
+// patch of the minor release. Pre-releases are only considered if the requested version is a pre-release.
+// ErrNoVersionsAvailable is returned if there are no available versions of the minor release.
+func (m *Manager) ClosestVersion(ctx context.Context, requested string) (semver.Version, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	defer release()
+
+	requestedVersion, err := semver.ParseTolerant(requested)
+	if err != nil {
+		return semver.Version{}, xerrors.NewTaggedf[InvalidVersionErrorTag]("failed to parse version %q: %s", requested, err)
+	}
+
+	available, err := m.GetTalosVersions(ctx)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	closest, ok := closestVersion(requestedVersion, available)
+	if !ok {
+		return semver.Version{}, xerrors.NewTaggedf[ErrNotFoundTag]("%w: no versions close to %s", ErrNoVersionsAvailable, requestedVersion)
+	}
+
+	return closest, nil
+}
+
+// closestVersion picks the version closest to the requested one, see ClosestVersion.
+func closestVersion(requested semver.Version, available []semver.Version) (semver.Version, bool) {
+	var (
+		below, latest           semver.Version
+		foundBelow, foundLatest bool
+	)
+
+	for _, version := range available {
+		if version.Major != requested.Major || version.Minor != requested.Minor {
+			continue
+		}
+
+		if len(version.Pre) > 0 && len(requested.Pre) == 0 {
+			continue
+		}
+
+		if version.LTE(requested) && (!foundBelow || version.GT(below)) {
+			below, foundBelow = version, true
+		}
+
+		if !foundLatest || version.GT(latest) {
+			latest, foundLatest = version, true
+		}
+	}
+
+	if foundBelow {
+		return below, true
+	}
+
+	return latest, foundLatest
+}
@@ -0,0 +1,54 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestClosestVersion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"v1.7.0", "v1.8.1", "v1.8.4", "v1.9.0-alpha.1", "v1.9.2"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	for _, test := range []struct {
+		requested string
+		expected  string
+	}{
+		{requested: "v1.8.99", expected: "1.8.4"},
+		{requested: "1.8.3", expected: "1.8.1"},
+		{requested: "1.8.4", expected: "1.8.4"},
+		{requested: "1.8.0", expected: "1.8.4"},
+		{requested: "1.9.1", expected: "1.9.2"},
+		{requested: "1.9.0-beta.0", expected: "1.9.0-alpha.1"},
+	} {
+		t.Run(test.requested, func(t *testing.T) {
+			version, err := manager.ClosestVersion(ctx, test.requested)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, version.String())
+		})
+	}
+
+	_, err := manager.ClosestVersion(ctx, "1.6.1")
+	require.ErrorIs(t, err, artifacts.ErrNoVersionsAvailable)
+
+	_, err = manager.ClosestVersion(ctx, "not-a-version")
+	assert.True(t, xerrors.TagIs[artifacts.InvalidVersionErrorTag](err))
+}
@@ -0,0 +1,242 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/siderolabs/gen/maps"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// FetchGroup is the kind of the fetched artifacts.
+type FetchGroup string
+
+// Fetch groups.
+const (
+	FetchGroupImager      FetchGroup = "imager"
+	FetchGroupVersions    FetchGroup = "versions"
+	FetchGroupExtensions  FetchGroup = "extensions"
+	FetchGroupOverlays    FetchGroup = "overlays"
+	FetchGroupInstaller   FetchGroup = "installer"
+	FetchGroupSchematics  FetchGroup = "schematics"
+	FetchGroupSBOM        FetchGroup = "sbom"
+	FetchGroupCompression FetchGroup = "compression"
+)
+
+// CoalescingStats is the effectiveness of deduplication of concurrent fetches.
+type CoalescingStats struct {
+	// Leaders is the number of requests which did the actual fetch.
+	Leaders int64
+	// Waiters is the number of requests which were coalesced with the fetch in progress.
+	Waiters int64
+}
+
+// coalescingTracker counts leaders and waiters of the singleflight fetches per fetch group.
+type coalescingTracker struct {
+	mu       sync.Mutex
+	stats    map[FetchGroup]CoalescingStats
+	inflight int
+
+	metricLeaders *prometheus.CounterVec
+	metricWaiters *prometheus.CounterVec
+}
+
+func newCoalescingTracker() *coalescingTracker {
+	return &coalescingTracker{
+		stats: map[FetchGroup]CoalescingStats{},
+		metricLeaders: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_factory_artifacts_fetch_leaders_total",
+				Help: "Number of artifact fetches which did the actual work.",
+			},
+			[]string{"group"},
+		),
+		metricWaiters: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_factory_artifacts_fetch_waiters_total",
+				Help: "Number of artifact fetches coalesced with the fetch in progress.",
+			},
+			[]string{"group"},
+		),
+	}
+}
+
+func (t *coalescingTracker) record(group FetchGroup, leader bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.stats[group]
+
+	if leader {
+		stats.Leaders++
+
+		t.metricLeaders.WithLabelValues(string(group)).Inc()
+	} else {
+		stats.Waiters++
+
+		t.metricWaiters.WithLabelValues(string(group)).Inc()
+	}
+
+	t.stats[group] = stats
+}
+
+// begin and end track the number of fetches in progress (one per singleflight key).
+func (t *coalescingTracker) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inflight++
+}
+
+func (t *coalescingTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inflight--
+}
+
+func (t *coalescingTracker) inflightFetches() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.inflight
+}
+
+func (t *coalescingTracker) snapshot() map[FetchGroup]CoalescingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[FetchGroup]CoalescingStats, len(t.stats))
+
+	for group, stats := range t.stats {
+		result[group] = stats
+	}
+
+	return result
+}
+
+// fetchOnce runs the fetch deduplicating concurrent fetches with the same key.
+//
+// The fetch lifecycle events are published, and the request is counted as a leader or a waiter.
+// The fetch context is not bound to any request, but it can be canceled with CancelFetch, and it is canceled on Close.
+//
+// The singleflight group forgets the key as soon as the fetch completes (before the result is delivered),
+// so the group only holds the keys of the fetches in progress, however many distinct keys are fetched.
+func (m *Manager) fetchOnce(group FetchGroup, key string, fetch func(ctx context.Context) error) <-chan singleflight.Result {
+	var leader atomic.Bool
+
+	sfCh := m.sf.DoChan(key, m.observeFetch(key, func() error {
+		leader.Store(true)
+
+		m.coalescing.begin()
+		defer m.coalescing.end()
+
+		ctx, unregister := m.registerFetch(key)
+		defer unregister()
+
+		tracker, releaseTracker := m.acquireProgress(key)
+		defer releaseTracker()
+
+		return fetch(withProgress(ctx, tracker))
+	}))
+
+	resultCh := make(chan singleflight.Result, 1)
+
+	go func() {
+		result := <-sfCh
+
+		m.coalescing.record(group, leader.Load())
+
+		resultCh <- result
+	}()
+
+	return resultCh
+}
+
+// inflightFetch is the fetch in progress which can be canceled with CancelFetch.
+type inflightFetch struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// registerFetch creates the cancelable context for the fetch.
+//
+// The context is derived from the manager, not from the request which started the fetch, so that the requests
+// coalesced with the fetch don't fail when that request is canceled. Close cancels the fetches in progress.
+func (m *Manager) registerFetch(key string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(m.fetchCtx)
+
+	m.fetchWg.Add(1)
+
+	fetch := &inflightFetch{cancel: cancel, done: make(chan struct{})}
+
+	m.fetchesMu.Lock()
+
+	if m.fetches == nil {
+		m.fetches = map[string]*inflightFetch{}
+	}
+
+	m.fetches[key] = fetch
+
+	m.fetchesMu.Unlock()
+
+	return ctx, func() {
+		m.fetchesMu.Lock()
+		delete(m.fetches, key)
+		m.fetchesMu.Unlock()
+
+		cancel()
+		close(fetch.done)
+
+		m.fetchWg.Done()
+	}
+}
+
+// CancelFetch cancels the fetch in progress by its key (see Event.Key), e.g. when the registry hangs.
+//
+// CancelFetch waits for the fetch to return, so that the partially fetched files are removed.
+// The requests waiting for the fetch fail, the next request starts a new one.
+// CancelFetch returns false if there is no such fetch in progress.
+func (m *Manager) CancelFetch(key string) bool {
+	m.fetchesMu.Lock()
+	fetch, ok := m.fetches[key]
+	m.fetchesMu.Unlock()
+
+	if !ok {
+		m.overridesMu.Lock()
+		overrides := maps.Values(m.overrides)
+		m.overridesMu.Unlock()
+
+		return slices.ContainsFunc(overrides, func(override *Manager) bool {
+			return override.CancelFetch(key)
+		})
+	}
+
+	m.logger.Info("canceling fetch", zap.String("key", key))
+
+	fetch.cancel()
+	<-fetch.done
+
+	return true
+}
+
+// Describe implements prom.Collector interface.
+func (m *Manager) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(m, ch)
+}
+
+// Collect implements prom.Collector interface.
+func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+	m.coalescing.metricLeaders.Collect(ch)
+	m.coalescing.metricWaiters.Collect(ch)
+}
+
+var _ prometheus.Collector = &Manager{}
@@ -0,0 +1,225 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestCoalescingStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	const requests = 8
+
+	var eg errgroup.Group
+
+	for range requests {
+		eg.Go(func() error {
+			_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			return err
+		})
+	}
+
+	require.NoError(t, eg.Wait())
+
+	stats := manager.Stats().Coalescing[artifacts.FetchGroupImager]
+	assert.Positive(t, stats.Leaders)
+	assert.LessOrEqual(t, stats.Leaders+stats.Waiters, int64(requests))
+
+	// cached artifacts are not fetched again
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	assert.Equal(t, stats, manager.Stats().Coalescing[artifacts.FetchGroupImager])
+	assert.Positive(t, manager.Stats().Coalescing[artifacts.FetchGroupVersions].Leaders)
+}
+
+func TestFetchKeysForgotten(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	tag := extensionTag(t, registryHost, "siderolabs/missing")
+
+	const extensions = 50
+
+	// every missing extension is fetched under a distinct key
+	for i := range extensions {
+		digest := v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%064x", i)}
+
+		_, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()})
+		require.Error(t, err)
+
+		assert.Zero(t, manager.Stats().FetchesInFlight)
+	}
+
+	assert.Equal(t, int64(extensions), manager.Stats().Coalescing[artifacts.FetchGroupExtensions].Leaders)
+}
+
+func TestCancelFetch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	img, err := crane.Image(map[string][]byte{
+		"usr/install/amd64/vmlinuz": bytes.Repeat([]byte("kernel"), 1024*1024),
+	})
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	registryHandler := registry.New()
+	hung := make(chan struct{}, 1)
+
+	// send half of the layer, and hang
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, layerDigest.String()) {
+			registryHandler.ServeHTTP(w, r)
+
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		registryHandler.ServeHTTP(rec, r)
+
+		w.Header().Set("Content-Length", strconv.Itoa(rec.Body.Len()))
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes()[:rec.Body.Len()/2]) //nolint:errcheck
+		w.(http.Flusher).Flush()
+
+		hung <- struct{}{}
+
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(u.Host+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	storagePath := t.TempDir()
+
+	manager, err := artifacts.NewManagerWithDeps(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         u.Host,
+		InsecureImageRegistry: true,
+	}, artifacts.Dependencies{StoragePath: storagePath})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	assert.False(t, manager.CancelFetch("v1.7.0"))
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		errCh <- err
+	}()
+
+	select {
+	case <-hung:
+	case <-ctx.Done():
+		t.Fatal("fetch didn't start")
+	}
+
+	assert.True(t, manager.CancelFetch("v1.7.0"))
+	assert.NoDirExists(t, filepath.Join(storagePath, "v1.7.0-tmp"))
+
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+	assert.False(t, manager.CancelFetch("v1.7.0"))
+}
+
+func TestFetchSurvivesLeaderCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	registryHost, blocked, unblock := setupBlockingRegistry(t)
+	t.Cleanup(unblock)
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         registryHost,
+		InsecureImageRegistry: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	leaderCtx, leaderCancel := context.WithCancel(ctx)
+	leaderErr := make(chan error, 1)
+
+	go func() {
+		_, err := manager.Get(leaderCtx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		leaderErr <- err
+	}()
+
+	// the leader is fetching the layers
+	select {
+	case <-blocked:
+	case <-ctx.Done():
+		require.FailNow(t, "fetch didn't start")
+	}
+
+	waiterResult := make(chan error, 1)
+
+	go func() {
+		_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		waiterResult <- err
+	}()
+
+	// let the waiter join the fetch
+	time.Sleep(50 * time.Millisecond)
+
+	leaderCancel()
+	require.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	unblock()
+	require.NoError(t, <-waiterResult)
+
+	// the leader is recorded once the fetch completes, after the leader request has returned
+	assert.EventuallyWithT(t, func(collect *assert.CollectT) {
+		assert.Equal(collect, artifacts.CoalescingStats{Leaders: 1, Waiters: 1}, manager.Stats().Coalescing[artifacts.FetchGroupImager])
+	}, 5*time.Second, 10*time.Millisecond)
+}
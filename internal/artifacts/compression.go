@@ -0,0 +1,188 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ulikunitz/xz"
+)
+
+// CompressionScheme is the compression of the artifact.
+type CompressionScheme string
+
+// Supported compression schemes.
+const (
+	CompressionNone CompressionScheme = ""
+	CompressionGzip CompressionScheme = "gzip"
+	CompressionXZ   CompressionScheme = "xz"
+)
+
+// compressionSuffixes are the file name suffixes of the compressed artifacts.
+var compressionSuffixes = map[CompressionScheme]string{
+	CompressionGzip: ".gz",
+	CompressionXZ:   ".xz",
+}
+
+// kindCompression is the compression of the artifacts as extracted from the imager.
+var kindCompression = map[Kind]CompressionScheme{
+	KindInitramfs: CompressionXZ,
+}
+
+// validateKindCompression checks that only uncompressed artifacts are compressed.
+func validateKindCompression(kindCompressionOverrides map[Kind]CompressionScheme) error {
+	for kind, scheme := range kindCompressionOverrides {
+		if _, ok := compressionSuffixes[scheme]; !ok && scheme != CompressionNone {
+			return fmt.Errorf("unsupported compression %q for artifact %q", scheme, kind)
+		}
+
+		if native := kindCompression[kind]; native != CompressionNone && native != scheme {
+			return fmt.Errorf("artifact %q is already compressed with %s", kind, native)
+		}
+	}
+
+	return nil
+}
+
+// compression returns the compression of the artifacts of the kind returned by the manager.
+func (m *Manager) compression(kind Kind) CompressionScheme {
+	if scheme, ok := m.options.KindCompression[kind]; ok {
+		return scheme
+	}
+
+	return kindCompression[kind]
+}
+
+// needsCompression returns true if the extracted artifacts of the kind are compressed before serving.
+func (m *Manager) needsCompression(kind Kind) bool {
+	return m.compression(kind) != kindCompression[kind]
+}
+
+// newCompressor wraps w to compress the data written with the scheme.
+func newCompressor(w io.Writer, scheme CompressionScheme) (io.WriteCloser, error) {
+	switch scheme {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionXZ:
+		return xz.NewWriter(w)
+	case CompressionNone:
+	}
+
+	return nil, fmt.Errorf("unsupported compression %q", scheme)
+}
+
+// compressArtifact compresses the extracted artifact once, and returns the path of the compressed copy.
+//
+// Concurrent requests for the same artifact and compression scheme wait for a single compression.
+func (m *Manager) compressArtifact(ctx context.Context, path string, scheme CompressionScheme) (string, error) {
+	compressedPath := path + compressionSuffixes[scheme]
+
+	if _, err := os.Stat(compressedPath); err == nil {
+		return compressedPath, nil
+	}
+
+	resultCh := m.fetchOnce(FetchGroupCompression, "compress-"+string(scheme)+"-"+path, func(context.Context) error {
+		return compressFile(path, compressedPath, scheme)
+	})
+
+	// wait for the compression to finish
+	select {
+	case result := <-resultCh:
+		if result.Err != nil {
+			return "", result.Err
+		}
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	return compressedPath, nil
+}
+
+// compressFile compresses the file at path into compressedPath.
+func compressFile(path, compressedPath string, scheme CompressionScheme) error {
+	if _, err := os.Stat(compressedPath); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close() //nolint:errcheck
+
+	dst, err := os.CreateTemp(filepath.Dir(path), filepath.Base(compressedPath)+"*"+tmpSuffix)
+	if err != nil {
+		return fmt.Errorf("error creating compressed artifact: %w", err)
+	}
+
+	defer os.Remove(dst.Name()) //nolint:errcheck
+
+	defer dst.Close() //nolint:errcheck
+
+	compressor, err := newCompressor(dst, scheme)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(compressor, src); err != nil {
+		return fmt.Errorf("error compressing artifact: %w", err)
+	}
+
+	if err = compressor.Close(); err != nil {
+		return fmt.Errorf("error compressing artifact: %w", err)
+	}
+
+	if err = dst.Close(); err != nil {
+		return fmt.Errorf("error compressing artifact: %w", err)
+	}
+
+	if err = os.Rename(dst.Name(), compressedPath); err != nil {
+		return fmt.Errorf("error compressing artifact: %w", err)
+	}
+
+	return nil
+}
+
+// ArtifactInfo describes the artifact returned by Get.
+type ArtifactInfo struct {
+	// Path is the path of the artifact.
+	Path string
+	// ContentEncoding is the compression of the artifact, see Options.KindCompression.
+	ContentEncoding CompressionScheme
+	// Size is the size of the artifact (as stored, i.e. compressed).
+	Size int64
+}
+
+// Stat fetches the artifact like Get, and describes it.
+func (m *Manager) Stat(ctx context.Context, versionString string, arch Arch, kind Kind) (ArtifactInfo, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+
+	defer release()
+
+	path, err := m.Get(ctx, versionString, arch, kind)
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to stat artifact: %w", err)
+	}
+
+	return ArtifactInfo{
+		Path:            path,
+		ContentEncoding: m.compression(kind),
+		Size:            st.Size(),
+	}, nil
+}
@@ -0,0 +1,117 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestKindCompression(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	_, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		KindCompression: map[artifacts.Kind]artifacts.CompressionScheme{artifacts.KindInitramfs: artifacts.CompressionGzip},
+	})
+	require.EqualError(t, err, `artifact "initramfs.xz" is already compressed with xz`)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		KindCompression: map[artifacts.Kind]artifacts.CompressionScheme{artifacts.KindKernel: artifacts.CompressionGzip},
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+	})
+
+	info, err := manager.Stat(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+	assert.Equal(t, artifacts.CompressionGzip, info.ContentEncoding)
+	assert.True(t, strings.HasSuffix(info.Path, "vmlinuz.gz"), info.Path)
+
+	f, err := os.Open(info.Path)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { f.Close() }) //nolint:errcheck
+
+	st, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, st.Size(), info.Size)
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	contents, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(contents))
+
+	var buf bytes.Buffer
+
+	require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, &buf))
+
+	gz, err = gzip.NewReader(&buf)
+	require.NoError(t, err)
+
+	contents, err = io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(contents))
+
+	info, err = manager.Stat(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs)
+	require.NoError(t, err)
+	assert.Equal(t, artifacts.CompressionXZ, info.ContentEncoding)
+	assert.Equal(t, int64(len("initramfs")), info.Size)
+}
+
+func TestKindCompressionCoalescing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		KindCompression: map[artifacts.Kind]artifacts.CompressionScheme{artifacts.KindKernel: artifacts.CompressionXZ},
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": bytes.Repeat([]byte("kernel"), 1<<16),
+	})
+
+	const requests = 8
+
+	var eg errgroup.Group
+
+	for range requests {
+		eg.Go(func() error {
+			_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			return err
+		})
+	}
+
+	require.NoError(t, eg.Wait())
+
+	stats := manager.Stats().Coalescing[artifacts.FetchGroupCompression]
+	assert.Positive(t, stats.Leaders)
+	assert.LessOrEqual(t, stats.Leaders+stats.Waiters, int64(requests))
+
+	// the compressed copy is not compressed again
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(path, "vmlinuz.xz"), path)
+
+	assert.Equal(t, stats, manager.Stats().Coalescing[artifacts.FetchGroupCompression])
+}
@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Puller is the subset of remote.Puller the artifacts manager uses to access the image registry.
+type Puller interface {
+	Get(ctx context.Context, ref name.Reference) (*remote.Descriptor, error)
+	Head(ctx context.Context, ref name.Reference) (*v1.Descriptor, error)
+	List(ctx context.Context, repo name.Repository) ([]string, error)
+}
+
+var _ Puller = (*remote.Puller)(nil)
+
+// Dependencies are the dependencies of the artifacts manager which can be replaced in tests.
+//
+// Zero values are replaced with the defaults.
+type Dependencies struct {
+	// NewPuller creates the image registry puller for each architecture.
+	//
+	// Tests might wrap the remote.Puller, e.g. to inject registry failures. Defaults to remote.NewPuller.
+	NewPuller func(options ...remote.Option) (Puller, error)
+	// Now returns the current time.
+	//
+	// Defaults to time.Now.
+	Now func() time.Time
+	// StoragePath is the directory to store the artifacts in.
+	//
+	// Artifacts are served as filesystem paths, so the storage is always on disk,
+	// but tests might point it to a directory they control, which is left in place on Close.
+	// Defaults to a new temporary directory, which is removed on Close.
+	StoragePath string
+	// CreateFile creates the files extracted from the imager image.
+	//
+	// Defaults to os.Create.
+	CreateFile func(name string) (io.WriteCloser, error)
+	// FreeSpace returns the free space in bytes on the filesystem of the path.
+	//
+	// Defaults to statfs(2).
+	FreeSpace func(path string) (int64, error)
+}
@@ -0,0 +1,54 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+// failingPuller fails the image pulls, but passes other requests to the registry.
+type failingPuller struct {
+	artifacts.Puller
+
+	err error
+}
+
+func (p failingPuller) Get(context.Context, name.Reference) (*remote.Descriptor, error) {
+	return nil, p.err
+}
+
+func TestFakePuller(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	errPull := errors.New("pull failed")
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{}, artifacts.Dependencies{
+		NewPuller: func(options ...remote.Option) (artifacts.Puller, error) {
+			puller, err := remote.NewPuller(options...)
+
+			return failingPuller{Puller: puller, err: errPull}, err
+		},
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	versions, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, errPull)
+}
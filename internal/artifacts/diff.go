@@ -0,0 +1,91 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/gen/maps"
+)
+
+// ExtensionDiff is the difference between official extensions of two Talos versions.
+//
+// Extensions are matched by the image repository, and all lists are sorted by it.
+type ExtensionDiff struct {
+	Added   []ExtensionRef
+	Removed []ExtensionRef
+	Changed []ExtensionChange
+}
+
+// ExtensionChange is the extension which was bumped between two Talos versions.
+type ExtensionChange struct {
+	From ExtensionRef
+	To   ExtensionRef
+}
+
+// DiffOfficialExtensions returns the difference between official extensions of two Talos versions.
+func (m *Manager) DiffOfficialExtensions(ctx context.Context, fromVersion, toVersion string) (ExtensionDiff, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return ExtensionDiff{}, err
+	}
+
+	defer release()
+
+	fromExtensions, err := m.GetOfficialExtensions(ctx, fromVersion)
+	if err != nil {
+		return ExtensionDiff{}, err
+	}
+
+	toExtensions, err := m.GetOfficialExtensions(ctx, toVersion)
+	if err != nil {
+		return ExtensionDiff{}, err
+	}
+
+	fromByName := extensionsByName(fromExtensions)
+	toByName := extensionsByName(toExtensions)
+
+	var diff ExtensionDiff
+
+	for _, name := range sortedNames(toByName) {
+		to := toByName[name]
+
+		from, ok := fromByName[name]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, to)
+		case from.TaggedReference.TagStr() != to.TaggedReference.TagStr() || from.Digest != to.Digest:
+			diff.Changed = append(diff.Changed, ExtensionChange{From: from, To: to})
+		}
+	}
+
+	for _, name := range sortedNames(fromByName) {
+		if _, ok := toByName[name]; !ok {
+			diff.Removed = append(diff.Removed, fromByName[name])
+		}
+	}
+
+	return diff, nil
+}
+
+func extensionsByName(extensions []ExtensionRef) map[string]ExtensionRef {
+	result := make(map[string]ExtensionRef, len(extensions))
+
+	for _, extension := range extensions {
+		result[extension.TaggedReference.RepositoryStr()] = extension
+	}
+
+	return result
+}
+
+func sortedNames(extensions map[string]ExtensionRef) []string {
+	names := maps.Keys(extensions)
+	slices.SortFunc(names, strings.Compare)
+
+	return names
+}
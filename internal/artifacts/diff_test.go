@@ -0,0 +1,54 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/gen/xslices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestDiffOfficialExtensions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for tag, digests := range map[string]string{
+		"v1.7.0": "ghcr.io/siderolabs/gvisor:20231214.0-v1.7.0@sha256:548b2b121611424f6b1b6cfb72a1669421ffaf2f1560911c324a546c7cee655e\n" +
+			"ghcr.io/siderolabs/hello-world-service:v1.7.0@sha256:2ef3d7fe5c1aeb5b9e0a4b6c4d0e3fcbc4fbdc0e7c8d5d4e1fc0f1d4e5e2e2e2\n" +
+			"ghcr.io/siderolabs/nvidia-fabricmanager:535.129.03-v1.7.0@sha256:1b4e45ae2ab9bd1e0a59d1a5b5a5c5d0e2b8f1d8c6c4d1f3d2e4f1e9c9b5e4a3\n",
+		"v1.8.0": "ghcr.io/siderolabs/gvisor:20231214.0-v1.8.0@sha256:7f5e6c3b1b9e1b2c2f6e0e7b3d2c4a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c1b\n" +
+			"ghcr.io/siderolabs/hello-world-service:v1.7.0@sha256:2ef3d7fe5c1aeb5b9e0a4b6c4d0e3fcbc4fbdc0e7c8d5d4e1fc0f1d4e5e2e2e2\n" +
+			"ghcr.io/siderolabs/iscsi-tools:v0.1.4@sha256:3c3b5e5f0a4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f\n",
+	} {
+		pushImager(t, registryHost, tag)
+		pushImage(t, registryHost, artifacts.ExtensionManifestImage, tag, map[string][]byte{
+			"image-digests": []byte(digests),
+		})
+	}
+
+	diff, err := manager.DiffOfficialExtensions(ctx, "1.7.0", "1.8.0")
+	require.NoError(t, err)
+
+	names := func(refs []artifacts.ExtensionRef) []string {
+		return xslices.Map(refs, func(ref artifacts.ExtensionRef) string { return ref.TaggedReference.RepositoryStr() })
+	}
+
+	assert.Equal(t, []string{"siderolabs/iscsi-tools"}, names(diff.Added))
+	assert.Equal(t, []string{"siderolabs/nvidia-fabricmanager"}, names(diff.Removed))
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, "20231214.0-v1.7.0", diff.Changed[0].From.TaggedReference.TagStr())
+	assert.Equal(t, "20231214.0-v1.8.0", diff.Changed[0].To.TaggedReference.TagStr())
+
+	// the extension listings are counted as extension fetches
+	assert.Equal(t, int64(2), manager.Stats().Coalescing[artifacts.FetchGroupExtensions].Leaders)
+}
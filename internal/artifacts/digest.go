@@ -0,0 +1,197 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/siderolabs/gen/xerrors"
+)
+
+const digestPrefix = "sha256:"
+
+// DigestPath returns the content-addressed identifier of the artifact, e.g. "sha256:<hash>".
+//
+// The identifier can be mapped back to the artifact path with ResolveDigestPath.
+// Only file artifacts (kernel, initramfs, etc.) have identifiers.
+func (m *Manager) DigestPath(ctx context.Context, versionString string, arch Arch, kind Kind) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	// make sure the artifact is extracted
+	if _, err := m.Get(ctx, versionString, arch, kind); err != nil {
+		return "", err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	checksums, err := readChecksums(filepath.Join(m.storagePath, tag+checksumSuffix))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	checksum, ok := checksums[string(arch)+"/"+m.kindPath(kind)]
+	if !ok {
+		return "", xerrors.NewTaggedf[ErrNotFoundTag]("artifact %s/%s is not a file", arch, kind)
+	}
+
+	return digestPrefix + checksum, nil
+}
+
+// ResolveDigestPath returns the artifact path for the content-addressed identifier returned by DigestPath.
+//
+// The identifiers are looked up in an index of the extracted artifacts, which is loaded from the storage on first use,
+// and kept up to date as the Talos versions are extracted and evicted. The identifiers issued with a registry override
+// (see WithRegistryOverride) are resolved with the same override.
+func (m *Manager) ResolveDigestPath(ctx context.Context, digest string) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	checksum, ok := strings.CutPrefix(digest, digestPrefix)
+	if !ok {
+		return "", fmt.Errorf("unsupported digest %q", digest)
+	}
+
+	relPath, ok, err := m.digestIndex.lookup(checksum, m.loadDigestIndex)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", xerrors.NewTaggedf[ErrNotFoundTag]("artifact %s not found", digest)
+	}
+
+	return filepath.Join(m.storagePath, relPath), nil
+}
+
+// loadDigestIndex reads the checksums of all extracted Talos versions, keyed by the tag.
+func (m *Manager) loadDigestIndex() (map[string]map[string]string, error) {
+	entries, err := os.ReadDir(m.storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage directory: %w", err)
+	}
+
+	tags := map[string]map[string]string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), checksumSuffix) {
+			continue
+		}
+
+		checksums, err := readChecksums(filepath.Join(m.storagePath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		tags[strings.TrimSuffix(entry.Name(), checksumSuffix)] = checksums
+	}
+
+	return tags, nil
+}
+
+// digestIndex maps the checksums of the extracted artifacts to their paths relative to the storage.
+//
+// Until the index is loaded, the updates are ignored, as the loaded index reflects them.
+type digestIndex struct {
+	mu sync.Mutex
+
+	loaded bool
+	// paths are the paths of the artifacts (tag/relPath) with the checksum, extracted for any Talos version
+	paths map[string][]string
+	// checksums are the checksums of the artifacts keyed by the tag, then by the path relative to the tag
+	checksums map[string]map[string]string
+}
+
+// lookup returns the path of an artifact with the checksum, loading the index first if needed.
+func (idx *digestIndex) lookup(checksum string, load func() (map[string]map[string]string, error)) (string, bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.loaded {
+		tags, err := load()
+		if err != nil {
+			return "", false, err
+		}
+
+		idx.loaded = true
+		idx.paths = map[string][]string{}
+		idx.checksums = map[string]map[string]string{}
+
+		for tag, checksums := range tags {
+			idx.addLocked(tag, checksums)
+		}
+	}
+
+	paths := idx.paths[checksum]
+	if len(paths) == 0 {
+		return "", false, nil
+	}
+
+	return paths[0], true, nil
+}
+
+// add the checksums of the extracted Talos version, replacing the previous ones.
+func (idx *digestIndex) add(tag string, checksums map[string]string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.loaded {
+		return
+	}
+
+	idx.removeLocked(tag)
+	idx.addLocked(tag, checksums)
+}
+
+// remove the checksums of the evicted Talos version.
+func (idx *digestIndex) remove(tag string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.loaded {
+		return
+	}
+
+	idx.removeLocked(tag)
+}
+
+func (idx *digestIndex) addLocked(tag string, checksums map[string]string) {
+	idx.checksums[tag] = checksums
+
+	for relPath, checksum := range checksums {
+		idx.paths[checksum] = append(idx.paths[checksum], filepath.Join(tag, filepath.FromSlash(relPath)))
+	}
+}
+
+func (idx *digestIndex) removeLocked(tag string) {
+	for relPath, checksum := range idx.checksums[tag] {
+		path := filepath.Join(tag, filepath.FromSlash(relPath))
+
+		idx.paths[checksum] = slices.DeleteFunc(idx.paths[checksum], func(p string) bool { return p == path })
+
+		if len(idx.paths[checksum]) == 0 {
+			delete(idx.paths, checksum)
+		}
+	}
+
+	delete(idx.checksums, tag)
+}
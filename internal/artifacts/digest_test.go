@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestDigestPath(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+	})
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	digest, err := manager.DigestPath(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:6923dd1bc0460082c5d55a831908c24a282860b7f1cd6c2b79cf1bc8857c639c", digest)
+
+	resolvedPath, err := manager.ResolveDigestPath(ctx, digest)
+	require.NoError(t, err)
+	assert.Equal(t, path, resolvedPath)
+
+	_, err = manager.ResolveDigestPath(ctx, "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
+
+func TestDigestPathIndex(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{RetainPatchesPerMinor: 1}, artifacts.Dependencies{StoragePath: storagePath})
+
+	for _, tag := range []string{"v1.7.0", "v1.7.1"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz":      []byte("kernel " + tag),
+			"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+		})
+	}
+
+	kernelDigest, err := manager.DigestPath(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	initramfsDigest, err := manager.DigestPath(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs)
+	require.NoError(t, err)
+
+	// the index is loaded from the storage
+	resolvedPath, err := manager.ResolveDigestPath(ctx, kernelDigest)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(storagePath, "v1.7.0", "amd64", "vmlinuz"), resolvedPath)
+
+	// v1.7.0 is evicted by the retention once v1.7.1 is extracted
+	newKernelDigest, err := manager.DigestPath(ctx, "1.7.1", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	_, err = manager.ResolveDigestPath(ctx, kernelDigest)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	resolvedPath, err = manager.ResolveDigestPath(ctx, newKernelDigest)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(storagePath, "v1.7.1", "amd64", "vmlinuz"), resolvedPath)
+
+	// the artifacts shared by both versions are still resolved to the retained version
+	resolvedPath, err = manager.ResolveDigestPath(ctx, initramfsDigest)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(storagePath, "v1.7.1", "amd64", "initramfs.xz"), resolvedPath)
+}
@@ -0,0 +1,143 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// defaultDigestCacheSize is the default number of resolved tags to keep.
+const defaultDigestCacheSize = 1024
+
+// digestCache is a bounded LRU cache of resolved tag to digest mappings.
+type digestCache struct {
+	mu sync.Mutex
+
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	entries map[string]*list.Element
+	lru     list.List
+
+	hits, misses uint64
+}
+
+type digestCacheEntry struct {
+	expires time.Time
+	key     string
+	digest  v1.Hash
+}
+
+func newDigestCache(size int, ttl time.Duration, now func() time.Time) *digestCache {
+	if size <= 0 {
+		size = defaultDigestCacheSize
+	}
+
+	return &digestCache{
+		size:    size,
+		ttl:     ttl,
+		now:     now,
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached digest for the tag.
+func (c *digestCache) get(key string) (v1.Hash, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+
+		return v1.Hash{}, false
+	}
+
+	entry := elem.Value.(*digestCacheEntry) //nolint:forcetypeassert,errcheck
+
+	if c.ttl > 0 && c.now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+
+		c.misses++
+
+		return v1.Hash{}, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.hits++
+
+	return entry.digest, true
+}
+
+// put stores the digest for the tag, evicting the least recently used entry if the cache is full.
+func (c *digestCache) put(key string, digest v1.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &digestCacheEntry{
+		key:     key,
+		digest:  digest,
+		expires: c.now().Add(c.ttl),
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*digestCacheEntry).key) //nolint:forcetypeassert,errcheck
+	}
+}
+
+// remove removes the entry for the tag.
+func (c *digestCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// purge removes all entries.
+func (c *digestCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// hitRate returns the ratio of cache hits to all lookups.
+func (c *digestCache) hitRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hits+c.misses == 0 {
+		return 0
+	}
+
+	return float64(c.hits) / float64(c.hits+c.misses)
+}
+
+// Invalidate drops all resolved tag to digest mappings, so that tags are resolved again on next use.
+func (m *Manager) Invalidate() {
+	m.digests.purge()
+}
@@ -0,0 +1,66 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestDigestCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{DigestCacheTTL: time.Hour}, artifacts.Dependencies{
+		Now: func() time.Time {
+			return now
+		},
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	pushInstaller := func(contents string) {
+		pushImage(t, registryHost, artifacts.InstallerImage, "v1.7.0", map[string][]byte{
+			"usr/bin/installer": []byte(contents),
+		})
+	}
+
+	pushInstaller("old installer")
+
+	oldRef, err := manager.GetInstallerImageRefByDigest(ctx, "1.7.0")
+	require.NoError(t, err)
+
+	pushInstaller("new installer")
+
+	// resolved digest is cached
+	ref, err := manager.GetInstallerImageRefByDigest(ctx, "1.7.0")
+	require.NoError(t, err)
+	assert.Equal(t, oldRef, ref)
+	assert.InDelta(t, 0.5, manager.Stats().DigestCacheHitRate, 0.01)
+
+	// ... until invalidated
+	manager.Invalidate()
+
+	newRef, err := manager.GetInstallerImageRefByDigest(ctx, "1.7.0")
+	require.NoError(t, err)
+	assert.NotEqual(t, oldRef, newRef)
+
+	pushInstaller("newer installer")
+
+	// ... or expired
+	now = now.Add(2 * time.Hour)
+
+	ref, err = manager.GetInstallerImageRefByDigest(ctx, "1.7.0")
+	require.NoError(t, err)
+	assert.NotEqual(t, newRef, ref)
+}
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// retryOnDiskFull runs the extraction into the staging paths, cleaning them up on failure.
+//
+// If the storage runs out of space, other extracted Talos versions which are not in use are evicted
+// (with Options.EvictOnDiskFull) one by one, oldest first, and the extraction is retried after each eviction
+// until it succeeds or there is nothing left to evict.
+func (m *Manager) retryOnDiskFull(tag string, stagingPaths []string, extract func() error) error {
+	err := extract()
+	if err == nil {
+		return nil
+	}
+
+	m.removePartial(stagingPaths...)
+
+	if !errors.Is(err, syscall.ENOSPC) {
+		return err
+	}
+
+	for m.options.EvictOnDiskFull {
+		m.logger.Warn("storage is full, evicting extracted Talos version", zap.String("tag", tag), zap.Error(err))
+
+		evicted, evictErr := m.evictOldest(tag)
+		if evictErr != nil {
+			return evictErr
+		}
+
+		if !evicted {
+			break
+		}
+
+		if err = extract(); err == nil {
+			return nil
+		}
+
+		m.removePartial(stagingPaths...)
+
+		if !errors.Is(err, syscall.ENOSPC) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: %w", ErrDiskFull, err)
+}
+
+// evictOldest evicts the oldest extracted Talos version other than the tag which is not in use.
+//
+// It returns false if there is no such version.
+func (m *Manager) evictOldest(tag string) (bool, error) {
+	m.retentionMu.Lock()
+	defer m.retentionMu.Unlock()
+
+	tags, err := m.extractedTags()
+	if err != nil {
+		return false, err
+	}
+
+	m.sortOldestFirst(tags)
+
+	for _, extracted := range tags {
+		if extracted == tag || m.fetchInProgress(extracted) || m.readers.active(extracted) {
+			continue
+		}
+
+		m.logger.Info("evicting Talos version to free up the storage", zap.String("tag", extracted))
+
+		return true, m.evictExtracted(extracted)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+type diskFullWriter struct {
+	*os.File
+}
+
+func (w diskFullWriter) Write([]byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: w.Name(), Err: syscall.ENOSPC}
+}
+
+func TestDiskFull(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		evictOnDiskFull bool
+		openOldest      bool
+		expectEvicted   []string
+		expectErr       bool
+	}{
+		{
+			name:      "no eviction",
+			expectErr: true,
+		},
+		{
+			name:            "eviction",
+			evictOnDiskFull: true,
+			expectEvicted:   []string{"v1.5.0"},
+		},
+		{
+			name:            "in use",
+			evictOnDiskFull: true,
+			openOldest:      true,
+			expectEvicted:   []string{"v1.6.0"},
+			expectErr:       true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			var diskFull atomic.Bool
+
+			storagePath := t.TempDir()
+
+			manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+				EvictOnDiskFull: test.evictOnDiskFull,
+			}, artifacts.Dependencies{
+				StoragePath: storagePath,
+				CreateFile: func(name string) (io.WriteCloser, error) {
+					f, err := os.Create(name)
+					if err != nil {
+						return nil, err
+					}
+
+					// the disk is full until the oldest extracted Talos version is evicted
+					if _, statErr := os.Stat(filepath.Join(storagePath, "v1.5.0")); diskFull.Load() && statErr == nil {
+						return diskFullWriter{f}, nil
+					}
+
+					return f, nil
+				},
+			})
+
+			for _, tag := range []string{"v1.5.0", "v1.6.0", "v1.7.0"} {
+				pushImager(t, registryHost, tag)
+			}
+
+			for _, version := range []string{"1.6.0", "1.5.0"} {
+				_, err := manager.Get(ctx, version, artifacts.ArchAmd64, artifacts.KindKernel)
+				require.NoError(t, err)
+			}
+
+			if test.openOldest {
+				r, err := manager.Open(ctx, "1.5.0", artifacts.ArchAmd64, artifacts.KindKernel)
+				require.NoError(t, err)
+
+				t.Cleanup(func() {
+					require.NoError(t, r.Close())
+				})
+			}
+
+			diskFull.Store(true)
+
+			path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			if test.expectErr {
+				require.ErrorIs(t, err, artifacts.ErrDiskFull)
+				require.ErrorIs(t, err, syscall.ENOSPC)
+			} else {
+				require.NoError(t, err)
+				assert.FileExists(t, path)
+			}
+
+			for _, tag := range []string{"v1.5.0", "v1.6.0"} {
+				if slices.Contains(test.expectEvicted, tag) {
+					assert.NoDirExists(t, filepath.Join(storagePath, tag))
+				} else {
+					assert.DirExists(t, filepath.Join(storagePath, tag))
+				}
+			}
+
+			// the partial extraction is cleaned up
+			assert.NoDirExists(t, filepath.Join(storagePath, "v1.7.0-tmp"))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// ensureFreeSpace checks that the storage has at least minFreeBytes free before fetching for the tag,
+// e.g. Options.MinFreeDiskBytes.
+//
+// The free space is checked on the filesystems of the storage and of all Options.StoragePathPerArch roots.
+// If the free space is below the threshold, other extracted Talos versions which are not in use are evicted,
+// oldest first, until the threshold is met. Evicted versions which are still open are only removed
+// after Options.EvictionGracePeriod, so the free space is not checked again: the size of the evicted
+// versions is counted as freed instead.
+func (m *Manager) ensureFreeSpace(tag string, minFreeBytes int64) error {
+	if minFreeBytes <= 0 {
+		return nil
+	}
+
+	roots := m.storageRoots()
+	free := make(map[string]int64, len(roots))
+
+	for _, root := range roots {
+		rootFree, err := m.deps.FreeSpace(root)
+		if err != nil {
+			return err
+		}
+
+		free[root] = rootFree
+	}
+
+	if lowSpaceRoot(roots, free, minFreeBytes) == "" {
+		return nil
+	}
+
+	m.retentionMu.Lock()
+	defer m.retentionMu.Unlock()
+
+	tags, err := m.extractedTags()
+	if err != nil {
+		return err
+	}
+
+	m.sortOldestFirst(tags)
+
+	for _, extracted := range tags {
+		if extracted == tag || m.fetchInProgress(extracted) || m.readers.active(extracted) {
+			continue
+		}
+
+		sizes, err := m.extractedSizes(extracted)
+		if err != nil {
+			return err
+		}
+
+		// evicting the version doesn't help the filesystems which are low on space
+		if !slices.ContainsFunc(roots, func(root string) bool { return free[root] < minFreeBytes && sizes[root] > 0 }) {
+			continue
+		}
+
+		m.logger.Warn("free disk space is low, evicting Talos version",
+			zap.String("tag", extracted), zap.Any("free_bytes", free), zap.Int64("min_free_bytes", minFreeBytes))
+
+		if err = m.evictExtracted(extracted); err != nil {
+			return err
+		}
+
+		for root, size := range sizes {
+			free[root] += size
+		}
+
+		if lowSpaceRoot(roots, free, minFreeBytes) == "" {
+			return nil
+		}
+	}
+
+	root := lowSpaceRoot(roots, free, minFreeBytes)
+
+	return fmt.Errorf("%w: %d bytes free in %q, %d bytes required", ErrInsufficientDiskSpace, free[root], root, minFreeBytes)
+}
+
+// sortOldestFirst sorts the extracted Talos versions oldest first, unparsable tags go first.
+func (m *Manager) sortOldestFirst(tags []string) {
+	tagParser := m.options.TagParser
+	if tagParser == nil {
+		tagParser = parseTagTolerant
+	}
+
+	slices.SortFunc(tags, func(a, b string) int {
+		versionA, _ := tagParser(a)
+		versionB, _ := tagParser(b)
+
+		return versionA.Compare(versionB)
+	})
+}
+
+// storageRoots returns the storage path and the per-architecture storage roots.
+func (m *Manager) storageRoots() []string {
+	roots := []string{m.storagePath}
+
+	for _, root := range m.archRoots {
+		if !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+
+	slices.Sort(roots[1:])
+
+	return roots
+}
+
+// lowSpaceRoot returns the first storage root with less than minFreeBytes free, if any.
+func lowSpaceRoot(roots []string, free map[string]int64, minFreeBytes int64) string {
+	for _, root := range roots {
+		if free[root] < minFreeBytes {
+			return root
+		}
+	}
+
+	return ""
+}
+
+// extractedSizes returns the size of the files of the extracted Talos version in each storage root.
+func (m *Manager) extractedSizes(tag string) (map[string]int64, error) {
+	paths := map[string][]string{
+		m.storagePath: {
+			filepath.Join(m.storagePath, tag),
+			filepath.Join(m.storagePath, chunksDir, tag),
+			filepath.Join(m.storagePath, tag+provenanceSuffix),
+			filepath.Join(m.storagePath, tag+checksumSuffix),
+		},
+	}
+
+	for _, root := range m.archRoots {
+		paths[root] = append(paths[root], filepath.Join(root, tag))
+	}
+
+	sizes := make(map[string]int64, len(paths))
+
+	for root, rootPaths := range paths {
+		for _, path := range rootPaths {
+			size, err := filesSize(path)
+			if err != nil {
+				return nil, err
+			}
+
+			sizes[root] += size
+		}
+	}
+
+	return sizes, nil
+}
+
+// filesSize returns the total size of the regular files under the path, links are not followed.
+func filesSize(path string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error measuring %q: %w", path, err)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,131 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xslices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestMinFreeDiskBytes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	// each extracted Talos version takes 60 bytes out of 100
+	freeSpace := func(string) (int64, error) {
+		free := int64(100)
+
+		for _, tag := range []string{"v1.6.0", "v1.7.0", "v1.8.0"} {
+			if _, err := os.Stat(filepath.Join(storagePath, tag)); err == nil {
+				free -= 60
+			}
+		}
+
+		return free, nil
+	}
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		MinFreeDiskBytes: 50,
+	}, artifacts.Dependencies{StoragePath: storagePath, FreeSpace: freeSpace})
+
+	for _, tag := range []string{"v1.6.0", "v1.7.0", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	_, err := manager.Get(ctx, "1.6.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	// v1.6.0 is evicted to make space for v1.7.0
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	versions, err := manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.7.0"}, xslices.Map(versions, semver.Version.String))
+
+	// the version with open readers is not evicted
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { r.Close() }) //nolint:errcheck
+
+	_, err = manager.Get(ctx, "1.8.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.ErrorIs(t, err, artifacts.ErrInsufficientDiskSpace)
+
+	_, err = os.Stat(filepath.Join(storagePath, "v1.8.0"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestMinFreeDiskBytesPerArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	arm64Root := t.TempDir()
+
+	// the free space of the arm64 root never reflects the evictions, as if the evicted versions were still open
+	var arm64Free atomic.Int64
+
+	arm64Free.Store(1 << 30)
+
+	freeSpace := func(path string) (int64, error) {
+		if strings.HasPrefix(path, arm64Root) {
+			return arm64Free.Load(), nil
+		}
+
+		return 1 << 30, nil
+	}
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		MinFreeDiskBytes:   1000,
+		StoragePathPerArch: map[artifacts.Arch]string{artifacts.ArchArm64: arm64Root},
+	}, artifacts.Dependencies{FreeSpace: freeSpace})
+
+	for _, tag := range []string{"v1.6.0", "v1.7.0", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+			"usr/install/arm64/vmlinuz": bytes.Repeat([]byte("k"), 1000),
+		})
+	}
+
+	_, err := manager.Get(ctx, "1.6.0", artifacts.ArchArm64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	arm64Free.Store(500)
+
+	// v1.6.0 is evicted from the arm64 root, and the size of its artifacts is counted as freed
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	versions, err := manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.7.0"}, xslices.Map(versions, semver.Version.String))
+
+	// the version with open readers is not evicted
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { r.Close() }) //nolint:errcheck
+
+	_, err = manager.Get(ctx, "1.8.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.ErrorIs(t, err, artifacts.ErrInsufficientDiskSpace)
+	assert.ErrorContains(t, err, arm64Root)
+}
@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the type of the manager lifecycle event.
+type EventType string
+
+// Supported event types.
+const (
+	EventFetchStarted   EventType = "fetch-started"
+	EventFetchSucceeded EventType = "fetch-succeeded"
+	EventFetchFailed    EventType = "fetch-failed"
+	EventCacheHit       EventType = "cache-hit"
+	EventEviction       EventType = "eviction"
+)
+
+// Event is the manager lifecycle event.
+type Event struct {
+	Timestamp time.Time
+	// Error is set for EventFetchFailed.
+	Error error
+	Type  EventType
+	// Key identifies the fetched artifact: Talos version tag, image path, etc.
+	Key string
+}
+
+// eventBufferSize is the size of the buffer of each subscriber.
+//
+// Events are dropped if the subscriber doesn't keep up.
+const eventBufferSize = 64
+
+// eventBus delivers events to multiple subscribers without blocking the publisher.
+type eventBus struct {
+	now         func() time.Time
+	mu          sync.Mutex
+	subscribers []chan Event
+	closed      bool
+}
+
+func (b *eventBus) subscribe() <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, eventBufferSize)
+
+	if b.closed {
+		close(ch)
+
+		return ch
+	}
+
+	b.subscribers = append(b.subscribers, ch)
+
+	return ch
+}
+
+func (b *eventBus) publish(eventType EventType, key string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || len(b.subscribers) == 0 {
+		return
+	}
+
+	ev := Event{
+		Timestamp: b.now(),
+		Type:      eventType,
+		Key:       key,
+		Error:     err,
+	}
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default: // drop the event, subscriber is too slow
+		}
+	}
+}
+
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.closed = true
+
+	for _, ch := range b.subscribers {
+		close(ch)
+	}
+
+	b.subscribers = nil
+}
+
+// Events subscribes to the manager lifecycle events.
+//
+// Events are delivered without blocking the manager, so they are dropped if the subscriber doesn't keep up.
+// The channel is closed when the manager is closed.
+func (m *Manager) Events() <-chan Event {
+	return m.events.subscribe()
+}
+
+// observeFetch wraps the fetch function to publish fetch lifecycle events.
+func (m *Manager) observeFetch(key string, fetch func() error) func() (any, error) {
+	return func() (any, error) {
+		m.events.publish(EventFetchStarted, key, nil)
+
+		err := fetch()
+		if err != nil {
+			m.events.publish(EventFetchFailed, key, err)
+		} else {
+			m.events.publish(EventFetchSucceeded, key, nil)
+		}
+
+		return nil, err
+	}
+}
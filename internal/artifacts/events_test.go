@@ -0,0 +1,55 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	events1, events2 := manager.Events(), manager.Events()
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/events")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	for range 2 {
+		_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, manager.Close())
+
+	for _, events := range []<-chan artifacts.Event{events1, events2} {
+		var eventTypes []artifacts.EventType
+
+		for ev := range events {
+			eventTypes = append(eventTypes, ev.Type)
+		}
+
+		assert.Equal(t, []artifacts.EventType{artifacts.EventFetchStarted, artifacts.EventFetchSucceeded, artifacts.EventCacheHit}, eventTypes)
+	}
+}
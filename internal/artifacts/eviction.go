@@ -0,0 +1,349 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// readerTracker counts the open readers of the extracted Talos versions.
+type readerTracker struct {
+	mu      sync.Mutex
+	readers map[string]*readerGroup
+}
+
+// readerGroup is the set of the readers of a single extraction of the Talos version.
+type readerGroup struct {
+	idle    []chan struct{}
+	count   int
+	retired bool
+}
+
+// acquire registers a reader for the tag.
+//
+// The returned release function should be called once the reader is done, retired returns true
+// once the extraction the reader belongs to is evicted.
+func (t *readerTracker) acquire(tag string) (release func(), retired func() bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.readers == nil {
+		t.readers = map[string]*readerGroup{}
+	}
+
+	group := t.readers[tag]
+	if group == nil {
+		group = &readerGroup{}
+		t.readers[tag] = group
+	}
+
+	group.count++
+
+	var once sync.Once
+
+	release = func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+
+			group.count--
+
+			if group.count > 0 {
+				return
+			}
+
+			if t.readers[tag] == group {
+				delete(t.readers, tag)
+			}
+
+			for _, ch := range group.idle {
+				close(ch)
+			}
+
+			group.idle = nil
+		})
+	}
+
+	retired = func() bool {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		return group.retired
+	}
+
+	return release, retired
+}
+
+// active returns true if there are open readers for the tag.
+func (t *readerTracker) active(tag string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.readers[tag] != nil
+}
+
+// retire detaches the current readers of the tag, as its extraction is evicted.
+//
+// The returned channel is closed when the detached readers are done, the readers acquired
+// afterwards belong to the next extraction of the tag.
+func (t *readerTracker) retire(tag string) <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := make(chan struct{})
+
+	group := t.readers[tag]
+	if group == nil {
+		close(ch)
+
+		return ch
+	}
+
+	delete(t.readers, tag)
+
+	group.retired = true
+	group.idle = append(group.idle, ch)
+
+	return ch
+}
+
+// trackedFile is the artifact opened with Open.
+type trackedFile struct {
+	*os.File
+	release func()
+}
+
+// Close the file and release the reader.
+func (f *trackedFile) Close() error {
+	defer f.release()
+
+	return f.File.Close()
+}
+
+// Open opens the artifact for the given version, arch and kind for reading.
+//
+// While the artifact is open, the extracted Talos version is not removed from the storage on eviction
+// (up to Options.EvictionGracePeriod), so that in-flight downloads are not interrupted.
+// Artifacts up to Options.InMemoryThreshold are read once and served from memory afterwards.
+func (m *Manager) Open(ctx context.Context, versionString string, arch Arch, kind Kind) (io.ReadSeekCloser, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return nil, err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheHit, releaseReader, err := m.acquireImager(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	key := memoryCacheKey{tag: tag, arch: arch, kind: kind}
+
+	if data, ok := m.memory.get(key); ok {
+		releaseReader()
+
+		m.served(tag, arch, kind, cacheHit)
+
+		return memoryFile{bytes.NewReader(data)}, nil
+	}
+
+	path, err := m.artifactPath(ctx, tag, arch, kind)
+	if err != nil {
+		releaseReader()
+
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		releaseReader()
+
+		return nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+
+	if st, statErr := f.Stat(); statErr == nil && m.memory.fits(st.Size()) {
+		defer releaseReader()
+		defer f.Close() //nolint:errcheck
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact: %w", err)
+		}
+
+		m.memory.put(key, data)
+
+		m.served(tag, arch, kind, cacheHit)
+
+		return memoryFile{bytes.NewReader(data)}, nil
+	}
+
+	m.served(tag, arch, kind, cacheHit)
+
+	return &trackedFile{File: f, release: releaseReader}, nil
+}
+
+// Acquire returns the path to the artifact like Get, and keeps it in the storage until release is called.
+//
+// On eviction, the extracted Talos version is removed once all of its artifacts are released
+// (up to Options.EvictionGracePeriod), so that the path can be used e.g. for an asset build.
+func (m *Manager) Acquire(ctx context.Context, versionString string, arch Arch, kind Kind) (path string, release func(), err error) {
+	m, releaseManager, err := m.managerFor(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer releaseManager()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return "", nil, err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cacheHit, releaseReader, err := m.acquireImager(ctx, tag)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if m.options.PrefetchLatestPatch {
+		m.prefetchLatestPatch(versionString)
+	}
+
+	path, err = m.artifactPath(ctx, tag, arch, kind)
+	if err != nil {
+		releaseReader()
+
+		return "", nil, err
+	}
+
+	m.served(tag, arch, kind, cacheHit)
+
+	return path, releaseReader, nil
+}
+
+// acquireImager makes sure the Talos version is extracted, and registers a reader of the extraction.
+//
+// If the extraction is evicted before the reader is registered (e.g. the tag is remapped), the lookup is retried,
+// so that the reader always holds the extraction which is served.
+func (m *Manager) acquireImager(ctx context.Context, tag string) (bool, func(), error) {
+	for {
+		releaseReader, retired := m.readers.acquire(tag)
+
+		cacheHit, err := m.ensureImager(ctx, tag)
+		if err != nil {
+			releaseReader()
+
+			return false, nil, err
+		}
+
+		if !retired() {
+			return cacheHit, releaseReader, nil
+		}
+
+		releaseReader()
+	}
+}
+
+// evict moves away the extracted Talos version, and removes it once there are no open readers.
+//
+// The removal is delayed up to Options.EvictionGracePeriod.
+func (m *Manager) evict(tag string) error {
+	defer m.memory.purgeTag(tag)
+
+	m.digestIndex.remove(tag)
+
+	paths := []string{filepath.Join(m.storagePath, tag), filepath.Join(m.storagePath, chunksDir, tag)}
+
+	for _, root := range m.archRoots {
+		paths = append(paths, filepath.Join(root, tag))
+	}
+
+	eviction := m.evictions.Add(1)
+
+	var evictedPaths []string
+
+	for _, path := range paths {
+		evictedPath := fmt.Sprintf("%s-evicted-%d", path, eviction)
+
+		if err := os.Rename(path, evictedPath); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return fmt.Errorf("error evicting %q: %w", path, err)
+		}
+
+		evictedPaths = append(evictedPaths, evictedPath)
+	}
+
+	if len(evictedPaths) == 0 {
+		return nil
+	}
+
+	// the readers which acquire the tag from now on read the next extraction
+	idle := m.readers.retire(tag)
+
+	if m.options.EvictionGracePeriod <= 0 {
+		return removeAll(evictedPaths)
+	}
+
+	m.evictionWg.Add(1)
+
+	go func() {
+		defer m.evictionWg.Done()
+
+		timer := time.NewTimer(m.options.EvictionGracePeriod)
+		defer timer.Stop()
+
+		select {
+		case <-idle:
+		case <-timer.C:
+			m.logger.Warn("removing evicted artifacts with open readers", zap.String("tag", tag))
+		case <-m.evictionCtx.Done():
+		}
+
+		if err := removeAll(evictedPaths); err != nil {
+			m.logger.Error("failed to remove evicted artifacts", zap.Strings("paths", evictedPaths), zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// removeAll removes all the paths.
+func removeAll(paths []string) error {
+	var errs []error
+
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestEvictionGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		ImagerPullPolicy:    artifacts.PullPolicyAlways,
+		EvictionGracePeriod: time.Minute,
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("old kernel"),
+	})
+
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	f, ok := r.(interface{ Name() string })
+	require.True(t, ok)
+
+	storagePath := filepath.Dir(filepath.Dir(filepath.Dir(f.Name())))
+
+	// remap the tag to a new digest, so that the old artifacts are evicted
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("new kernel"),
+	})
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new kernel", string(contents))
+
+	// evicted artifacts are kept while the reader is open
+	evicted, err := filepath.Glob(filepath.Join(storagePath, "v1.7.0-evicted-*"))
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+
+	contents, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "old kernel", string(contents))
+
+	require.NoError(t, r.Close())
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(evicted[0])
+
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestEvictionReaders(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		ImagerPullPolicy:    artifacts.PullPolicyAlways,
+		EvictionGracePeriod: time.Minute,
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("old kernel"),
+	})
+
+	oldPath, release, err := manager.Acquire(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	storagePath := filepath.Dir(filepath.Dir(filepath.Dir(oldPath)))
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("new kernel"),
+	})
+
+	// the reader acquired after the eviction holds the new extraction only
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	contents, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "new kernel", string(contents))
+
+	evicted, err := filepath.Glob(filepath.Join(storagePath, "v1.7.0-evicted-*"))
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+
+	// the evicted artifacts are kept while held with Acquire
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = os.Stat(evicted[0])
+	require.NoError(t, err)
+
+	release()
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(evicted[0])
+
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,20 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import "context"
+
+// NewTransport exports newTransport for the tests.
+var NewTransport = newTransport
+
+// ScanIntegrity runs a single integrity scan, as the integrity scanner does on each Options.IntegrityScanInterval.
+func (m *Manager) ScanIntegrity(ctx context.Context) error {
+	return m.scanIntegrity(ctx)
+}
+
+// StartPrefetch exports startPrefetch for the tests.
+func (m *Manager) StartPrefetch(fn func()) bool {
+	return m.startPrefetch(fn)
+}
@@ -0,0 +1,143 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/siderolabs/gen/xerrors"
+)
+
+// ExtensionArchMatrix returns the architectures supported by each extension image, keyed by the extension name
+// (e.g. "siderolabs/gvisor").
+//
+// If refs are empty, the official extensions of the Talos version are used. Multi-arch extension images support
+// the architectures of their manifests, while single-arch images support the architecture they are built for
+// (or all architectures, if not set). The architectures are cached by the extension image digest.
+func (m *Manager) ExtensionArchMatrix(ctx context.Context, versionString string, refs []ExtensionRef) (map[string][]Arch, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if len(refs) == 0 {
+		if refs, err = m.GetOfficialExtensions(ctx, versionString); err != nil {
+			return nil, err
+		}
+	}
+
+	matrix := make(map[string][]Arch, len(refs))
+
+	for _, ref := range refs {
+		if ref.Digest == "" {
+			digestRef, err := m.resolveTag(ctx, ArchAmd64, m.extensionRepository(ref).Tag(ref.TaggedReference.TagStr()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s: %w", ref.TaggedReference, err)
+			}
+
+			ref.Digest = digestRef.DigestStr()
+		}
+
+		arches, err := m.inspectExtensionArches(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		matrix[ref.TaggedReference.RepositoryStr()] = arches
+	}
+
+	return matrix, nil
+}
+
+// inspectExtensionArches returns the architectures supported by the extension image, see ExtensionArchMatrix.
+func (m *Manager) inspectExtensionArches(ctx context.Context, ref ExtensionRef) ([]Arch, error) {
+	m.extensionArchesMu.Lock()
+	arches, ok := m.extensionArches[ref.Digest]
+	m.extensionArchesMu.Unlock()
+
+	if ok {
+		return slices.Clone(arches), nil
+	}
+
+	imageRef := m.extensionRepository(ref).Digest(ref.Digest)
+
+	var desc *remote.Descriptor
+
+	if err := m.guardRegistry(RegistryOpPull, imageRef.RegistryStr(), func() error {
+		var err error
+
+		desc, err = m.pullers[ArchAmd64].Get(ctx, imageRef)
+
+		return err
+	}); err != nil {
+		var transportError *transport.Error
+
+		if errors.As(err, &transportError) && transportError.StatusCode == http.StatusNotFound {
+			return nil, xerrors.NewTaggedf[ErrNotFoundTag]("extension image %s is not found", imageRef)
+		}
+
+		return nil, fmt.Errorf("error getting extension image %s: %w", imageRef, err)
+	}
+
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("error creating index from descriptor: %w", err)
+		}
+
+		indexManifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("error reading index manifest: %w", err)
+		}
+
+		for _, manifest := range indexManifest.Manifests {
+			if manifest.Platform == nil || manifest.Platform.OS != "linux" {
+				continue
+			}
+
+			if arch := Arch(manifest.Platform.Architecture); validateArch(arch) == nil && !slices.Contains(arches, arch) {
+				arches = append(arches, arch)
+			}
+		}
+	} else {
+		img, err := desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("error creating image from descriptor: %w", err)
+		}
+
+		config, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("error reading extension image config: %w", err)
+		}
+
+		switch arch := Arch(config.Architecture); {
+		case config.Architecture == "":
+			arches = []Arch{ArchAmd64, ArchArm64}
+		case validateArch(arch) == nil:
+			arches = []Arch{arch}
+		}
+	}
+
+	slices.Sort(arches)
+
+	m.extensionArchesMu.Lock()
+
+	if m.extensionArches == nil {
+		m.extensionArches = map[string][]Arch{}
+	}
+
+	m.extensionArches[ref.Digest] = arches
+	m.extensionArchesMu.Unlock()
+
+	return slices.Clone(arches), nil
+}
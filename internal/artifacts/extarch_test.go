@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestExtensionArchMatrix(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	registryHandler := registry.New()
+
+	var manifestRequests atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+			manifestRequests.Add(1)
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         u.Host,
+		InsecureImageRegistry: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	pushImage(t, u.Host, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+
+	amd64Img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	arm64Img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64Img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: string(artifacts.ArchAmd64)}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64Img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: string(artifacts.ArchArm64)}},
+		},
+	)
+
+	require.NoError(t, remote.WriteIndex(extensionTag(t, u.Host, "siderolabs/multi-arch"), index))
+
+	indexDigest, err := index.Digest()
+	require.NoError(t, err)
+
+	singleArchImg, err := mutate.ConfigFile(empty.Image, &v1.ConfigFile{Architecture: string(artifacts.ArchArm64), OS: "linux"})
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(extensionTag(t, u.Host, "siderolabs/single-arch"), singleArchImg))
+
+	singleArchDigest, err := singleArchImg.Digest()
+	require.NoError(t, err)
+
+	anyArchImg, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(extensionTag(t, u.Host, "siderolabs/any-arch"), anyArchImg))
+
+	anyArchDigest, err := anyArchImg.Digest()
+	require.NoError(t, err)
+
+	pushImage(t, u.Host, artifacts.ExtensionManifestImage, "v1.7.0", map[string][]byte{
+		"image-digests": []byte("ghcr.io/siderolabs/multi-arch:v1.0.0@" + indexDigest.String() + "\n" +
+			"ghcr.io/siderolabs/single-arch:v1.0.0@" + singleArchDigest.String() + "\n" +
+			"ghcr.io/siderolabs/any-arch:v1.0.0@" + anyArchDigest.String() + "\n"),
+	})
+
+	expected := map[string][]artifacts.Arch{
+		"siderolabs/multi-arch":  {artifacts.ArchAmd64, artifacts.ArchArm64},
+		"siderolabs/single-arch": {artifacts.ArchArm64},
+		"siderolabs/any-arch":    {artifacts.ArchAmd64, artifacts.ArchArm64},
+	}
+
+	matrix, err := manager.ExtensionArchMatrix(ctx, "1.7.0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, matrix)
+
+	// the extension images are inspected once
+	requests := manifestRequests.Load()
+
+	matrix, err = manager.ExtensionArchMatrix(ctx, "1.7.0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, matrix)
+
+	assert.Equal(t, requests, manifestRequests.Load())
+}
@@ -0,0 +1,171 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/siderolabs/gen/xerrors"
+)
+
+// VerifyExtensionTarball verifies the cached extension image against the extension ref digest.
+//
+// The extension image is cached in OCI layout: the digests of the manifest, the config and the layers are recomputed
+// from the stored contents, and the manifest digest is compared with ref.Digest. If ref.Digest is a multi-arch index,
+// the registry is consulted for the manifest digest of the architecture. ErrExtensionCorrupt is returned on mismatch,
+// and an ErrNotFoundTag error if the extension image is not cached.
+func (m *Manager) VerifyExtensionTarball(ctx context.Context, arch Arch, ref ExtensionRef) error {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return err
+	}
+
+	if ref.Digest == "" {
+		digestRef, err := m.resolveTag(ctx, arch, m.extensionRepository(ref).Tag(ref.TaggedReference.TagStr()))
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", ref.TaggedReference, err)
+		}
+
+		ref.Digest = digestRef.DigestStr()
+	}
+
+	ociPath, ok := m.cachedExtensionPath(arch, ref.Digest)
+	if !ok {
+		return xerrors.NewTaggedf[ErrNotFoundTag]("extension %s is not cached", ref.TaggedReference)
+	}
+
+	manifestDigest, err := verifyLayout(ociPath)
+	if err != nil {
+		return fmt.Errorf("extension %s: %w", ref.TaggedReference, err)
+	}
+
+	if manifestDigest.String() == ref.Digest {
+		return nil
+	}
+
+	// the ref might point to the multi-arch index, while the image for the architecture is cached
+	imageRef := m.extensionRepository(ref).Digest(ref.Digest)
+
+	var desc *remote.Descriptor
+
+	if err = m.guardRegistry(RegistryOpPull, imageRef.RegistryStr(), func() error {
+		desc, err = m.pullers[arch].Get(ctx, imageRef)
+
+		return err
+	}); err != nil {
+		return fmt.Errorf("error getting extension image %s: %w", imageRef, err)
+	}
+
+	img, err := imageForArch(imageRef, desc, arch)
+	if err != nil {
+		return err
+	}
+
+	expected, err := img.Digest()
+	if err != nil {
+		return fmt.Errorf("error getting extension image digest: %w", err)
+	}
+
+	if manifestDigest != expected {
+		return fmt.Errorf("%w: %s is cached as %s, expected %s", ErrExtensionCorrupt, ref.TaggedReference, manifestDigest, expected)
+	}
+
+	return nil
+}
+
+// cachedExtensionPath returns the path of the cached extension image, see GetExtensionImage.
+func (m *Manager) cachedExtensionPath(arch Arch, digest string) (string, bool) {
+	for _, path := range []string{
+		// extension images used to be stored directly in the storage path
+		filepath.Join(m.storagePath, string(arch)+"-"+digest),
+		filepath.Join(m.extensionsPath, string(arch)+"-"+digest),
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// verifyLayout recomputes the digests of the single image in the OCI layout, and returns the manifest digest.
+func verifyLayout(path string) (v1.Hash, error) {
+	l, err := layout.FromPath(path)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("error reading layout: %w", err)
+	}
+
+	index, err := l.ImageIndex()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("error reading layout: %w", err)
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("error reading layout index: %w", err)
+	}
+
+	if len(indexManifest.Manifests) != 1 {
+		return v1.Hash{}, fmt.Errorf("%w: layout has %d manifests", ErrExtensionCorrupt, len(indexManifest.Manifests))
+	}
+
+	manifestDesc := indexManifest.Manifests[0]
+
+	rawManifest, err := l.Bytes(manifestDesc.Digest)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	if actual, n, err := v1.SHA256(bytes.NewReader(rawManifest)); err != nil || actual != manifestDesc.Digest || n != manifestDesc.Size {
+		return v1.Hash{}, fmt.Errorf("%w: manifest %s has digest %s", ErrExtensionCorrupt, manifestDesc.Digest, actual)
+	}
+
+	manifest, err := v1.ParseManifest(bytes.NewReader(rawManifest))
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("%w: error parsing manifest: %w", ErrExtensionCorrupt, err)
+	}
+
+	for _, desc := range append([]v1.Descriptor{manifest.Config}, manifest.Layers...) {
+		if err = verifyBlob(l, desc.Digest, desc.Size); err != nil {
+			return v1.Hash{}, err
+		}
+	}
+
+	return manifestDesc.Digest, nil
+}
+
+// verifyBlob recomputes the digest and the size of the blob in the OCI layout.
+func verifyBlob(l layout.Path, digest v1.Hash, size int64) error {
+	r, err := l.Blob(digest)
+	if err != nil {
+		return fmt.Errorf("%w: blob %s: %w", ErrExtensionCorrupt, digest, err)
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	actual, n, err := v1.SHA256(r)
+	if err != nil {
+		return fmt.Errorf("error reading blob %s: %w", digest, err)
+	}
+
+	if actual != digest || n != size {
+		return fmt.Errorf("%w: blob %s has digest %s and size %d, expected size %d", ErrExtensionCorrupt, digest, actual, n, size)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestVerifyExtensionTarball(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/verified")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	err = manager.VerifyExtensionTarball(ctx, artifacts.ArchAmd64, ref)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	ociPath, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.VerifyExtensionTarball(ctx, artifacts.ArchAmd64, ref))
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+
+	layerDigest, err := layers[1].Digest()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(ociPath, "blobs", layerDigest.Algorithm, layerDigest.Hex), []byte("tampered"), 0o644))
+
+	require.ErrorIs(t, manager.VerifyExtensionTarball(ctx, artifacts.ArchAmd64, ref), artifacts.ErrExtensionCorrupt)
+
+	require.ErrorIs(t, manager.VerifyExtensionTarball(ctx, "riscv64", ref), artifacts.ErrInvalidArch)
+}
@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"golang.org/x/sync/errgroup"
+)
+
+// feedConcurrency is the number of imager image configs fetched concurrently by VersionFeed.
+const feedConcurrency = 4
+
+// VersionFeedItem is a Talos version in the release feed.
+type VersionFeedItem struct {
+	// Published is the creation timestamp of the imager image.
+	Published time.Time
+	// ID is the canonical Talos version tag, it is stable across re-publishing of the imager image.
+	ID      string
+	Version semver.Version
+}
+
+// VersionFeed returns the available Talos versions with their publish timestamps, newest first.
+//
+// The timestamp is taken from the imager image "org.opencontainers.image.created" label,
+// falling back to the image config creation time. Timestamps are cached by the imager image digest.
+// A failure for a version doesn't abort the feed, all errors are returned joined.
+func (m *Manager) VersionFeed(ctx context.Context) ([]VersionFeedItem, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	versions, err := m.GetTalosVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		eg    errgroup.Group
+		mu    sync.Mutex
+		items []VersionFeedItem
+		errs  []error
+	)
+
+	eg.SetLimit(feedConcurrency)
+
+	for _, version := range versions {
+		eg.Go(func() error {
+			published, err := m.publishedAt(ctx, version)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to get publish timestamp of %s: %w", version, err))
+
+				return nil
+			}
+
+			items = append(items, VersionFeedItem{
+				Published: published,
+				ID:        "v" + version.String(),
+				Version:   version,
+			})
+
+			return nil
+		})
+	}
+
+	eg.Wait() //nolint:errcheck // errors are collected separately
+
+	slices.SortFunc(items, func(a, b VersionFeedItem) int {
+		return cmp.Or(b.Published.Compare(a.Published), b.Version.Compare(a.Version))
+	})
+
+	return items, errors.Join(errs...)
+}
+
+// publishedAt returns the creation timestamp of the imager image for the Talos version.
+func (m *Manager) publishedAt(ctx context.Context, version semver.Version) (time.Time, error) {
+	tag, err := m.parseTag(ctx, version.String())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	digestRef, err := m.resolveTag(ctx, ArchArm64, m.repository(ImagerImage).Tag(tag))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to resolve imager image: %w", err)
+	}
+
+	m.publishedMu.Lock()
+	published, ok := m.published[digestRef.DigestStr()]
+	m.publishedMu.Unlock()
+
+	if ok {
+		return published, nil
+	}
+
+	_, img, err := m.imagerImage(ctx, version.String(), ArchArm64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error reading imager image config: %w", err)
+	}
+
+	published = config.Created.Time
+
+	if created, ok := config.Config.Labels[labelCreated]; ok {
+		if published, err = time.Parse(time.RFC3339, created); err != nil {
+			return time.Time{}, fmt.Errorf("error parsing imager image creation timestamp %q: %w", created, err)
+		}
+	}
+
+	m.publishedMu.Lock()
+
+	if m.published == nil {
+		m.published = map[string]time.Time{}
+	}
+
+	m.published[digestRef.DigestStr()] = published
+	m.publishedMu.Unlock()
+
+	return published, nil
+}
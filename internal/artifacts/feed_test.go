@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestVersionFeed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, test := range []struct {
+		tag     string
+		created time.Time
+		label   string
+	}{
+		{tag: "v1.6.0", created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{tag: "v1.7.0", label: "2024-05-01T12:00:00Z"},
+		{tag: "v1.6.1", label: "2024-06-01T12:00:00Z"},
+	} {
+		img, err := crane.Image(map[string][]byte{"usr/install/amd64/vmlinuz": []byte("kernel " + test.tag)})
+		require.NoError(t, err)
+
+		configFile, err := img.ConfigFile()
+		require.NoError(t, err)
+
+		configFile = configFile.DeepCopy()
+		configFile.Created = v1.Time{Time: test.created}
+
+		if test.label != "" {
+			configFile.Config.Labels = map[string]string{"org.opencontainers.image.created": test.label}
+		}
+
+		img, err = mutate.ConfigFile(img, configFile)
+		require.NoError(t, err)
+
+		ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":"+test.tag, name.Insecure)
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img))
+	}
+
+	feed, err := manager.VersionFeed(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []artifacts.VersionFeedItem{
+		{Published: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), ID: "v1.6.1", Version: semver.MustParse("1.6.1")},
+		{Published: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), ID: "v1.7.0", Version: semver.MustParse("1.7.0")},
+		{Published: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: "v1.6.0", Version: semver.MustParse("1.6.0")},
+	}, feed)
+}
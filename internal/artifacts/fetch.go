@@ -7,6 +7,8 @@ package artifacts
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +21,8 @@ import (
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/siderolabs/gen/xerrors"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -26,8 +30,19 @@ import (
 type imageHandler func(ctx context.Context, logger *zap.Logger, img v1.Image) error
 
 // imageExportHandler exports the image for further processing.
+//
+// The image must have OCI or Docker media types, see checkMediaTypes.
 func imageExportHandler(exportHandler func(logger *zap.Logger, r io.Reader) error) imageHandler {
-	return func(_ context.Context, logger *zap.Logger, img v1.Image) error {
+	return func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		if err := checkMediaTypes(img); err != nil {
+			return err
+		}
+
+		img, err := imageWithProgress(img, progressFromContext(ctx))
+		if err != nil {
+			return err
+		}
+
 		logger.Info("extracting the image")
 
 		r, w := io.Pipe()
@@ -79,81 +94,349 @@ func imageOCIHandler(path string) imageHandler {
 	}
 }
 
+// stagingHandler recreates the empty staging paths before each run of the image handler,
+// so that a retried download doesn't extract over the files left by the interrupted one.
+func stagingHandler(stagingPaths []string, handler imageHandler) imageHandler {
+	return func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		for _, path := range stagingPaths {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("error removing the directory %q: %w", path, err)
+			}
+
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return fmt.Errorf("error creating directory %q: %w", path, err)
+			}
+		}
+
+		return handler(ctx, logger, img)
+	}
+}
+
 // fetchImageByTag contains combined logic of image handling: heading, downloading, verifying signatures, and exporting.
-func (m *Manager) fetchImageByTag(imageName, tag string, architecture Arch, imageHandler imageHandler) error {
-	// set a timeout for fetching, but don't bind it to any context, as we want fetch operation to finish
-	ctx, cancel := context.WithTimeout(context.Background(), FetchTimeout)
+func (m *Manager) fetchImageByTag(ctx context.Context, imageName, tag string, architecture Arch, imageHandler imageHandler) error {
+	// set a timeout for fetching, the fetch context isn't bound to the request, as we want fetch operation to finish
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
 	defer cancel()
 
 	// light check first - if the image exists, and resolve the digest
 	// it's important to do further checks by digest exactly
-	repoRef := m.imageRegistry.Repo(imageName).Tag(tag)
+	digestRef, err := m.resolveTag(ctx, architecture, m.repository(imageName).Tag(tag))
+	if err != nil {
+		return err
+	}
+
+	return m.fetchImageByDigest(ctx, digestRef, architecture, imageHandler)
+}
 
+// resolveTag resolves the tag to the image digest.
+//
+// Resolved digests are pinned in the digest cache (up to Options.DigestCacheTTL, or until Invalidate),
+// so that all artifacts for a tag are consistent.
+func (m *Manager) resolveTag(ctx context.Context, architecture Arch, repoRef name.Tag) (name.Digest, error) {
+	if digest, ok := m.digests.get(repoRef.String()); ok {
+		return repoRef.Digest(digest.String()), nil
+	}
+
+	return m.refreshTag(ctx, architecture, repoRef)
+}
+
+// refreshTag resolves the tag to the image digest bypassing the digest cache, and pins the result.
+func (m *Manager) refreshTag(ctx context.Context, architecture Arch, repoRef name.Tag) (name.Digest, error) {
 	m.logger.Debug("heading the image", zap.Stringer("image", repoRef))
 
-	descriptor, err := m.pullers[architecture].Head(ctx, repoRef)
-	if err != nil {
+	var descriptor *v1.Descriptor
+
+	if err := m.guardRegistry(RegistryOpHead, repoRef.RegistryStr(), func() error {
+		var err error
+
+		descriptor, err = m.pullers[architecture].Head(ctx, repoRef)
+
 		return err
+	}); err != nil {
+		return name.Digest{}, err
 	}
 
-	digestRef := repoRef.Digest(descriptor.Digest.String())
+	m.digests.put(repoRef.String(), descriptor.Digest)
 
-	return m.fetchImageByDigest(digestRef, architecture, imageHandler)
+	return repoRef.Digest(descriptor.Digest.String()), nil
 }
 
 // fetchImageByDigest fetches an image by digest, verifies signatures, and exports it to the storage.
-func (m *Manager) fetchImageByDigest(digestRef name.Digest, architecture Arch, imageHandler imageHandler) error {
-	// set a timeout for fetching, but don't bind it to any context, as we want fetch operation to finish
-	ctx, cancel := context.WithTimeout(context.Background(), FetchTimeout)
+func (m *Manager) fetchImageByDigest(ctx context.Context, digestRef name.Digest, architecture Arch, imageHandler imageHandler) error {
+	// set a timeout for fetching, the fetch context isn't bound to the request, as we want fetch operation to finish
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
 	defer cancel()
 
 	logger := m.logger.With(zap.Stringer("image", digestRef))
 
+	if err := m.verifySignature(ctx, logger, digestRef); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.pullImage(ctx, logger, digestRef, architecture, imageHandler)
+
+		// registries occasionally drop layer downloads, retry them with a fresh request
+		if err == nil || !errors.Is(err, io.ErrUnexpectedEOF) || attempt >= m.options.FetchRetries {
+			return err
+		}
+
+		logger.Warn("image download interrupted, retrying", zap.Int("attempt", attempt+1), zap.Error(err))
+	}
+}
+
+// pullImage pulls the image and passes it to the image handler.
+func (m *Manager) pullImage(ctx context.Context, logger *zap.Logger, digestRef name.Digest, architecture Arch, imageHandler imageHandler) error {
 	// pull down the image and extract the necessary parts
 	logger.Info("pulling the image")
 
-	desc, err := m.pullers[architecture].Get(ctx, digestRef)
-	if err != nil {
+	var desc *remote.Descriptor
+
+	if err := m.guardRegistry(RegistryOpPull, digestRef.RegistryStr(), func() error {
+		var err error
+
+		desc, err = m.pullers[architecture].Get(ctx, digestRef)
+
+		return err
+	}); err != nil {
 		return fmt.Errorf("error pulling image %s: %w", digestRef, err)
 	}
 
-	img, err := desc.Image()
+	img, err := imageForArch(digestRef, desc, architecture)
 	if err != nil {
-		return fmt.Errorf("error creating image from descriptor: %w", err)
+		return err
 	}
 
 	return imageHandler(ctx, logger, img)
 }
 
+// imageForArch resolves the image for the architecture.
+//
+// Single-arch images are used as is (platform is ignored), while multi-arch indexes are resolved to the matching platform.
+func imageForArch(ref name.Reference, desc *remote.Descriptor, architecture Arch) (v1.Image, error) {
+	if !desc.MediaType.IsIndex() {
+		img, err := desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("error creating image from descriptor: %w", err)
+		}
+
+		return img, nil
+	}
+
+	index, err := desc.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("error creating index from descriptor: %w", err)
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("error reading index manifest: %w", err)
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		if manifest.Platform == nil || manifest.Platform.OS != "linux" || manifest.Platform.Architecture != string(architecture) {
+			continue
+		}
+
+		img, err := index.Image(manifest.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("error creating image for platform linux/%s: %w", architecture, err)
+		}
+
+		return img, nil
+	}
+
+	return nil, xerrors.NewTaggedf[ErrNotFoundTag]("image %s is not available for platform linux/%s", ref, architecture)
+}
+
 // fetchImager fetches 'imager' container, and saves to the storage path.
-func (m *Manager) fetchImager(tag string) error {
+func (m *Manager) fetchImager(ctx context.Context, tag string) error {
+	// set a timeout for fetching, the fetch context isn't bound to the request, as we want fetch operation to finish
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+
 	destinationPath := filepath.Join(m.storagePath, tag)
 
-	if err := m.fetchImageByTag(ImagerImage, tag, ArchArm64, imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
-		return untar(logger, r, destinationPath+tmpSuffix)
-	})); err != nil {
+	digestRef, err := m.resolveTag(ctx, ArchArm64, m.repository(ImagerImage).Tag(tag))
+	if err != nil {
+		return err
+	}
+
+	if err = m.ensureFreeSpace(tag, m.options.MinFreeDiskBytes); err != nil {
+		return err
+	}
+
+	var checksums map[string]string
+
+	// architectures with own storage roots are staged there, and linked into the Talos version directory
+	archDestinations := make(map[Arch]string, len(m.archRoots))
+	stagingPaths := []string{destinationPath + tmpSuffix}
+
+	for arch, root := range m.archRoots {
+		archDestinations[arch] = filepath.Join(root, tag+tmpSuffix)
+		stagingPaths = append(stagingPaths, archDestinations[arch])
+	}
+
+	if err = m.retryOnDiskFull(tag, stagingPaths, func() error {
+		if err := m.fetchImageByDigest(ctx, digestRef, ArchArm64, stagingHandler(stagingPaths, func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+			// single-arch imager images might not nest the output under the architecture directory
+			arch, err := imageArch(img)
+			if err != nil {
+				return err
+			}
+
+			return m.upstreamExportHandler(upstreamImagerKey(digestRef), func(logger *zap.Logger, r io.Reader) error {
+				var extractErr error
+
+				checksums, extractErr = m.untar(logger, r, destinationPath+tmpSuffix, archDestinations, nil)
+				if extractErr != nil {
+					return extractErr
+				}
+
+				if extractErr = linkArchDestinations(destinationPath+tmpSuffix, archDestinations); extractErr != nil {
+					return extractErr
+				}
+
+				if m.options.ValidateImagerOutputs {
+					if extractErr = validateOutputs(logger, destinationPath+tmpSuffix, checksums); extractErr != nil {
+						return extractErr
+					}
+				}
+
+				checksums, extractErr = normalizeSingleArch(logger, destinationPath+tmpSuffix, archDestinations, arch, checksums)
+
+				return extractErr
+			})(ctx, logger, img)
+		})); err != nil {
+			return err
+		}
+
+		return m.runPostExtractHook(ctx, tag, destinationPath+tmpSuffix, checksums)
+	}); err != nil {
+		return err
+	}
+
+	// replace previously extracted artifacts, if any
+	if err = m.evict(tag); err != nil {
+		return err
+	}
+
+	if err = os.Rename(destinationPath+tmpSuffix, destinationPath); err != nil {
+		return err
+	}
+
+	for arch, root := range m.archRoots {
+		if err = finalizeArchRoot(destinationPath, filepath.Join(root, tag), arch); err != nil {
+			return err
+		}
+	}
+
+	if err = os.WriteFile(destinationPath+provenanceSuffix, []byte(digestRef.DigestStr()), 0o644); err != nil {
+		return fmt.Errorf("error writing provenance: %w", err)
+	}
+
+	if err = writeChecksums(destinationPath+checksumSuffix, checksums); err != nil {
 		return err
 	}
 
-	return os.Rename(destinationPath+tmpSuffix, destinationPath)
+	m.digestIndex.add(tag, checksums)
+
+	return nil
+}
+
+// removePartial cleans up partially fetched artifacts.
+func (m *Manager) removePartial(paths ...string) {
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			m.logger.Error("failed to clean up partially fetched artifacts", zap.String("path", path), zap.Error(err))
+		}
+	}
+}
+
+// imagerChanged checks whether the imager tag points to a different digest than the extracted artifacts.
+func (m *Manager) imagerChanged(ctx context.Context, tag string) (bool, error) {
+	provenance, err := os.ReadFile(filepath.Join(m.storagePath, tag+provenanceSuffix))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("error reading provenance: %w", err)
+	}
+
+	digestRef, err := m.refreshTag(ctx, ArchArm64, m.repository(ImagerImage).Tag(tag))
+	if err != nil {
+		return false, err
+	}
+
+	return digestRef.DigestStr() != string(provenance), nil
+}
+
+// extensionOCIHandler exports the extension image to the OCI format.
+//
+// Extensions might be published as OCI artifacts with a non-standard config and layer media types,
+// so only the manifest media type is checked, and the image is only required to have layers.
+func extensionOCIHandler(path string) imageHandler {
+	ociHandler := imageOCIHandler(path)
+
+	return func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		if err := checkManifestMediaType(img); err != nil {
+			return err
+		}
+
+		layers, err := img.Layers()
+		if err != nil {
+			return fmt.Errorf("error reading image layers: %w", err)
+		}
+
+		if len(layers) == 0 {
+			return errors.New("extension image has no layers")
+		}
+
+		return ociHandler(ctx, logger, img)
+	}
 }
 
 // fetchExtensionImage fetches a specified extension image and exports it to the storage as OCI.
-func (m *Manager) fetchExtensionImage(arch Arch, ref ExtensionRef, destPath string) error {
-	imageRef := m.imageRegistry.Repo(ref.TaggedReference.RepositoryStr()).Digest(ref.Digest)
+func (m *Manager) fetchExtensionImage(ctx context.Context, arch Arch, ref ExtensionRef, destPath string) error {
+	imageRef := m.extensionRepository(ref).Digest(ref.Digest)
+
+	if err := m.verifyExtensionSignature(ctx, ref, imageRef); err != nil {
+		return err
+	}
+
+	if err := m.ensureFreeSpace("", m.options.MinFreeDiskBytes); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create extensions directory: %w", err)
+	}
+
+	handler := m.upstreamLayoutHandler(upstreamExtensionKey(arch, ref.Digest), destPath+tmpSuffix, extensionOCIHandler(destPath+tmpSuffix))
+
+	if err := m.fetchImageByDigest(ctx, imageRef, arch, handler); err != nil {
+		m.removePartial(destPath + tmpSuffix)
 
-	if err := m.fetchImageByDigest(imageRef, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
 		return err
 	}
 
+	if m.options.FetchExtensionSBOMs {
+		if err := m.fetchSBOM(ctx, imageRef, destPath+sbomSuffix); err != nil {
+			m.logger.Warn("failed to fetch extension SBOM", zap.Stringer("image", imageRef), zap.Error(err))
+		}
+	}
+
 	return os.Rename(destPath+tmpSuffix, destPath)
 }
 
 // fetchOverlayImage fetches a specified overlay image and exports it to the storage as OCI.
-func (m *Manager) fetchOverlayImage(arch Arch, ref OverlayRef, destPath string) error {
-	imageRef := m.imageRegistry.Repo(ref.TaggedReference.RepositoryStr()).Digest(ref.Digest)
+func (m *Manager) fetchOverlayImage(ctx context.Context, arch Arch, ref OverlayRef, destPath string) error {
+	imageRef := m.repository(ref.TaggedReference.RepositoryStr()).Digest(ref.Digest)
+
+	if err := m.fetchImageByDigest(ctx, imageRef, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
+		m.removePartial(destPath + tmpSuffix)
 
-	if err := m.fetchImageByDigest(imageRef, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
 		return err
 	}
 
@@ -161,20 +444,40 @@ func (m *Manager) fetchOverlayImage(arch Arch, ref OverlayRef, destPath string)
 }
 
 // fetchInstallerImage fetches a Talos installer image and exports it to the storage.
-func (m *Manager) fetchInstallerImage(arch Arch, versionTag string, destPath string) error {
-	if err := m.fetchImageByTag(InstallerImage, versionTag, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
+func (m *Manager) fetchInstallerImage(ctx context.Context, arch Arch, versionTag string, destPath string) error {
+	if err := m.fetchImageByTag(ctx, InstallerImage, versionTag, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
+		m.removePartial(destPath + tmpSuffix)
+
 		return err
 	}
 
 	return os.Rename(destPath+tmpSuffix, destPath)
 }
 
-func untar(logger *zap.Logger, r io.Reader, destination string) error {
+// untar extracts the imager artifacts, and returns the SHA-256 checksums of the extracted files.
+//
+// Extraction fails with ErrArtifactTooLarge on any file larger than Options.MaxArtifactSize.
+// If match is set, only the files it matches are extracted.
+// Symlinks are skipped unless Options.FollowSymlinks is set; entries which would end up outside of the destination
+// fail the extraction with ErrUnsafePath.
+//
+// The files of the architectures in archDestinations are extracted to the specified destinations instead
+// (keeping the same relative paths).
+func (m *Manager) untar(logger *zap.Logger, r io.Reader, destination string, archDestinations map[Arch]string, match func(relPath string) bool) (map[string]string, error) {
 	const usrInstallPrefix = "usr/install/"
 
+	maxSize, followSymlinks := m.options.MaxArtifactSize, m.options.FollowSymlinks
+
 	tr := tar.NewReader(r)
 
 	size := int64(0)
+	checksums := map[string]string{}
+
+	type extractedSymlink struct {
+		root, path string
+	}
+
+	var symlinks []extractedSymlink
 
 	for {
 		hdr, err := tr.Next()
@@ -183,42 +486,156 @@ func untar(logger *zap.Logger, r io.Reader, destination string) error {
 				break
 			}
 
-			return fmt.Errorf("error reading tar header: %w", err)
+			return nil, fmt.Errorf("error reading tar header: %w", err)
 		}
 
-		if hdr.Typeflag != tar.TypeReg || !strings.HasPrefix(hdr.Name, usrInstallPrefix) { // skip
+		relPath, ok := strings.CutPrefix(hdr.Name, usrInstallPrefix)
+
+		isSymlink := hdr.Typeflag == tar.TypeSymlink && followSymlinks
+
+		if (hdr.Typeflag != tar.TypeReg && !isSymlink) || !ok || (match != nil && !match(relPath)) { // skip
 			_, err = io.Copy(io.Discard, tr)
 			if err != nil {
-				return fmt.Errorf("error skipping data: %w", err)
+				return nil, fmt.Errorf("error skipping data: %w", err)
 			}
 
 			continue
 		}
 
-		destPath := filepath.Join(destination, hdr.Name[len(usrInstallPrefix):])
+		if !filepath.IsLocal(relPath) {
+			return nil, fmt.Errorf("%w: %q", ErrUnsafePath, hdr.Name)
+		}
+
+		if maxSize > 0 && hdr.Size > maxSize {
+			return nil, fmt.Errorf("%w: %q is %d bytes", ErrArtifactTooLarge, hdr.Name, hdr.Size)
+		}
+
+		root := destination
+
+		if archDestination, ok := archDestinations[Arch(strings.SplitN(relPath, "/", 2)[0])]; ok {
+			root = archDestination
+		}
+
+		if err = os.MkdirAll(root, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating directory %q: %w", root, err)
+		}
+
+		destPath := filepath.Join(root, relPath)
+
+		// the parent directory might be reached through a symlink extracted earlier
+		if err = checkAncestorWithin(root, filepath.Dir(destPath)); err != nil {
+			return nil, fmt.Errorf("%w: %q", err, hdr.Name)
+		}
 
 		if err = os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
-			return fmt.Errorf("error creating directory %q: %w", filepath.Dir(destPath), err)
+			return nil, fmt.Errorf("error creating directory %q: %w", filepath.Dir(destPath), err)
+		}
+
+		parentPath, err := resolveWithin(root, filepath.Dir(destPath))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, hdr.Name)
+		}
+
+		if isSymlink {
+			if filepath.IsAbs(hdr.Linkname) || !filepath.IsLocal(filepath.Join(parentPath, hdr.Linkname)) {
+				return nil, fmt.Errorf("%w: %q links to %q", ErrUnsafePath, hdr.Name, hdr.Linkname)
+			}
+
+			if err = os.Symlink(hdr.Linkname, destPath); err != nil {
+				return nil, fmt.Errorf("error creating symlink %q: %w", destPath, err)
+			}
+
+			symlinks = append(symlinks, extractedSymlink{root: root, path: destPath})
+
+			continue
 		}
 
-		f, err := os.Create(destPath)
+		// don't write through the symlink extracted earlier with the same name
+		if st, statErr := os.Lstat(destPath); statErr == nil && st.Mode()&os.ModeSymlink != 0 {
+			if err = os.Remove(destPath); err != nil {
+				return nil, fmt.Errorf("error removing symlink %q: %w", destPath, err)
+			}
+		}
+
+		f, err := m.deps.CreateFile(destPath)
 		if err != nil {
-			return fmt.Errorf("error creating file %q: %w", destPath, err)
+			return nil, fmt.Errorf("error creating file %q: %w", destPath, err)
 		}
 
-		_, err = io.Copy(f, tr)
+		hash := sha256.New()
+
+		_, err = io.Copy(io.MultiWriter(f, hash), tr)
 		if err != nil {
-			return fmt.Errorf("error copying data to %q: %w", destPath, err)
+			f.Close() //nolint:errcheck
+
+			return nil, fmt.Errorf("error copying data to %q: %w", destPath, err)
 		}
 
 		if err = f.Close(); err != nil {
-			return fmt.Errorf("error closing %q: %w", destPath, err)
+			return nil, fmt.Errorf("error closing %q: %w", destPath, err)
 		}
 
+		checksums[relPath] = hex.EncodeToString(hash.Sum(nil))
 		size += hdr.Size
 	}
 
+	// symlinks might point outside through other symlinks, so check them once everything is extracted
+	for _, symlink := range symlinks {
+		if _, err := resolveWithin(symlink.root, symlink.path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				logger.Warn("removing dangling symlink", zap.String("path", symlink.path))
+
+				if err = os.Remove(symlink.path); err != nil {
+					return nil, fmt.Errorf("error removing dangling symlink %q: %w", symlink.path, err)
+				}
+
+				continue
+			}
+
+			return nil, fmt.Errorf("%w: %q", err, symlink.path)
+		}
+	}
+
 	logger.Info("extracted the image", zap.Int64("size", size), zap.String("destination", destination))
 
-	return nil
+	return checksums, nil
+}
+
+// resolveWithin resolves the symlinks in the path, and returns it relative to the root.
+//
+// Paths which resolve outside of the root fail with ErrUnsafePath.
+func resolveWithin(root, path string) (string, error) {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	relPath, err := filepath.Rel(resolvedRoot, resolvedPath)
+	if err != nil || (relPath != "." && !filepath.IsLocal(relPath)) {
+		return "", ErrUnsafePath
+	}
+
+	return relPath, nil
+}
+
+// checkAncestorWithin verifies that the closest existing ancestor of the path resolves within the root,
+// so that creating the missing directories doesn't escape the root.
+func checkAncestorWithin(root, path string) error {
+	for {
+		_, err := resolveWithin(root, path)
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		if path == root {
+			return nil
+		}
+
+		path = filepath.Dir(path)
+	}
 }
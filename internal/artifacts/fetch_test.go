@@ -0,0 +1,282 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestMaxArtifactSize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{MaxArtifactSize: 8})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("a kernel which is too large"),
+	})
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.ErrorIs(t, err, artifacts.ErrArtifactTooLarge)
+}
+
+func TestFetchRetries(t *testing.T) {
+	img, err := crane.Image(map[string][]byte{
+		"usr/install/amd64/vmlinuz": bytes.Repeat([]byte("kernel"), 1024),
+	})
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name      string
+		retries   int
+		expectErr bool
+	}{
+		{name: "no retries", retries: 0, expectErr: true},
+		{name: "retries", retries: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			var truncated atomic.Bool
+
+			registryHandler := registry.New()
+
+			// truncate the first download of the layer
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, layerDigest.String()) || !truncated.CompareAndSwap(false, true) {
+					registryHandler.ServeHTTP(w, r)
+
+					return
+				}
+
+				rec := httptest.NewRecorder()
+				registryHandler.ServeHTTP(rec, r)
+
+				w.Header().Set("Content-Length", strconv.Itoa(rec.Body.Len()))
+				w.WriteHeader(rec.Code)
+				w.Write(rec.Body.Bytes()[:rec.Body.Len()/2]) //nolint:errcheck
+			}))
+			t.Cleanup(srv.Close)
+
+			u, err := url.Parse(srv.URL)
+			require.NoError(t, err)
+
+			ref, err := name.NewTag(u.Host+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+			require.NoError(t, err)
+
+			require.NoError(t, remote.Write(ref, img))
+
+			manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+				ImageRegistry:         u.Host,
+				InsecureImageRegistry: true,
+				FetchRetries:          test.retries,
+			})
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, manager.Close())
+			})
+
+			_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			if test.expectErr {
+				assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFetchRetriesAfterPartialWrite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	kernel := bytes.Repeat([]byte("kernel"), 16*1024)
+
+	var buf bytes.Buffer
+
+	// the symlink is extracted before the download is truncated in the middle of the kernel
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "usr/install/amd64/vmlinuz", Typeflag: tar.TypeSymlink, Linkname: "vmlinuz-6.6"}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "usr/install/amd64/vmlinuz-6.6", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(kernel))}))
+	_, err := tw.Write(kernel)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	layer := static.NewLayer(buf.Bytes(), types.DockerUncompressedLayer)
+
+	layerDigest, err := layer.Digest()
+	require.NoError(t, err)
+
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+
+	var truncated atomic.Bool
+
+	registryHandler := registry.New()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, layerDigest.String()) || !truncated.CompareAndSwap(false, true) {
+			registryHandler.ServeHTTP(w, r)
+
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		registryHandler.ServeHTTP(rec, r)
+
+		w.Header().Set("Content-Length", strconv.Itoa(rec.Body.Len()))
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes()[:rec.Body.Len()/2]) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(u.Host+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(ref, img))
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         u.Host,
+		InsecureImageRegistry: true,
+		FetchRetries:          1,
+		FollowSymlinks:        true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	assert.True(t, truncated.Load())
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, kernel, contents)
+}
+
+func TestImagerSymlinks(t *testing.T) {
+	kernel := &tar.Header{Name: "usr/install/amd64/vmlinuz-6.6", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len("kernel"))}
+
+	for _, test := range []struct {
+		name           string
+		headers        []*tar.Header
+		followSymlinks bool
+		expectedErr    error
+	}{
+		{
+			name:           "within root",
+			headers:        []*tar.Header{kernel, {Name: "usr/install/amd64/vmlinuz", Typeflag: tar.TypeSymlink, Linkname: "vmlinuz-6.6"}},
+			followSymlinks: true,
+		},
+		{
+			name:        "skipped by default",
+			headers:     []*tar.Header{kernel, {Name: "usr/install/amd64/vmlinuz", Typeflag: tar.TypeSymlink, Linkname: "vmlinuz-6.6"}},
+			expectedErr: os.ErrNotExist,
+		},
+		{
+			name:           "outside of root",
+			headers:        []*tar.Header{{Name: "usr/install/amd64/vmlinuz", Typeflag: tar.TypeSymlink, Linkname: "../../../../../../etc/passwd"}},
+			followSymlinks: true,
+			expectedErr:    artifacts.ErrUnsafePath,
+		},
+		{
+			name:           "absolute",
+			headers:        []*tar.Header{{Name: "usr/install/amd64/vmlinuz", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+			followSymlinks: true,
+			expectedErr:    artifacts.ErrUnsafePath,
+		},
+		{
+			// the layer paths are cleaned when the image is flattened, so the file is outside of usr/install
+			name:        "path traversal",
+			headers:     []*tar.Header{{Name: "usr/install/../../../evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len("kernel"))}},
+			expectedErr: os.ErrNotExist,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			var buf bytes.Buffer
+
+			tw := tar.NewWriter(&buf)
+
+			for _, hdr := range test.headers {
+				require.NoError(t, tw.WriteHeader(hdr))
+
+				if hdr.Typeflag == tar.TypeReg {
+					_, err := tw.Write([]byte("kernel"))
+					require.NoError(t, err)
+				}
+			}
+
+			require.NoError(t, tw.Close())
+
+			manager, registryHost := setupManager(t, artifacts.Options{
+				FollowSymlinks: test.followSymlinks,
+			})
+
+			img, err := mutate.AppendLayers(empty.Image, static.NewLayer(buf.Bytes(), types.DockerUncompressedLayer))
+			require.NoError(t, err)
+
+			ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+			require.NoError(t, err)
+			require.NoError(t, remote.Write(ref, img))
+
+			path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "kernel", string(contents))
+		})
+	}
+}
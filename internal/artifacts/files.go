@@ -0,0 +1,209 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"go.uber.org/zap"
+)
+
+// ErrNoMatch is returned when the patterns match no files in the imager output.
+var ErrNoMatch = errors.New("no files match the patterns")
+
+// GetFiles returns paths of the imager output files for the architecture matching any of the glob patterns.
+//
+// Patterns are matched with path.Match against the paths relative to the architecture directory, e.g. "vmlinuz" or "*.xz".
+// If the Talos version is not extracted yet, only the matching files are extracted.
+// The result is keyed by the relative path, and ErrNoMatch is returned if nothing matches.
+func (m *Manager) GetFiles(ctx context.Context, versionString string, arch Arch, patterns []string) (map[string]string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range patterns {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(relPath string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			matched, _ := path.Match(pattern, relPath) //nolint:errcheck // patterns are validated above
+
+			return matched
+		})
+	}
+
+	// serve from the complete imager output if it is already extracted
+	root := filepath.Join(m.storagePath, tag, string(arch))
+
+	if _, err = os.Stat(filepath.Join(m.storagePath, tag)); err != nil {
+		root, err = m.extractFiles(ctx, tag, arch, patterns, match)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the architecture directory might be linked to its own storage root
+	if resolved, resolveErr := filepath.EvalSymlinks(root); resolveErr == nil {
+		root = resolved
+	}
+
+	files := map[string]string{}
+
+	if err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		if relPath = filepath.ToSlash(relPath); match(relPath) {
+			files[relPath] = p
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatch, strings.Join(patterns, ", "))
+	}
+
+	return files, nil
+}
+
+// extractFiles extracts only the imager output files for the architecture matching the patterns.
+//
+// The files are stored separately from the complete imager output, keyed by the patterns.
+func (m *Manager) extractFiles(ctx context.Context, tag string, arch Arch, patterns []string, match func(relPath string) bool) (string, error) {
+	sortedPatterns := slices.Clone(patterns)
+	slices.Sort(sortedPatterns)
+
+	patternsHash := sha256.Sum256([]byte(strings.Join(sortedPatterns, "\n")))
+
+	destinationPath := filepath.Join(m.storagePath, "files", tag, string(arch), hex.EncodeToString(patternsHash[:]))
+
+	if _, err := os.Stat(destinationPath); err == nil {
+		m.events.publish(EventCacheHit, destinationPath, nil)
+
+		return destinationPath, nil
+	}
+
+	resultCh := m.fetchOnce(FetchGroupImager, destinationPath, func(ctx context.Context) error {
+		return m.fetchImagerFiles(ctx, tag, arch, destinationPath, match)
+	})
+
+	select {
+	case result := <-resultCh:
+		if result.Err != nil {
+			return "", result.Err
+		}
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	return destinationPath, nil
+}
+
+// fetchImagerFiles fetches the imager image, and extracts the matching files for the architecture.
+func (m *Manager) fetchImagerFiles(ctx context.Context, tag string, arch Arch, destinationPath string, match func(relPath string) bool) error {
+	// set a timeout for fetching, the fetch context isn't bound to the request, as we want fetch operation to finish
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+
+	digestRef, err := m.resolveTag(ctx, ArchArm64, m.repository(ImagerImage).Tag(tag))
+	if err != nil {
+		return err
+	}
+
+	archPrefix := string(arch) + "/"
+
+	if err = m.retryOnDiskFull(tag, []string{destinationPath + tmpSuffix}, func() error {
+		return m.fetchImageByDigest(ctx, digestRef, ArchArm64, func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+			imgArch, err := imageArch(img)
+			if err != nil {
+				return err
+			}
+
+			return imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
+				_, extractErr := m.untar(logger, r, destinationPath+tmpSuffix, nil, func(relPath string) bool {
+					if archPath, ok := strings.CutPrefix(relPath, archPrefix); ok {
+						return match(archPath)
+					}
+
+					return singleArchOutput(imgArch, arch, relPath) && match(relPath)
+				})
+				if extractErr != nil {
+					return extractErr
+				}
+
+				// the output of a single-arch imager is normalized as on the complete extraction
+				_, extractErr = normalizeSingleArch(logger, destinationPath+tmpSuffix, nil, imgArch, nil)
+
+				return extractErr
+			})(ctx, logger, img)
+		})
+	}); err != nil {
+		return err
+	}
+
+	// the files are extracted with the architecture prefix, strip it
+	archPath := filepath.Join(destinationPath+tmpSuffix, string(arch))
+
+	if _, err = os.Stat(archPath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		// nothing matched
+		if err = os.MkdirAll(archPath, 0o755); err != nil {
+			return err
+		}
+	}
+
+	if err = os.Rename(archPath, destinationPath); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(destinationPath + tmpSuffix)
+}
@@ -0,0 +1,59 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetFiles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("amd64 kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("amd64 initramfs"),
+		"usr/install/amd64/systemd-boot": []byte("amd64 systemd-boot"),
+		"usr/install/arm64/vmlinuz":      []byte("arm64 kernel"),
+	})
+
+	files, err := manager.GetFiles(ctx, "1.7.0", artifacts.ArchAmd64, []string{"vmlinuz", "*.xz"})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	for relPath, expected := range map[string]string{"vmlinuz": "amd64 kernel", "initramfs.xz": "amd64 initramfs"} {
+		contents, err := os.ReadFile(files[relPath])
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(contents))
+	}
+
+	// non-matching files are not extracted
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(files["vmlinuz"]), "systemd-boot"))
+
+	_, err = manager.GetFiles(ctx, "1.7.0", artifacts.ArchAmd64, []string{"*.efi"})
+	assert.ErrorIs(t, err, artifacts.ErrNoMatch)
+
+	_, err = manager.GetFiles(ctx, "1.7.0", artifacts.ArchAmd64, []string{"["})
+	assert.Error(t, err)
+
+	// complete imager output is used once extracted
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	files, err = manager.GetFiles(ctx, "1.7.0", artifacts.ArchAmd64, []string{"systemd-boot"})
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
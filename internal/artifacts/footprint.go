@@ -0,0 +1,110 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// EstimateFootprint estimates the disk space used by the artifact and the extension images of the schematic.
+//
+// Nothing is downloaded: the sizes come from the image manifests, or from the disk for the already extracted
+// Talos version. For the Talos version which is not extracted yet, the estimate is the size of the imager
+// image layers, as the artifacts are extracted from them.
+func (m *Manager) EstimateFootprint(ctx context.Context, versionString string, arch Arch, kind Kind, refs []ExtensionRef) (int64, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer release()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return 0, err
+	}
+
+	size, err := m.estimateArtifactSize(ctx, versionString, arch, kind)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, ref := range refs {
+		if ref.Digest == "" {
+			digestRef, err := m.resolveTag(ctx, arch, m.extensionRepository(ref).Tag(ref.TaggedReference.TagStr()))
+			if err != nil {
+				return 0, fmt.Errorf("failed to resolve %s: %w", ref.TaggedReference, err)
+			}
+
+			ref.Digest = digestRef.DigestStr()
+		}
+
+		_, _, img, err := m.extensionImage(ctx, ref, arch)
+		if err != nil {
+			return 0, err
+		}
+
+		imgSize, err := imageSize(img)
+		if err != nil {
+			return 0, fmt.Errorf("error estimating size of %s: %w", ref.TaggedReference, err)
+		}
+
+		size += imgSize
+	}
+
+	return size, nil
+}
+
+func (m *Manager) estimateArtifactSize(ctx context.Context, versionString string, arch Arch, kind Kind) (int64, error) {
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return 0, err
+	}
+
+	if st, err := os.Stat(filepath.Join(m.storagePath, tag, string(arch), filepath.FromSlash(m.kindPath(kind)))); err == nil {
+		return st.Size(), nil
+	}
+
+	_, img, err := m.imagerImage(ctx, versionString, arch)
+	if err != nil {
+		return 0, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0, fmt.Errorf("error reading imager image manifest: %w", err)
+	}
+
+	return layersSize(manifest), nil
+}
+
+// imageSize returns the size of the image stored in OCI layout: the manifest, the config and the layers.
+func imageSize(img v1.Image) (int64, error) {
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(rawManifest)) + manifest.Config.Size + layersSize(manifest), nil
+}
+
+func layersSize(manifest *v1.Manifest) int64 {
+	var size int64
+
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	return size
+}
@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestEstimateFootprint(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/gvisor")
+	require.NoError(t, remote.Write(tag, img))
+
+	rawManifest, err := img.RawManifest()
+	require.NoError(t, err)
+
+	manifest, err := img.Manifest()
+	require.NoError(t, err)
+
+	extensionSize := int64(len(rawManifest)) + manifest.Config.Size + manifest.Layers[0].Size + manifest.Layers[1].Size
+
+	refs := []artifacts.ExtensionRef{{TaggedReference: tag}}
+
+	// before the extraction, the imager image layers are counted
+	size, err := manager.EstimateFootprint(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, refs)
+	require.NoError(t, err)
+	assert.Greater(t, size, extensionSize+int64(len("kernel")))
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	size, err = manager.EstimateFootprint(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, refs)
+	require.NoError(t, err)
+	assert.Equal(t, extensionSize+int64(len("kernel")), size)
+
+	// the extension image is not downloaded
+	assert.NoDirExists(t, filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(path))), "extensions"))
+
+	_, err = manager.EstimateFootprint(ctx, "1.7.0", "riscv64", artifacts.KindKernel, refs)
+	assert.ErrorIs(t, err, artifacts.ErrInvalidArch)
+}
@@ -0,0 +1,113 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func setupManager(t *testing.T, options artifacts.Options) (*artifacts.Manager, string) {
+	t.Helper()
+
+	return setupManagerWithDeps(t, options, artifacts.Dependencies{})
+}
+
+func setupManagerWithDeps(t *testing.T, options artifacts.Options, deps artifacts.Dependencies) (*artifacts.Manager, string) {
+	t.Helper()
+
+	srv := httptest.NewServer(registry.New())
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	options.ImageRegistry = u.Host
+	options.InsecureImageRegistry = true
+
+	manager, err := artifacts.NewManagerWithDeps(zaptest.NewLogger(t), options, deps)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	return manager, u.Host
+}
+
+func extensionTag(t *testing.T, registryHost, repository string) name.Tag {
+	t.Helper()
+
+	tag, err := name.NewTag(registryHost+"/"+repository+":v1.0.0", name.Insecure)
+	require.NoError(t, err)
+
+	return tag
+}
+
+func pushImage(t *testing.T, registryHost, repository, tag string, files map[string][]byte) {
+	t.Helper()
+
+	img, err := crane.Image(files)
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(registryHost+"/"+repository+":"+tag, name.Insecure)
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(ref, img))
+}
+
+// pushImager pushes the imager image with the amd64 kernel only, which is enough for most tests.
+func pushImager(t *testing.T, registryHost, tag string) {
+	t.Helper()
+
+	pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+}
+
+// setupBlockingRegistry returns the registry blocking the blob downloads until unblocked, or the download is canceled.
+func setupBlockingRegistry(t *testing.T) (registryHost string, blocked <-chan struct{}, unblock func()) {
+	t.Helper()
+
+	registryHandler := registry.New()
+	blockedCh := make(chan struct{}, 1)
+	unblockCh := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			select {
+			case blockedCh <- struct{}{}:
+			default:
+			}
+
+			select {
+			case <-unblockCh:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	return u.Host, blockedCh, sync.OnceFunc(func() { close(unblockCh) })
+}
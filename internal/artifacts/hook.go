@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/siderolabs/gen/maps"
+)
+
+// runPostExtractHook runs Options.PostExtractHook for each artifact extracted to the destination,
+// and updates the checksums of the artifacts transformed by the hook.
+func (m *Manager) runPostExtractHook(ctx context.Context, tag, destination string, checksums map[string]string) error {
+	if m.options.PostExtractHook == nil {
+		return nil
+	}
+
+	allKinds := append(slices.Clone(kinds), maps.Keys(m.registeredKinds())...)
+
+	for _, arch := range []Arch{ArchAmd64, ArchArm64} {
+		for _, kind := range allKinds {
+			relPath := string(arch) + "/" + m.kindPath(kind)
+			path := filepath.Join(destination, filepath.FromSlash(relPath))
+
+			if _, err := os.Stat(path); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+
+				return err
+			}
+
+			if err := m.options.PostExtractHook(ctx, tag, arch, kind, path); err != nil {
+				return fmt.Errorf("post-extract hook failed for %s/%s: %w", arch, kind, err)
+			}
+
+			checksum, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+
+			checksums[relPath] = checksum
+		}
+	}
+
+	return nil
+}
+
+// hashFile computes SHA-256 of the file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	hash := sha256.New()
+
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("error reading %q: %w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
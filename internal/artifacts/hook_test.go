@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestPostExtractHook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	var calls atomic.Int64
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		PostExtractHook: func(_ context.Context, tag string, arch artifacts.Arch, kind artifacts.Kind, path string) error {
+			calls.Add(1)
+
+			if tag == "v1.8.0" {
+				return errors.New("transform failed")
+			}
+
+			if kind != artifacts.KindKernel {
+				return nil
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			return os.WriteFile(path, append(contents, []byte(" signed for "+string(arch))...), 0o644)
+		},
+	}, artifacts.Dependencies{StoragePath: storagePath})
+
+	for _, tag := range []string{"v1.7.0", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz":      []byte("kernel"),
+			"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+		})
+	}
+
+	for range 2 {
+		path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kernel signed for amd64", string(contents))
+	}
+
+	// the hook is called once per artifact, and the transformed artifacts are cached
+	assert.EqualValues(t, 2, calls.Load())
+
+	checksums, err := os.ReadFile(filepath.Join(storagePath, "v1.7.0.sha256"))
+	require.NoError(t, err)
+
+	checksum := sha256.Sum256([]byte("kernel signed for amd64"))
+	assert.Contains(t, string(checksums), hex.EncodeToString(checksum[:])+"  amd64/vmlinuz\n")
+
+	// a failed transform fails the extraction
+	_, err = manager.Get(ctx, "1.8.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.ErrorContains(t, err, "transform failed")
+
+	for _, path := range []string{"v1.8.0", "v1.8.0-tmp"} {
+		_, err = os.Stat(filepath.Join(storagePath, path))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+}
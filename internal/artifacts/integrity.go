@@ -0,0 +1,267 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/siderolabs/gen/maps"
+	"go.uber.org/zap"
+)
+
+// checksumSuffix is the suffix of the sidecar file which stores checksums of the extracted imager artifacts.
+const checksumSuffix = ".sha256"
+
+// integrityScanBytesPerSecond limits the I/O rate of the integrity scanner.
+const integrityScanBytesPerSecond = 32 * 1024 * 1024
+
+// writeChecksums writes the checksums sidecar file in the 'sha256sum' format.
+func writeChecksums(path string, checksums map[string]string) error {
+	var sb strings.Builder
+
+	paths := maps.Keys(checksums)
+	slices.Sort(paths)
+
+	for _, relPath := range paths {
+		fmt.Fprintf(&sb, "%s  %s\n", checksums[relPath], relPath)
+	}
+
+	if err := os.WriteFile(path+tmpSuffix, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("error writing checksums: %w", err)
+	}
+
+	return os.Rename(path+tmpSuffix, path)
+}
+
+// readChecksums reads the checksums sidecar file.
+func readChecksums(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	checksums := map[string]string{}
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		checksum, relPath, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			return nil, fmt.Errorf("malformed checksum line %q", scanner.Text())
+		}
+
+		checksums[relPath] = checksum
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading checksums: %w", err)
+	}
+
+	return checksums, nil
+}
+
+// extractionComplete returns true if the checksums sidecar of the extracted Talos version is present.
+func (m *Manager) extractionComplete(tag string) bool {
+	_, err := os.Stat(filepath.Join(m.storagePath, tag+checksumSuffix))
+
+	return err == nil
+}
+
+// runIntegrityScanner periodically verifies extracted artifacts until the context is canceled.
+func (m *Manager) runIntegrityScanner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.scanIntegrity(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			m.logger.Error("integrity scan failed", zap.Error(err))
+		}
+	}
+}
+
+// scanIntegrity verifies every extracted Talos version against its checksums sidecar.
+//
+// Corrupted versions are removed from the storage and fetched again. Versions which are being fetched are skipped,
+// and versions evicted or extracted again while being verified are not considered corrupted.
+func (m *Manager) scanIntegrity(ctx context.Context) error {
+	entries, err := os.ReadDir(m.storagePath)
+	if err != nil {
+		return fmt.Errorf("error reading storage directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), checksumSuffix) {
+			continue
+		}
+
+		tag := strings.TrimSuffix(entry.Name(), checksumSuffix)
+
+		if m.fetchInProgress(tag) {
+			continue
+		}
+
+		dirInfo, err := os.Stat(filepath.Join(m.storagePath, tag))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // evicted
+			}
+
+			return err
+		}
+
+		checksums, err := readChecksums(filepath.Join(m.storagePath, entry.Name()))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // evicted
+			}
+
+			return err
+		}
+
+		err = verifyChecksums(ctx, filepath.Join(m.storagePath, tag), checksums)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		quarantined, quarantineErr := m.quarantine(tag, dirInfo)
+		if quarantineErr != nil {
+			return quarantineErr
+		}
+
+		if !quarantined {
+			m.logger.Debug("Talos version changed during the integrity scan", zap.String("tag", tag), zap.Error(err))
+
+			continue
+		}
+
+		m.logger.Error("corrupted artifacts found", zap.String("tag", tag), zap.Error(err))
+
+		m.events.publish(EventEviction, tag, nil)
+
+		resultCh := m.fetchOnce(FetchGroupImager, tag, func(ctx context.Context) error {
+			return m.fetchImager(ctx, tag)
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result := <-resultCh:
+			if result.Err != nil {
+				m.logger.Error("failed to re-fetch corrupted artifacts", zap.String("tag", tag), zap.Error(result.Err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// quarantine moves away corrupted artifacts for the tag, so that they are fetched again.
+//
+// The artifacts are kept (and false is returned) if the Talos version is being fetched, or if it has been evicted
+// or extracted again since the directory described by dirInfo was verified.
+func (m *Manager) quarantine(tag string, dirInfo os.FileInfo) (bool, error) {
+	m.retentionMu.Lock()
+	defer m.retentionMu.Unlock()
+
+	if m.fetchInProgress(tag) {
+		return false, nil
+	}
+
+	currentInfo, err := os.Stat(filepath.Join(m.storagePath, tag))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if !os.SameFile(dirInfo, currentInfo) {
+		return false, nil
+	}
+
+	if err = os.Remove(filepath.Join(m.storagePath, tag+checksumSuffix)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("error removing checksums: %w", err)
+	}
+
+	return true, m.evict(tag)
+}
+
+// verifyChecksums verifies the files in the directory against the checksums.
+func verifyChecksums(ctx context.Context, dir string, checksums map[string]string) error {
+	for relPath, expected := range checksums {
+		actual, err := hashFileThrottled(ctx, filepath.Join(dir, relPath))
+		if err != nil {
+			return err
+		}
+
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch for %q: expected %s, got %s", relPath, expected, actual)
+		}
+	}
+
+	return nil
+}
+
+// hashFileThrottled computes SHA-256 of the file, limiting the read rate to integrityScanBytesPerSecond.
+func hashFileThrottled(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	hash := sha256.New()
+	buf := make([]byte, 1024*1024)
+
+	for {
+		n, err := f.Read(buf)
+		hash.Write(buf[:n])
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("error reading %q: %w", path, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Duration(n) * time.Second / integrityScanBytesPerSecond):
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
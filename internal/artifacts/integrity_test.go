@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestOnIncompleteExtraction(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		mode      artifacts.IncompleteExtractionMode
+		expectErr bool
+	}{
+		{name: "default"},
+		{name: "retry", mode: artifacts.IncompleteExtractionRetry},
+		{name: "fail", mode: artifacts.IncompleteExtractionFail, expectErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			storagePath := t.TempDir()
+
+			manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+				OnIncompleteExtraction: test.mode,
+			}, artifacts.Dependencies{StoragePath: storagePath})
+
+			pushImager(t, registryHost, "v1.7.0")
+
+			_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			require.NoError(t, err)
+
+			// simulate an extraction interrupted before the checksums were written
+			require.NoError(t, os.Remove(filepath.Join(storagePath, "v1.7.0.sha256")))
+
+			_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			if test.expectErr {
+				require.ErrorIs(t, err, artifacts.ErrIncompleteExtraction)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.FileExists(t, filepath.Join(storagePath, "v1.7.0.sha256"))
+		})
+	}
+}
+
+func TestScanIntegrity(t *testing.T) {
+	for _, test := range []struct {
+		name string
+
+		modify func(t *testing.T, storagePath, path string)
+
+		expectExtracted bool
+	}{
+		{
+			name:            "intact",
+			modify:          func(*testing.T, string, string) {},
+			expectExtracted: true,
+		},
+		{
+			name: "corrupted",
+			modify: func(t *testing.T, _, path string) {
+				require.NoError(t, os.Remove(path))
+				require.NoError(t, os.WriteFile(path, []byte("corrupted"), 0o644))
+			},
+			expectExtracted: true,
+		},
+		{
+			// the artifacts are evicted before the checksums, so the scan might see the checksums without the artifacts
+			name: "evicted",
+			modify: func(t *testing.T, storagePath, _ string) {
+				require.NoError(t, os.RemoveAll(filepath.Join(storagePath, "v1.7.0")))
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			storagePath := t.TempDir()
+
+			manager, registryHost := setupManagerWithDeps(t, artifacts.Options{}, artifacts.Dependencies{
+				StoragePath: storagePath,
+			})
+
+			pushImager(t, registryHost, "v1.7.0")
+
+			path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			require.NoError(t, err)
+
+			test.modify(t, storagePath, path)
+
+			require.NoError(t, manager.ScanIntegrity(ctx))
+
+			if !test.expectExtracted {
+				// the evicted Talos version is not fetched again
+				assert.NoDirExists(t, filepath.Join(storagePath, "v1.7.0"))
+
+				return
+			}
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "kernel", string(contents))
+		})
+	}
+}
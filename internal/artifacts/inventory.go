@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// InventoryEntry is an extracted artifact, as written by StreamInventory.
+type InventoryEntry struct {
+	// Tag is the imager image tag of the Talos version.
+	Tag string `json:"tag"`
+	// Arch is the architecture of the artifact.
+	Arch Arch `json:"arch"`
+	// Path is the slash-separated path of the artifact relative to the architecture directory, e.g. "vmlinuz".
+	Path string `json:"path"`
+	// Size is the size of the artifact in bytes.
+	Size int64 `json:"size"`
+}
+
+// StreamInventory writes the extracted artifacts to w as newline-delimited JSON, one InventoryEntry per line.
+//
+// The storage is walked as the entries are written, so the inventory is never kept in memory.
+// Extractions in progress and incomplete extractions are skipped. The walk stops when the context is canceled.
+func (m *Manager) StreamInventory(ctx context.Context, w io.Writer) error {
+	release, err := m.enter()
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	tags, err := m.extractedTags()
+	if err != nil {
+		return err
+	}
+
+	slices.Sort(tags)
+
+	encoder := json.NewEncoder(w)
+
+	for _, tag := range tags {
+		if m.fetchInProgress(tag) || !m.extractionComplete(tag) {
+			continue
+		}
+
+		for _, arch := range []Arch{ArchAmd64, ArchArm64} {
+			if err = m.streamArchInventory(ctx, encoder, tag, arch); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// streamArchInventory writes the extracted artifacts of the Talos version for the architecture.
+func (m *Manager) streamArchInventory(ctx context.Context, encoder *json.Encoder, tag string, arch Arch) error {
+	root := filepath.Join(m.storagePath, tag, string(arch))
+
+	// the architecture directory might be linked to its own storage root
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // not extracted for the architecture, or evicted meanwhile
+			}
+
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		// links for Options.KindFilenames point to the artifacts
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		if err = encoder.Encode(InventoryEntry{
+			Tag:  tag,
+			Arch: arch,
+			Path: filepath.ToSlash(relPath),
+			Size: info.Size(),
+		}); err != nil {
+			return fmt.Errorf("error writing inventory: %w", err)
+		}
+
+		return nil
+	})
+}
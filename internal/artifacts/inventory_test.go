@@ -0,0 +1,71 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestStreamInventory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{}, artifacts.Dependencies{StoragePath: storagePath})
+
+	for _, tag := range []string{"v1.7.0", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz":       []byte("kernel " + tag),
+			"usr/install/arm64/initramfs.xz":  []byte("initramfs"),
+			"usr/install/arm64/dtb/board.dtb": []byte("dtb"),
+		})
+
+		_, err := manager.Get(ctx, tag, artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+	}
+
+	// incomplete extractions are skipped
+	require.NoError(t, os.Remove(filepath.Join(storagePath, "v1.8.0.sha256")))
+
+	var buf bytes.Buffer
+
+	require.NoError(t, manager.StreamInventory(ctx, &buf))
+
+	var entries []artifacts.InventoryEntry
+
+	decoder := json.NewDecoder(&buf)
+
+	for decoder.More() {
+		var entry artifacts.InventoryEntry
+
+		require.NoError(t, decoder.Decode(&entry))
+
+		entries = append(entries, entry)
+	}
+
+	assert.ElementsMatch(t, []artifacts.InventoryEntry{
+		{Tag: "v1.7.0", Arch: artifacts.ArchAmd64, Path: "vmlinuz", Size: int64(len("kernel v1.7.0"))},
+		{Tag: "v1.7.0", Arch: artifacts.ArchArm64, Path: "initramfs.xz", Size: int64(len("initramfs"))},
+		{Tag: "v1.7.0", Arch: artifacts.ArchArm64, Path: "dtb/board.dtb", Size: int64(len("dtb"))},
+	}, entries)
+
+	canceledCtx, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+
+	assert.ErrorIs(t, manager.StreamInventory(canceledCtx, io.Discard), context.Canceled)
+}
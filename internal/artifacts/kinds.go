@@ -0,0 +1,98 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RegisterKind registers a custom artifact kind, served from extractPath of the imager output.
+//
+// The extractPath is relative to the architecture directory of the imager output (usr/install/<arch>/),
+// e.g. "cloud/aws.raw.xz". Registered kinds are served by Get, Open and the other artifact methods
+// like the built-in ones, including the registry overrides.
+func (m *Manager) RegisterKind(kind Kind, extractPath string) error {
+	if m.parent != nil {
+		return m.parent.RegisterKind(kind, extractPath)
+	}
+
+	if name := string(kind); name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidKind, kind)
+	}
+
+	if slices.Contains(kinds, kind) {
+		return fmt.Errorf("artifact kind %q is built-in", kind)
+	}
+
+	if !filepath.IsLocal(extractPath) || path.Clean(extractPath) != extractPath || strings.Contains(extractPath, `\`) {
+		return fmt.Errorf("invalid extract path %q for artifact %q", extractPath, kind)
+	}
+
+	m.customKindsMu.Lock()
+	defer m.customKindsMu.Unlock()
+
+	if _, ok := m.customKinds[kind]; ok {
+		return fmt.Errorf("artifact kind %q is already registered", kind)
+	}
+
+	if m.customKinds == nil {
+		m.customKinds = map[Kind]string{}
+	}
+
+	m.customKinds[kind] = extractPath
+
+	return nil
+}
+
+// customKind returns the extract path of the custom artifact kind registered with RegisterKind.
+func (m *Manager) customKind(kind Kind) (string, bool) {
+	if m.parent != nil {
+		return m.parent.customKind(kind)
+	}
+
+	m.customKindsMu.RLock()
+	defer m.customKindsMu.RUnlock()
+
+	extractPath, ok := m.customKinds[kind]
+
+	return extractPath, ok
+}
+
+// registeredKinds returns the custom artifact kinds registered with RegisterKind.
+func (m *Manager) registeredKinds() map[Kind]string {
+	if m.parent != nil {
+		return m.parent.registeredKinds()
+	}
+
+	m.customKindsMu.RLock()
+	defer m.customKindsMu.RUnlock()
+
+	return maps.Clone(m.customKinds)
+}
+
+// kindSupported returns true for the built-in and the registered artifact kinds.
+func (m *Manager) kindSupported(kind Kind) bool {
+	if slices.Contains(kinds, kind) {
+		return true
+	}
+
+	_, ok := m.customKind(kind)
+
+	return ok
+}
+
+// kindPath returns the path of the artifact kind relative to the architecture directory, slash-separated.
+func (m *Manager) kindPath(kind Kind) string {
+	if extractPath, ok := m.customKind(kind); ok {
+		return extractPath
+	}
+
+	return string(kind)
+}
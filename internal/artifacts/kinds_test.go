@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestKindFilenames(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		KindFilenames: map[artifacts.Kind]string{
+			artifacts.KindKernel: "vmlinuz-amd64",
+		},
+	}, artifacts.Dependencies{StoragePath: storagePath})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+	})
+
+	for range 2 {
+		path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+		assert.Equal(t, "vmlinuz-amd64", filepath.Base(path))
+
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kernel", string(contents))
+	}
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs)
+	require.NoError(t, err)
+	assert.Equal(t, "initramfs.xz", filepath.Base(path))
+
+	// a stale link is replaced
+	linkPath := filepath.Join(storagePath, "v1.7.0", "amd64", "vmlinuz-amd64")
+
+	require.NoError(t, os.Remove(linkPath))
+	require.NoError(t, os.Symlink("initramfs.xz", linkPath))
+
+	path, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(contents))
+
+	// any other file is never overwritten
+	require.NoError(t, os.Remove(linkPath))
+	require.NoError(t, os.WriteFile(linkPath, []byte("other"), 0o644))
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorContains(t, err, "failed to link artifact")
+
+	for _, filenames := range []map[artifacts.Kind]string{
+		{artifacts.KindKernel: "../vmlinuz"},
+		{artifacts.KindKernel: "boot", artifacts.KindInitramfs: "boot"},
+		{artifacts.KindKernel: "initramfs.xz"},
+	} {
+		_, err = artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+			KindFilenames: filenames,
+		})
+		assert.Error(t, err, "%v", filenames)
+	}
+}
+
+func TestRegisterKind(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	const kindAWS artifacts.Kind = "aws.raw.xz"
+
+	require.ErrorIs(t, manager.RegisterKind("../aws", "cloud/aws.raw.xz"), artifacts.ErrInvalidKind)
+	require.Error(t, manager.RegisterKind(kindAWS, "../aws.raw.xz"))
+	require.Error(t, manager.RegisterKind(kindAWS, "/cloud/aws.raw.xz"))
+	require.Error(t, manager.RegisterKind(artifacts.KindKernel, "cloud/vmlinuz"))
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, kindAWS)
+	require.ErrorIs(t, err, artifacts.ErrInvalidKind)
+
+	require.NoError(t, manager.RegisterKind(kindAWS, "cloud/aws.raw.xz"))
+	require.Error(t, manager.RegisterKind(kindAWS, "cloud/aws.raw.xz"))
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":          []byte("kernel"),
+		"usr/install/amd64/cloud/aws.raw.xz": []byte("aws image"),
+	})
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, kindAWS)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "aws image", string(contents))
+
+	paths, err := manager.GetAllKinds(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	assert.Len(t, paths, 2)
+	assert.Equal(t, path, paths[kindAWS])
+}
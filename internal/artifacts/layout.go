@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// storageLayoutVersion is the version of the on-disk storage layout.
+//
+// Bump the version whenever the layout changes in an incompatible way, so that persistent caches are cleared.
+const storageLayoutVersion = 1
+
+// layoutVersionFile is the name of the file which stores the storage layout version.
+const layoutVersionFile = "layout.version"
+
+// preparePersistentStorage prepares the persistent storage directory for use.
+//
+// If the directory has a different (or no) layout version, its contents are removed.
+// Leftovers of interrupted fetches and evictions are removed as well.
+func preparePersistentStorage(logger *zap.Logger, path string) error {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return fmt.Errorf("failed to create persistent cache directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("failed to read persistent cache directory: %w", err)
+	}
+
+	currentVersion, err := readLayoutVersion(path)
+	if err != nil {
+		return err
+	}
+
+	layoutChanged := currentVersion != storageLayoutVersion
+
+	if layoutChanged && len(entries) > 0 {
+		logger.Warn("persistent cache has an incompatible layout, clearing it",
+			zap.String("path", path), zap.Int("layout_version", currentVersion), zap.Int("expected_layout_version", storageLayoutVersion))
+	}
+
+	if layoutChanged {
+		for _, entry := range entries {
+			if entry.Name() == layoutVersionFile {
+				continue
+			}
+
+			if err = os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+				return fmt.Errorf("failed to clean up persistent cache: %w", err)
+			}
+		}
+	} else {
+		removed, err := removeStaging(path)
+		if err != nil {
+			return fmt.Errorf("failed to clean up persistent cache: %w", err)
+		}
+
+		if removed > 0 {
+			logger.Info("removed leftovers of interrupted fetches from persistent cache", zap.String("path", path), zap.Int("count", removed))
+		}
+	}
+
+	if !layoutChanged {
+		return nil
+	}
+
+	return os.WriteFile(filepath.Join(path, layoutVersionFile), []byte(strconv.Itoa(storageLayoutVersion)+"\n"), 0o644)
+}
+
+// removeStaging removes leftovers of interrupted fetches and evictions anywhere in the storage directory.
+//
+// Staging entries are the ones with tmpSuffix (extraction and download in progress), and evicted Talos versions.
+func removeStaging(path string) (int, error) {
+	var removed int
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == path || (!strings.HasSuffix(d.Name(), tmpSuffix) && !strings.Contains(d.Name(), "-evicted-")) {
+			return nil
+		}
+
+		if err = os.RemoveAll(p); err != nil {
+			return err
+		}
+
+		removed++
+
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// readLayoutVersion reads the storage layout version, zero is returned if the version is not recorded.
+func readLayoutVersion(path string) (int, error) {
+	contents, err := os.ReadFile(filepath.Join(path, layoutVersionFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to read layout version: %w", err)
+	}
+
+	version, err := strconv.Atoi(string(bytes.TrimSpace(contents)))
+	if err != nil {
+		return 0, nil //nolint:nilerr // treat malformed version as unknown layout
+	}
+
+	return version, nil
+}
@@ -5,19 +5,31 @@
 package artifacts
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/siderolabs/gen/xerrors"
+	"github.com/siderolabs/gen/xslices"
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
 )
@@ -27,11 +39,66 @@ type Manager struct { //nolint:govet
 	options        Options
 	storagePath    string
 	schematicsPath string
+	extensionsPath string
 	logger         *zap.Logger
 	imageRegistry  name.Registry
-	pullers        map[Arch]*remote.Puller
+	pullers        map[Arch]Puller
+	remoteOptions  []remote.Option
+	breakers       *circuitBreakers
+	deps           Dependencies
+	now            func() time.Time
 
-	sf singleflight.Group
+	// allowedVersions is the parsed Options.AllowedVersions, nil if any version is allowed
+	allowedVersions map[string]struct{}
+
+	// archRoots are the storage roots from Options.StoragePathPerArch
+	archRoots map[Arch]string
+
+	// ownedStorage is set if the storage path was created by the manager, so it's removed on Close
+	ownedStorage bool
+
+	// parent is set for the registry override managers
+	parent      *Manager
+	overridesMu sync.Mutex
+	overrides   map[string]*Manager
+
+	sf         singleflight.Group
+	coalescing *coalescingTracker
+	fetchesMu  sync.Mutex
+	fetches    map[string]*inflightFetch
+
+	// fetchCtx is the parent context of all fetches, canceled on Close
+	fetchCtx    context.Context //nolint:containedctx
+	fetchCancel context.CancelFunc
+	fetchWg     sync.WaitGroup
+
+	retentionMu sync.Mutex
+
+	publishedMu sync.Mutex
+	published   map[string]time.Time
+
+	progressMu sync.Mutex
+	progress   map[string]*progressTracker
+
+	customKindsMu sync.RWMutex
+	// customKinds maps the kinds registered with RegisterKind to their extract paths
+	customKinds map[Kind]string
+
+	extensionSignaturesMu sync.Mutex
+	extensionSignatures   map[string]error
+
+	extensionArchesMu sync.Mutex
+	extensionArches   map[string][]Arch
+
+	// sbomNotAvailable keeps the expiry of the cached "no SBOM" results by the SBOM path
+	sbomNotAvailableMu sync.Mutex
+	sbomNotAvailable   map[string]time.Time
+
+	digests *digestCache
+	memory  *memoryCache
+
+	// digestIndex resolves the content-addressed identifiers of ResolveDigestPath
+	digestIndex digestIndex
 
 	officialExtensionsMu sync.Mutex
 	officialExtensions   map[string][]ExtensionRef
@@ -41,21 +108,171 @@ type Manager struct { //nolint:govet
 
 	talosVersionsMu        sync.Mutex
 	talosVersions          []semver.Version
+	talosVersionTags       map[string]string
 	talosVersionsTimestamp time.Time
+	talosVersionsGzip      []byte
+	talosVersionsText      string
+
+	events eventBus
+
+	integrityScanCancel context.CancelFunc
+	integrityScanWg     sync.WaitGroup
+
+	readers        readerTracker
+	evictions      atomic.Int64
+	evictionCtx    context.Context //nolint:containedctx
+	evictionCancel context.CancelFunc
+	evictionWg     sync.WaitGroup
+
+	prefetchSem    chan struct{}
+	prefetchCtx    context.Context //nolint:containedctx
+	prefetchCancel context.CancelFunc
+	prefetchWg     sync.WaitGroup
+
+	lifecycleMu sync.Mutex
+	closed      bool
+	inflight    sync.WaitGroup
 }
 
+// repositoryPrefixRegexp matches valid repository path components separated by slashes.
+var repositoryPrefixRegexp = regexp.MustCompile(`^[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*(/[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*)*$`)
+
 // NewManager creates a new artifacts manager.
 func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
-	tmpDir, err := os.MkdirTemp("", "image-factory")
+	return NewManagerWithDeps(logger, options, Dependencies{})
+}
+
+// NewManagerWithDeps creates a new artifacts manager with the injected dependencies.
+func NewManagerWithDeps(logger *zap.Logger, options Options, deps Dependencies) (*Manager, error) {
+	if deps.NewPuller == nil {
+		deps.NewPuller = func(options ...remote.Option) (Puller, error) {
+			return remote.NewPuller(options...)
+		}
+	}
+
+	if deps.Now == nil {
+		deps.Now = time.Now
+	}
+
+	if deps.CreateFile == nil {
+		deps.CreateFile = func(name string) (io.WriteCloser, error) {
+			return os.Create(name)
+		}
+	}
+
+	if deps.FreeSpace == nil {
+		deps.FreeSpace = freeSpace
+	}
+
+	switch options.ImagerPullPolicy {
+	case "", PullPolicyIfNotPresent, PullPolicyAlways:
+	default:
+		return nil, fmt.Errorf("unsupported imager pull policy %q", options.ImagerPullPolicy)
+	}
+
+	switch options.OnIncompleteExtraction {
+	case "", IncompleteExtractionRetry, IncompleteExtractionFail:
+	default:
+		return nil, fmt.Errorf("unsupported incomplete extraction mode %q", options.OnIncompleteExtraction)
+	}
+
+	if rewrite := options.ServedRootRewrite; rewrite != (PathRewrite{}) && (!filepath.IsAbs(rewrite.From) || !filepath.IsAbs(rewrite.To)) {
+		return nil, fmt.Errorf("invalid served root rewrite %q to %q: paths must be absolute", rewrite.From, rewrite.To)
+	}
+
+	switch options.SignaturePolicy {
+	case "", SignaturePolicyEnforce, SignaturePolicyWarnOnly:
+	default:
+		return nil, fmt.Errorf("unsupported signature policy %q", options.SignaturePolicy)
+	}
+
+	kindsByFilename := make(map[string]Kind, len(options.KindFilenames))
+
+	for kind, filename := range options.KindFilenames {
+		if filename == "" || filename == "." || filename == ".." || filepath.Base(filename) != filename {
+			return nil, fmt.Errorf("invalid file name %q for artifact %q", filename, kind)
+		}
+
+		// the links are created next to the artifacts, so they can't shadow another artifact or link
+		if other, ok := kindsByFilename[filename]; ok {
+			return nil, fmt.Errorf("duplicate file name %q for artifacts %q and %q", filename, min(kind, other), max(kind, other))
+		}
+
+		if other := Kind(filename); other != kind && slices.Contains(kinds, other) {
+			return nil, fmt.Errorf("invalid file name %q for artifact %q: conflicts with artifact %q", filename, kind, other)
+		}
+
+		kindsByFilename[filename] = kind
+	}
+
+	if err := validateKindCompression(options.KindCompression); err != nil {
+		return nil, err
+	}
+
+	if selector := options.PrefetchNewVersions; selector != nil {
+		for _, arch := range selector.Arches {
+			if err := validateArch(arch); err != nil {
+				return nil, fmt.Errorf("invalid prefetch selector: %w", err)
+			}
+		}
+
+		if selector.Concurrency < 0 || selector.MinFreeBytes < 0 {
+			return nil, fmt.Errorf("invalid prefetch selector: negative concurrency or free space")
+		}
+	}
+
+	var allowedVersions map[string]struct{}
+
+	for _, allowed := range options.AllowedVersions {
+		version, err := semver.ParseTolerant(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed version %q: %w", allowed, err)
+		}
+
+		if allowedVersions == nil {
+			allowedVersions = map[string]struct{}{}
+		}
+
+		allowedVersions[version.String()] = struct{}{}
+	}
+
+	options.RepositoryPrefix = strings.Trim(options.RepositoryPrefix, "/")
+
+	if options.RepositoryPrefix != "" && !repositoryPrefixRegexp.MatchString(options.RepositoryPrefix) {
+		return nil, fmt.Errorf("invalid repository prefix %q", options.RepositoryPrefix)
+	}
+
+	tmpDir := deps.StoragePath
+	ownedStorage := false
+
+	switch {
+	case options.PersistentCacheDir != "":
+		tmpDir = options.PersistentCacheDir
+
+		if err := preparePersistentStorage(logger, tmpDir); err != nil {
+			return nil, err
+		}
+	case tmpDir == "":
+		var err error
+
+		tmpDir, err = os.MkdirTemp("", "image-factory")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+
+		ownedStorage = true
+	}
+
+	archRoots, err := prepareArchRoots(logger, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		return nil, err
 	}
 
+	// schematics directory is created on first use
 	schematicsPath := filepath.Join(tmpDir, "schematics")
 
-	if err = os.Mkdir(schematicsPath, 0o700); err != nil {
-		return nil, fmt.Errorf("failed to create schematics directory: %w", err)
-	}
+	// extension images are kept apart from the Talos version directories, created on first use
+	extensionsPath := filepath.Join(tmpDir, "extensions")
 
 	opts := []name.Option{}
 	if options.InsecureImageRegistry {
@@ -67,10 +284,38 @@ func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
 		return nil, fmt.Errorf("failed to parse image registry: %w", err)
 	}
 
-	pullers := make(map[Arch]*remote.Puller, 2)
+	registryTransport, err := newTransport(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry transport: %w", err)
+	}
+
+	transport, err := withRedirectTransport(options, registryTransport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry transport: %w", err)
+	}
+
+	remoteOptions := []remote.Option{remote.WithTransport(transport)}
+
+	var keychains []authn.Keychain
+
+	if keychain := newPurposeKeychain(options, imageRegistry); keychain != nil {
+		keychains = append(keychains, keychain)
+	}
+
+	if options.UseDockerConfig {
+		keychains = append(keychains, authn.DefaultKeychain)
+	}
+
+	if len(keychains) > 0 {
+		remoteOptions = append(remoteOptions, remote.WithAuthFromKeychain(authn.NewMultiKeychain(keychains...)))
+	}
+
+	remoteOptions = append(remoteOptions, options.RemoteOptions...)
+
+	pullers := make(map[Arch]Puller, 2)
 
 	for _, arch := range []Arch{ArchAmd64, ArchArm64} {
-		pullers[arch], err = remote.NewPuller(
+		pullers[arch], err = deps.NewPuller(
 			append(
 				[]remote.Option{
 					remote.WithPlatform(v1.Platform{
@@ -78,7 +323,7 @@ func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
 						OS:           "linux",
 					}),
 				},
-				options.RemoteOptions...,
+				remoteOptions...,
 			)...,
 		)
 		if err != nil {
@@ -86,19 +331,132 @@ func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
 		}
 	}
 
-	return &Manager{
-		options:        options,
-		storagePath:    tmpDir,
-		schematicsPath: schematicsPath,
-		logger:         logger,
-		imageRegistry:  imageRegistry,
-		pullers:        pullers,
-	}, nil
+	m := &Manager{
+		options:         options,
+		allowedVersions: allowedVersions,
+		storagePath:     tmpDir,
+		ownedStorage:    ownedStorage,
+		archRoots:       archRoots,
+		schematicsPath:  schematicsPath,
+		extensionsPath:  extensionsPath,
+		logger:          logger,
+		imageRegistry:   imageRegistry,
+		pullers:         pullers,
+		remoteOptions:   remoteOptions,
+		breakers:        newCircuitBreakers(options.CircuitBreakerThreshold, options.CircuitBreakerWindow, options.CircuitBreakerCooldown, deps.Now),
+		digests:         newDigestCache(options.DigestCacheSize, options.DigestCacheTTL, deps.Now),
+		memory:          newMemoryCache(options.InMemoryThreshold, options.InMemoryCacheSize),
+		coalescing:      newCoalescingTracker(),
+		events:          eventBus{now: deps.Now},
+		deps:            deps,
+		now:             deps.Now,
+	}
+
+	m.fetchCtx, m.fetchCancel = context.WithCancel(context.Background())
+	m.evictionCtx, m.evictionCancel = context.WithCancel(context.Background())
+
+	m.prefetchSem = make(chan struct{}, 1)
+	m.prefetchCtx, m.prefetchCancel = context.WithCancel(context.Background())
+
+	if options.IntegrityScanInterval > 0 {
+		var scanCtx context.Context
+
+		scanCtx, m.integrityScanCancel = context.WithCancel(context.Background())
+
+		m.integrityScanWg.Add(1)
+
+		go func() {
+			defer m.integrityScanWg.Done()
+
+			m.runIntegrityScanner(scanCtx, options.IntegrityScanInterval)
+		}()
+	}
+
+	return m, nil
 }
 
 // Close the manager.
+//
+// Close waits for the requests in progress to finish, new requests fail with ErrManagerClosed.
 func (m *Manager) Close() error {
-	return os.RemoveAll(m.storagePath)
+	m.lifecycleMu.Lock()
+
+	if m.closed {
+		m.lifecycleMu.Unlock()
+
+		return nil
+	}
+
+	m.closed = true
+
+	m.lifecycleMu.Unlock()
+
+	m.inflight.Wait()
+
+	m.prefetchCancel()
+	m.prefetchWg.Wait()
+
+	if err := m.closeOverrides(); err != nil {
+		return err
+	}
+
+	if m.integrityScanCancel != nil {
+		m.integrityScanCancel()
+	}
+
+	m.integrityScanWg.Wait()
+
+	// the fetches left behind by the requests which gave up waiting
+	m.fetchCancel()
+	m.fetchWg.Wait()
+
+	m.evictionCancel()
+	m.evictionWg.Wait()
+
+	m.events.close()
+
+	if m.options.PersistentCacheDir != "" {
+		return nil
+	}
+
+	var paths []string
+
+	if m.ownedStorage {
+		paths = append(paths, m.storagePath)
+	}
+
+	for _, root := range m.archRoots {
+		paths = append(paths, root)
+	}
+
+	return removeAll(paths)
+}
+
+// enter registers the request in progress, so that Close waits for it.
+//
+// The returned release function must be called when the request is done.
+func (m *Manager) enter() (func(), error) {
+	m.lifecycleMu.Lock()
+	defer m.lifecycleMu.Unlock()
+
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+
+	m.inflight.Add(1)
+
+	return m.inflight.Done, nil
+}
+
+// Stats returns the current state of the manager.
+func (m *Manager) Stats() Stats {
+	return Stats{
+		CircuitBreakerState: m.breakers.State(),
+		CircuitBreakers:     m.breakers.States(),
+		DigestCacheHitRate:  m.digests.hitRate(),
+		Coalescing:          m.coalescing.snapshot(),
+		FetchesInFlight:     m.coalescing.inflightFetches(),
+	}
 }
 
 func (m *Manager) validateTalosVersion(ctx context.Context, version semver.Version) error {
@@ -115,78 +473,425 @@ func (m *Manager) validateTalosVersion(ctx context.Context, version semver.Versi
 }
 
 // Get returns the artifact path for the given version, arch and kind.
+//
+// See parseTag for the accepted version formats.
 func (m *Manager) Get(ctx context.Context, versionString string, arch Arch, kind Kind) (string, error) {
-	version, err := semver.Parse(versionString)
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return "", err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	cacheHit, err := m.ensureImager(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+
+	if m.options.PrefetchLatestPatch {
+		m.prefetchLatestPatch(versionString)
+	}
+
+	path, err := m.artifactPath(ctx, tag, arch, kind)
+	if err != nil {
+		return "", err
+	}
+
+	m.served(tag, arch, kind, cacheHit)
+
+	return path, nil
+}
+
+// served reports the served artifact to Options.OnServe.
+func (m *Manager) served(tag string, arch Arch, kind Kind, cacheHit bool) {
+	if m.options.OnServe != nil {
+		m.options.OnServe(tag, arch, kind, cacheHit)
+	}
+}
+
+// GetMultiArch returns the artifact paths for the given version and kind for each of the requested architectures.
+//
+// The imager image is extracted once for all architectures.
+func (m *Manager) GetMultiArch(ctx context.Context, versionString string, kind Kind, arches []Arch) (map[Arch]string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	for _, arch := range arches {
+		if err = m.validateArtifact(arch, kind); err != nil {
+			return nil, err
+		}
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = m.ensureImager(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	paths := make(map[Arch]string, len(arches))
+
+	for _, arch := range arches {
+		path, err := m.artifactPath(ctx, tag, arch, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		paths[arch] = path
+	}
+
+	return paths, nil
+}
+
+// GetAllKinds returns the artifact paths for every kind present for the given version and architecture.
+//
+// The imager image is extracted once, and the kinds missing from the imager output are skipped.
+// The kinds registered with RegisterKind are included.
+func (m *Manager) GetAllKinds(ctx context.Context, versionString string, arch Arch) (map[Kind]string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return nil, err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = m.ensureImager(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(m.storagePath, tag, string(arch)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, xerrors.NewTaggedf[ErrNotFoundTag]("no artifacts found for Talos version %s and architecture %s", tag, arch)
+		}
+
+		return nil, fmt.Errorf("failed to read artifacts: %w", err)
+	}
+
+	paths := make(map[Kind]string, len(kinds))
+
+	for _, entry := range entries {
+		kind := Kind(entry.Name())
+
+		if !slices.Contains(kinds, kind) {
+			continue
+		}
+
+		path, err := m.artifactPath(ctx, tag, arch, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		paths[kind] = path
+	}
+
+	for kind, extractPath := range m.registeredKinds() {
+		if _, err := os.Stat(filepath.Join(m.storagePath, tag, string(arch), filepath.FromSlash(extractPath))); err != nil {
+			continue
+		}
+
+		path, err := m.artifactPath(ctx, tag, arch, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		paths[kind] = path
+	}
+
+	return paths, nil
+}
+
+// ensureImager makes sure that the imager image for the tag is extracted.
+//
+// It returns true if the imager image was already extracted.
+func (m *Manager) ensureImager(ctx context.Context, tag string) (bool, error) {
+	// check if already extracted
+	_, err := os.Stat(filepath.Join(m.storagePath, tag))
+	extracted := err == nil
+
+	// the checksums are written last, after the extracted artifacts are moved in place
+	if extracted && !m.fetchInProgress(tag) && !m.extractionComplete(tag) {
+		if m.options.OnIncompleteExtraction == IncompleteExtractionFail {
+			return false, fmt.Errorf("%w: Talos version %s is missing checksums", ErrIncompleteExtraction, tag)
+		}
+
+		m.logger.Warn("incomplete extraction found, extracting again", zap.String("tag", tag))
+
+		extracted = false
+	}
+
+	if extracted && m.options.ImagerPullPolicy == PullPolicyAlways {
+		changed, err := m.imagerChanged(ctx, tag)
+		if err != nil {
+			return false, fmt.Errorf("failed to check imager image: %w", err)
+		}
+
+		extracted = !changed
+	}
+
+	if extracted {
+		m.events.publish(EventCacheHit, tag, nil)
+	} else {
+		resultCh := m.fetchOnce(FetchGroupImager, tag, func(ctx context.Context) error {
+			return m.fetchImager(ctx, tag)
+		})
+
+		// wait for the fetch to finish
+		select {
+		case result := <-resultCh:
+			if result.Err != nil {
+				return false, result.Err
+			}
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+
+		m.applyRetention()
+	}
+
+	return extracted, nil
+}
+
+// artifactPath returns the path of the extracted artifact, compressed as configured with Options.KindCompression.
+func (m *Manager) artifactPath(ctx context.Context, tag string, arch Arch, kind Kind) (string, error) {
+	// build the path
+	path := filepath.Join(m.storagePath, tag, string(arch), filepath.FromSlash(m.kindPath(kind)))
+
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("failed to find artifact: %w", err)
+	}
+
+	if m.needsCompression(kind) {
+		var err error
+
+		if path, err = m.compressArtifact(ctx, path, m.compression(kind)); err != nil {
+			return "", err
+		}
+	}
+
+	filename, ok := m.options.KindFilenames[kind]
+	if !ok || filename == filepath.Base(path) {
+		return path, nil
+	}
+
+	linkPath := filepath.Join(filepath.Dir(path), filename)
+
+	if err := linkArtifact(filepath.Base(path), linkPath); err != nil {
+		return "", fmt.Errorf("failed to link artifact: %w", err)
+	}
+
+	return linkPath, nil
+}
+
+// linkArtifact creates the symlink linkPath pointing to target.
+//
+// An existing symlink pointing elsewhere (e.g. to the artifact compressed with another scheme) is atomically replaced,
+// while any other existing file is never overwritten.
+func linkArtifact(target, linkPath string) error {
+	err := os.Symlink(target, linkPath)
+	if err == nil || !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	existing, err := os.Readlink(linkPath)
+	if err != nil {
+		return fmt.Errorf("%q exists and is not a link: %w", linkPath, err)
+	}
+
+	if existing == target {
+		return nil
+	}
+
+	tmpPath := linkPath + "-" + strconv.FormatInt(time.Now().UnixNano(), 36) + tmpSuffix
+
+	if err = os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpPath, linkPath); err != nil {
+		os.Remove(tmpPath) //nolint:errcheck
+
+		return err
+	}
+
+	return nil
+}
+
+// ServedPath rewrites the path returned by the manager (e.g. by Get) with Options.ServedRootRewrite,
+// so that it can be handed to an external server.
+//
+// The paths returned by the manager are always the local paths, valid for the image factory itself.
+// Paths outside of ServedRootRewrite.From are returned as is.
+func (m *Manager) ServedPath(path string) string {
+	rewrite := m.options.ServedRootRewrite
+	if rewrite.From == "" {
+		return path
+	}
+
+	relPath, err := filepath.Rel(filepath.Clean(rewrite.From), path)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return path
+	}
+
+	return filepath.Join(rewrite.To, relPath)
+}
+
+// GetTalosVersions returns a list of Talos versions available.
+//
+// The versions are sorted by semver precedence newest first (pre-releases go right after the release),
+// or oldest first with Options.VersionSortAscending.
+func (m *Manager) GetTalosVersions(ctx context.Context) ([]semver.Version, error) {
+	m, release, err := m.managerFor(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse version: %w", err)
+		return nil, err
+	}
+
+	defer release()
+
+	m.talosVersionsMu.Lock()
+	versions, timestamp := m.talosVersions, m.talosVersionsTimestamp
+	m.talosVersionsMu.Unlock()
+
+	if m.now().Sub(timestamp) < m.options.TalosVersionRecheckInterval {
+		return versions, nil
 	}
 
-	if err = m.validateTalosVersion(ctx, version); err != nil {
-		return "", err
+	resultCh := m.fetchOnce(FetchGroupVersions, "talos-versions", func(ctx context.Context) error {
+		_, err := m.fetchTalosVersions(ctx)
+
+		return err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-resultCh:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	m.talosVersionsMu.Lock()
+	versions = m.talosVersions
+	m.talosVersionsMu.Unlock()
+
+	return versions, nil
+}
+
+// GetTalosVersionsGzip returns a list of Talos versions available as gzip-compressed JSON.
+//
+// The list is serialized once per refresh of the Talos versions, and served from memory afterwards.
+func (m *Manager) GetTalosVersionsGzip(ctx context.Context) ([]byte, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if _, err := m.GetTalosVersions(ctx); err != nil {
+		return nil, err
+	}
+
+	m.talosVersionsMu.Lock()
+	defer m.talosVersionsMu.Unlock()
+
+	if m.talosVersionsGzip != nil {
+		return m.talosVersionsGzip, nil
 	}
 
-	tag := "v" + version.String()
+	var buf bytes.Buffer
 
-	// check if already extracted
-	if _, err = os.Stat(filepath.Join(m.storagePath, tag)); err != nil {
-		resultCh := m.sf.DoChan(tag, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchImager(tag)
-		})
+	gz := gzip.NewWriter(&buf)
 
-		// wait for the fetch to finish
-		select {
-		case result := <-resultCh:
-			if result.Err != nil {
-				return "", err
-			}
-		case <-ctx.Done():
-			return "", ctx.Err()
-		}
+	if err := json.NewEncoder(gz).Encode(
+		xslices.Map(m.talosVersions, func(v semver.Version) string {
+			return "v" + v.String()
+		}),
+	); err != nil {
+		return nil, fmt.Errorf("failed to marshal Talos versions: %w", err)
 	}
 
-	// build the path
-	path := filepath.Join(m.storagePath, tag, string(arch), string(kind))
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress Talos versions: %w", err)
+	}
+
+	m.talosVersionsGzip = buf.Bytes()
+
+	return m.talosVersionsGzip, nil
+}
 
-	_, err = os.Stat(path)
+// GetTalosVersionsText returns a list of Talos versions available as newline-separated "vX.Y.Z" lines, newest first.
+func (m *Manager) GetTalosVersionsText(ctx context.Context) (string, error) {
+	m, release, err := m.managerFor(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to find artifact: %w", err)
+		return "", err
 	}
 
-	return path, nil
-}
+	defer release()
+
+	if _, err := m.GetTalosVersions(ctx); err != nil {
+		return "", err
+	}
 
-// GetTalosVersions returns a list of Talos versions available.
-func (m *Manager) GetTalosVersions(ctx context.Context) ([]semver.Version, error) {
 	m.talosVersionsMu.Lock()
-	versions, timestamp := m.talosVersions, m.talosVersionsTimestamp
-	m.talosVersionsMu.Unlock()
+	defer m.talosVersionsMu.Unlock()
 
-	if time.Since(timestamp) < m.options.TalosVersionRecheckInterval {
-		return versions, nil
+	if m.talosVersionsText != "" {
+		return m.talosVersionsText, nil
 	}
 
-	resultCh := m.sf.DoChan("talos-versions", m.fetchTalosVersions)
+	versions := slices.Clone(m.talosVersions)
+	slices.SortStableFunc(versions, compareVersionsDescending)
 
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case result := <-resultCh:
-		if result.Err != nil {
-			return nil, result.Err
-		}
+	var sb strings.Builder
+
+	for _, version := range versions {
+		sb.WriteString("v" + version.String() + "\n")
 	}
 
-	m.talosVersionsMu.Lock()
-	versions = m.talosVersions
-	m.talosVersionsMu.Unlock()
+	m.talosVersionsText = sb.String()
 
-	return versions, nil
+	return m.talosVersionsText, nil
 }
 
 // GetOfficialExtensions returns a list of Talos extensions per Talos version available.
 //
+// See parseTag for the accepted version formats.
+//
 //nolint:dupl
 func (m *Manager) GetOfficialExtensions(ctx context.Context, versionString string) ([]ExtensionRef, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
 	tag, err := m.parseTag(ctx, versionString)
 	if err != nil {
 		return nil, err
@@ -197,11 +902,13 @@ func (m *Manager) GetOfficialExtensions(ctx context.Context, versionString strin
 	m.officialExtensionsMu.Unlock()
 
 	if ok {
+		m.events.publish(EventCacheHit, "extensions-"+tag, nil)
+
 		return extensions, nil
 	}
 
-	resultCh := m.sf.DoChan("extensions-"+tag, func() (any, error) { //nolint:contextcheck
-		return nil, m.fetchOfficialExtensions(tag)
+	resultCh := m.fetchOnce(FetchGroupExtensions, "extensions-"+tag, func(ctx context.Context) error {
+		return m.fetchOfficialExtensions(ctx, tag)
 	})
 
 	select {
@@ -220,10 +927,45 @@ func (m *Manager) GetOfficialExtensions(ctx context.Context, versionString strin
 	return extensions, nil
 }
 
+// InvalidateExtensions drops the cached official extensions of the Talos version, so that the next GetOfficialExtensions
+// fetches the extensions list again, e.g. after new extensions are published.
+//
+// The extensions manifest tag is resolved again as well. A fetch in progress is not interrupted, its waiters
+// get the extensions list it fetched. Nothing is done if the extensions of the version are not cached.
+func (m *Manager) InvalidateExtensions(versionString string) {
+	tag, err := m.normalizeVersion(versionString)
+	if err != nil {
+		return
+	}
+
+	m.talosVersionsMu.Lock()
+	imageTag, ok := m.talosVersionTags[tag[1:]]
+	m.talosVersionsMu.Unlock()
+
+	if ok {
+		tag = imageTag
+	}
+
+	m.officialExtensionsMu.Lock()
+	delete(m.officialExtensions, tag)
+	m.officialExtensionsMu.Unlock()
+
+	m.digests.remove(m.repository(ExtensionManifestImage).Tag(tag).String())
+}
+
 // GetOfficialOverlays returns a list of overlays per Talos version available.
 //
+// See parseTag for the accepted version formats.
+//
 //nolint:dupl
 func (m *Manager) GetOfficialOverlays(ctx context.Context, versionString string) ([]OverlayRef, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
 	tag, err := m.parseTag(ctx, versionString)
 	if err != nil {
 		return nil, err
@@ -234,11 +976,13 @@ func (m *Manager) GetOfficialOverlays(ctx context.Context, versionString string)
 	m.officialOverlaysMu.Unlock()
 
 	if ok {
+		m.events.publish(EventCacheHit, "overlays-"+tag, nil)
+
 		return overlays, nil
 	}
 
-	resultCh := m.sf.DoChan("overlays-"+tag, func() (any, error) { //nolint:contextcheck
-		return nil, m.fetchOfficialOverlays(tag)
+	resultCh := m.fetchOnce(FetchGroupOverlays, "overlays-"+tag, func(ctx context.Context) error {
+		return m.fetchOfficialOverlays(ctx, tag)
 	})
 
 	select {
@@ -258,24 +1002,33 @@ func (m *Manager) GetOfficialOverlays(ctx context.Context, versionString string)
 }
 
 // GetInstallerImage pulls and stoers in OCI layout installer image.
+//
+// See parseTag for the accepted version formats.
 func (m *Manager) GetInstallerImage(ctx context.Context, arch Arch, versionString string) (string, error) {
-	version, err := semver.ParseTolerant(versionString)
+	m, release, err := m.managerFor(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse version: %w", err)
+		return "", err
 	}
 
-	if err = m.validateTalosVersion(ctx, version); err != nil {
+	defer release()
+
+	if err = validateArch(arch); err != nil {
 		return "", err
 	}
 
-	tag := "v" + version.String()
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
 
 	ociPath := filepath.Join(m.storagePath, string(arch)+"-installer-"+tag)
 
 	// check if already fetched
-	if _, err := os.Stat(ociPath); err != nil {
-		resultCh := m.sf.DoChan(ociPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchInstallerImage(arch, tag, ociPath)
+	if _, err := os.Stat(ociPath); err == nil {
+		m.events.publish(EventCacheHit, ociPath, nil)
+	} else {
+		resultCh := m.fetchOnce(FetchGroupInstaller, ociPath, func(ctx context.Context) error {
+			return m.fetchInstallerImage(ctx, arch, tag, ociPath)
 		})
 
 		select {
@@ -291,14 +1044,174 @@ func (m *Manager) GetInstallerImage(ctx context.Context, arch Arch, versionStrin
 	return ociPath, nil
 }
 
+// GetInstallerImageRefByDigest returns the digest-pinned reference of the installer image for the Talos version.
+//
+// The digest is the same one used to fetch the installer image with GetInstallerImage.
+func (m *Manager) GetInstallerImageRefByDigest(ctx context.Context, versionString string) (string, error) {
+	return m.GetInstallerImageRefVariant(ctx, versionString, InstallerVariantStandard)
+}
+
+// GetInstallerImageRefVariant returns the digest-pinned reference of the installer image variant for the Talos version.
+func (m *Manager) GetInstallerImageRefVariant(ctx context.Context, versionString string, variant InstallerVariant) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	var imageName string
+
+	switch variant {
+	case InstallerVariantStandard:
+		imageName = InstallerImage
+	case InstallerVariantBase:
+		imageName = InstallerBaseImage
+	default:
+		return "", fmt.Errorf("unsupported installer variant %q", variant)
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	digestRef, err := m.resolveTag(ctx, ArchAmd64, m.repository(imageName).Tag(tag))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve installer image: %w", err)
+	}
+
+	return digestRef.String(), nil
+}
+
+// InstallerRedirectTarget returns the registry URL of the digest-pinned installer image manifest for the Talos version,
+// e.g. "https://ghcr.io/v2/siderolabs/installer/manifests/sha256:...".
+//
+// Clients can be redirected to the URL to pull the installer image directly from the registry, instead of proxying it.
+func (m *Manager) InstallerRedirectTarget(ctx context.Context, versionString string) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	digestRef, err := m.resolveTag(ctx, ArchAmd64, m.repository(InstallerImage).Tag(tag))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve installer image: %w", err)
+	}
+
+	repository := digestRef.Context()
+	target := url.URL{Scheme: repository.Scheme(), Host: repository.RegistryStr()}
+
+	return target.JoinPath("v2", repository.RepositoryStr(), "manifests", digestRef.DigestStr()).String(), nil
+}
+
+// ImagerImageRef returns the digest-pinned reference of the imager image for the Talos version and architecture.
+//
+// For multi-arch imager images, the reference points to the image for the architecture, not to the index.
+func (m *Manager) ImagerImageRef(ctx context.Context, versionString string, arch Arch) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return "", err
+	}
+
+	digestRef, img, err := m.imagerImage(ctx, versionString, arch)
+	if err != nil {
+		return "", err
+	}
+
+	imgDigest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("error getting imager image digest: %w", err)
+	}
+
+	return digestRef.Context().Digest(imgDigest.String()).String(), nil
+}
+
+// imagerImage resolves the imager image for the Talos version and architecture, without pulling the layers.
+func (m *Manager) imagerImage(ctx context.Context, versionString string, arch Arch) (name.Digest, v1.Image, error) {
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return name.Digest{}, nil, err
+	}
+
+	digestRef, err := m.resolveTag(ctx, ArchArm64, m.repository(ImagerImage).Tag(tag))
+	if err != nil {
+		return name.Digest{}, nil, fmt.Errorf("failed to resolve imager image: %w", err)
+	}
+
+	var desc *remote.Descriptor
+
+	if err = m.guardRegistry(RegistryOpPull, digestRef.RegistryStr(), func() error {
+		desc, err = m.pullers[arch].Get(ctx, digestRef)
+
+		return err
+	}); err != nil {
+		return name.Digest{}, nil, fmt.Errorf("error getting imager image %s: %w", digestRef, err)
+	}
+
+	img, err := imageForArch(digestRef, desc, arch)
+	if err != nil {
+		return name.Digest{}, nil, err
+	}
+
+	return digestRef, img, nil
+}
+
 // GetExtensionImage pulls and stores in OCI layout an extension image.
+//
+// If the ref doesn't have the digest, the tag is resolved to the digest first,
+// so that the cached image is shared between tag and digest refs.
 func (m *Manager) GetExtensionImage(ctx context.Context, arch Arch, ref ExtensionRef) (string, error) {
-	ociPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest)
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return "", err
+	}
+
+	if ref.Digest == "" {
+		digestRef, err := m.resolveTag(ctx, arch, m.extensionRepository(ref).Tag(ref.TaggedReference.TagStr()))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", ref.TaggedReference, err)
+		}
+
+		ref.Digest = digestRef.DigestStr()
+	}
+
+	// extension images used to be stored directly in the storage path
+	legacyOCIPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest)
+
+	if _, err := os.Stat(legacyOCIPath); err == nil {
+		m.events.publish(EventCacheHit, legacyOCIPath, nil)
+
+		return legacyOCIPath, nil
+	}
+
+	ociPath := filepath.Join(m.extensionsPath, string(arch)+"-"+ref.Digest)
 
 	// check if already fetched
-	if _, err := os.Stat(ociPath); err != nil {
-		resultCh := m.sf.DoChan(ociPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchExtensionImage(arch, ref, ociPath)
+	if _, err := os.Stat(ociPath); err == nil {
+		m.events.publish(EventCacheHit, ociPath, nil)
+	} else {
+		resultCh := m.fetchOnce(FetchGroupExtensions, ociPath, func(ctx context.Context) error {
+			return m.fetchExtensionImage(ctx, arch, ref, ociPath)
 		})
 
 		select {
@@ -314,14 +1227,52 @@ func (m *Manager) GetExtensionImage(ctx context.Context, arch Arch, ref Extensio
 	return ociPath, nil
 }
 
+// GetExtensionSBOM returns the path to the OCI layout with SBOM and attestation artifacts attached to the extension image.
+//
+// SBOMs are only fetched if enabled with Options.FetchExtensionSBOMs.
+func (m *Manager) GetExtensionSBOM(ctx context.Context, arch Arch, ref ExtensionRef) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	ociPath, err := m.GetExtensionImage(ctx, arch, ref)
+	if err != nil {
+		return "", err
+	}
+
+	sbomPath := ociPath + sbomSuffix
+
+	if _, err = os.Stat(sbomPath); err != nil {
+		return "", xerrors.NewTaggedf[ErrNotFoundTag]("no SBOM found for extension %s", ref.TaggedReference)
+	}
+
+	return sbomPath, nil
+}
+
 // GetOverlayImage pulls and stores in OCI layout an overlay image.
 func (m *Manager) GetOverlayImage(ctx context.Context, arch Arch, ref OverlayRef) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return "", err
+	}
+
 	ociPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest)
 
 	// check if already fetched
-	if _, err := os.Stat(ociPath); err != nil {
-		resultCh := m.sf.DoChan(ociPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchOverlayImage(arch, ref, ociPath)
+	if _, err := os.Stat(ociPath); err == nil {
+		m.events.publish(EventCacheHit, ociPath, nil)
+	} else {
+		resultCh := m.fetchOnce(FetchGroupOverlays, ociPath, func(ctx context.Context) error {
+			return m.fetchOverlayImage(ctx, arch, ref, ociPath)
 		})
 
 		select {
@@ -337,15 +1288,136 @@ func (m *Manager) GetOverlayImage(ctx context.Context, arch Arch, ref OverlayRef
 	return ociPath, nil
 }
 
+// repository returns the image repository in the image registry honoring the repository prefix.
+func (m *Manager) repository(repositoryPath string) name.Repository {
+	return repositoryFor(m.imageRegistry, m.options.RepositoryPrefix, repositoryPath)
+}
+
+// repositoryFor returns the repository in the image registry, with the optional repository prefix.
+func repositoryFor(imageRegistry name.Registry, repositoryPrefix, repositoryPath string) name.Repository {
+	if repositoryPrefix != "" {
+		repositoryPath = repositoryPrefix + "/" + repositoryPath
+	}
+
+	return imageRegistry.Repo(repositoryPath)
+}
+
+// extensionRepository returns the repository to pull the extension image from.
+func (m *Manager) extensionRepository(ref ExtensionRef) name.Repository {
+	if m.options.ExtensionRefRewriter == nil {
+		return m.repository(ref.TaggedReference.RepositoryStr())
+	}
+
+	return m.options.ExtensionRefRewriter(ref).TaggedReference.Repository
+}
+
+// parseTag parses the Talos version, validates that it is available, and returns the imager image tag.
+//
+// Accepted version formats are "1.7.0" and "v1.7.0", pre-releases like "1.7.0-alpha.1",
+// and versions with missing components like "1.7" (treated as "1.7.0").
+//
+// With Options.VersionResolver, partial versions are expanded against the available Talos versions first.
+//
+// The tag is the canonical "vX.Y.Z" form, unless the imager image is tagged differently (see Options.TagParser).
 func (m *Manager) parseTag(ctx context.Context, versionString string) (string, error) {
-	version, err := semver.ParseTolerant(versionString)
+	versionString, err := m.resolveVersion(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	tag, err := m.normalizeVersion(versionString)
+	if err != nil {
+		return "", err
+	}
+
+	if err = m.validateTalosVersion(ctx, semver.MustParse(tag[1:])); err != nil {
+		return "", err
+	}
+
+	m.talosVersionsMu.Lock()
+	imageTag, ok := m.talosVersionTags[tag[1:]]
+	m.talosVersionsMu.Unlock()
+
+	if ok {
+		return imageTag, nil
+	}
+
+	return tag, nil
+}
+
+// versionAllowed checks the version against Options.AllowedVersions.
+func (m *Manager) versionAllowed(version semver.Version) bool {
+	if m.allowedVersions == nil {
+		return true
+	}
+
+	_, ok := m.allowedVersions[version.String()]
+
+	return ok
+}
+
+// resolveVersion expands the partial Talos version with Options.VersionResolver against the available Talos versions.
+//
+// The version is returned as is if there is no resolver, or if the resolver doesn't match it.
+func (m *Manager) resolveVersion(ctx context.Context, versionString string) (string, error) {
+	if m.options.VersionResolver == nil {
+		return versionString, nil
+	}
+
+	availableVersions, err := m.GetTalosVersions(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get available Talos versions: %w", err)
+	}
+
+	version, ok := m.options.VersionResolver(versionString, availableVersions)
+	if !ok {
+		return versionString, nil
+	}
+
+	m.logger.Debug("resolved Talos version", zap.String("requested", versionString), zap.Stringer("resolved", version))
+
+	return version.String(), nil
+}
+
+// NormalizeVersion validates the user-supplied Talos version and returns the canonical "vX.Y.Z" form.
+//
+// See parseTag for the accepted version formats. With Options.VersionResolver, partial versions are expanded
+// as in Get. Versions outside of the MinVersion-MaxVersion range (or not in the AllowedVersions) are rejected
+// with an InvalidVersionErrorTag error. The availability of the version is not checked.
+func (m *Manager) NormalizeVersion(ctx context.Context, versionString string) (string, error) {
+	m, release, err := m.managerFor(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse version: %w", err)
+		return "", err
 	}
 
-	if err = m.validateTalosVersion(ctx, version); err != nil {
+	defer release()
+
+	versionString, err = m.resolveVersion(ctx, versionString)
+	if err != nil {
 		return "", err
 	}
 
+	return m.normalizeVersion(versionString)
+}
+
+// normalizeVersion validates the Talos version and returns the canonical "vX.Y.Z" form, without resolving it.
+func (m *Manager) normalizeVersion(versionString string) (string, error) {
+	version, err := semver.ParseTolerant(versionString)
+	if err != nil {
+		return "", xerrors.NewTaggedf[InvalidVersionErrorTag]("failed to parse version %q: %s", versionString, err)
+	}
+
+	if version.LT(m.options.MinVersion) {
+		return "", xerrors.NewTaggedf[InvalidVersionErrorTag]("version %s is below the minimum supported version %s", version, m.options.MinVersion)
+	}
+
+	if !m.options.MaxVersion.Equals(semver.Version{}) && version.GT(m.options.MaxVersion) {
+		return "", xerrors.NewTaggedf[InvalidVersionErrorTag]("version %s is above the maximum supported version %s", version, m.options.MaxVersion)
+	}
+
+	if !m.versionAllowed(version) {
+		return "", xerrors.NewTaggedf[InvalidVersionErrorTag]("%w: %s is not in the allowed versions", ErrVersionUnsupported, version)
+	}
+
 	return "v" + version.String(), nil
 }
@@ -0,0 +1,941 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/siderolabs/gen/xslices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestVersionFormats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"v1.7.0", "v1.7.0-alpha.1"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+		pushImage(t, registryHost, artifacts.ExtensionManifestImage, tag, map[string][]byte{
+			"image-digests": []byte("ghcr.io/siderolabs/gvisor:20231214.0-" + tag +
+				"@sha256:548b2b121611424f6b1b6cfb72a1669421ffaf2f1560911c324a546c7cee655e\n"),
+		})
+	}
+
+	for _, test := range []struct {
+		version     string
+		expectedTag string
+	}{
+		{version: "1.7", expectedTag: "v1.7.0"},
+		{version: "v1.7.0", expectedTag: "v1.7.0"},
+		{version: "1.7.0-alpha.1", expectedTag: "v1.7.0-alpha.1"},
+	} {
+		t.Run(test.version, func(t *testing.T) {
+			path, err := manager.Get(ctx, test.version, artifacts.ArchAmd64, artifacts.KindKernel)
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "kernel "+test.expectedTag, string(contents))
+
+			extensions, err := manager.GetOfficialExtensions(ctx, test.version)
+			require.NoError(t, err)
+			require.Len(t, extensions, 1)
+			assert.Equal(t, "20231214.0-"+test.expectedTag, extensions[0].TaggedReference.TagStr())
+		})
+	}
+}
+
+func TestImagerPullPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy   artifacts.PullPolicy
+		expected string
+	}{
+		{policy: artifacts.PullPolicyIfNotPresent, expected: "old kernel"},
+		{policy: artifacts.PullPolicyAlways, expected: "new kernel"},
+	} {
+		t.Run(string(test.policy), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			manager, registryHost := setupManager(t, artifacts.Options{ImagerPullPolicy: test.policy})
+
+			pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+				"usr/install/amd64/vmlinuz": []byte("old kernel"),
+			})
+
+			path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "old kernel", string(contents))
+
+			// remap the tag to a new digest
+			pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+				"usr/install/amd64/vmlinuz": []byte("new kernel"),
+			})
+
+			path, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			require.NoError(t, err)
+
+			contents, err = os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(contents))
+		})
+	}
+}
+
+func TestGetExtensionImageSingleArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/single-arch")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	// single-arch image is used for any architecture
+	for _, arch := range []artifacts.Arch{artifacts.ArchAmd64, artifacts.ArchArm64} {
+		path, err := manager.GetExtensionImage(ctx, arch, ref)
+		require.NoError(t, err)
+		assert.DirExists(t, path)
+
+		// extension images are kept apart from Talos versions
+		assert.Equal(t, "extensions", filepath.Base(filepath.Dir(path)))
+	}
+}
+
+func TestGetExtensionImageMultiArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	index := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add: img,
+		Descriptor: v1.Descriptor{
+			Platform: &v1.Platform{
+				OS:           "linux",
+				Architecture: string(artifacts.ArchAmd64),
+			},
+		},
+	})
+
+	tag := extensionTag(t, registryHost, "siderolabs/multi-arch")
+	require.NoError(t, remote.WriteIndex(tag, index))
+
+	digest, err := index.Digest()
+	require.NoError(t, err)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	path, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+	require.NoError(t, err)
+	assert.DirExists(t, path)
+
+	_, err = manager.GetExtensionImage(ctx, artifacts.ArchArm64, ref)
+	require.Error(t, err)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
+
+func TestGetExtensionImageTagRef(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/tagged")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	digestPath, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()})
+	require.NoError(t, err)
+
+	tagPath, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag})
+	require.NoError(t, err)
+
+	assert.Equal(t, digestPath, tagPath)
+}
+
+func TestGetExtensionImageArtifact(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	// OCI artifact with a non-standard config media type
+	artifact := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), "application/vnd.example.extension.config.v1+json")
+
+	artifact, err := mutate.AppendLayers(artifact, static.NewLayer([]byte("extension"), "application/vnd.example.extension.layer.v1.tar"))
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/artifact")
+	require.NoError(t, remote.Write(tag, artifact))
+
+	path, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag})
+	require.NoError(t, err)
+
+	l, err := layout.FromPath(path)
+	require.NoError(t, err)
+
+	index, err := l.ImageIndex()
+	require.NoError(t, err)
+
+	indexManifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, indexManifest.Manifests, 1)
+
+	img, err := index.Image(indexManifest.Manifests[0].Digest)
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	assert.Len(t, layers, 1)
+
+	// artifact without layers is not usable
+	emptyArtifact := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), "application/vnd.example.empty.config.v1+json")
+
+	emptyTag := extensionTag(t, registryHost, "siderolabs/empty-artifact")
+	require.NoError(t, remote.Write(emptyTag, emptyArtifact))
+
+	_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: emptyTag})
+	assert.ErrorContains(t, err, "extension image has no layers")
+}
+
+func TestRepositoryPrefix(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{RepositoryPrefix: "mygroup/nested"})
+
+	pushImage(t, registryHost, "mygroup/nested/"+artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	_, err = artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:    registryHost,
+		RepositoryPrefix: "MyGroup/../nested",
+	})
+	require.Error(t, err)
+}
+
+func TestGetMultiArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("amd64 kernel"),
+		"usr/install/arm64/vmlinuz": []byte("arm64 kernel"),
+	})
+
+	paths, err := manager.GetMultiArch(ctx, "1.7.0", artifacts.KindKernel, []artifacts.Arch{artifacts.ArchAmd64, artifacts.ArchArm64})
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+
+	for arch, path := range paths {
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(arch)+" kernel", string(contents))
+	}
+
+	_, err = manager.GetMultiArch(ctx, "1.7.0", artifacts.KindInitramfs, []artifacts.Arch{artifacts.ArchAmd64})
+	assert.Error(t, err)
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, _ := setupManager(t, artifacts.Options{
+		MinVersion: semver.MustParse("1.5.0"),
+		MaxVersion: semver.MustParse("1.8.0"),
+	})
+
+	for _, test := range []struct {
+		version     string
+		expectedTag string
+		expectedErr string
+	}{
+		{version: "1.7", expectedTag: "v1.7.0"},
+		{version: "v1.7.0", expectedTag: "v1.7.0"},
+		{version: "1.7.0-alpha.1", expectedTag: "v1.7.0-alpha.1"},
+		{version: "latest", expectedErr: `failed to parse version "latest"`},
+		{version: "1.4.0", expectedErr: "version 1.4.0 is below the minimum supported version 1.5.0"},
+		{version: "1.9.0", expectedErr: "version 1.9.0 is above the maximum supported version 1.8.0"},
+	} {
+		t.Run(test.version, func(t *testing.T) {
+			tag, err := manager.NormalizeVersion(ctx, test.version)
+			if test.expectedErr != "" {
+				assert.ErrorContains(t, err, test.expectedErr)
+				assert.True(t, xerrors.TagIs[artifacts.InvalidVersionErrorTag](err))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedTag, tag)
+		})
+	}
+}
+
+func TestNewManagerWithDeps(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	var (
+		now     = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		pullers int
+	)
+
+	storagePath := filepath.Join(t.TempDir(), "storage")
+	require.NoError(t, os.Mkdir(storagePath, 0o700))
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{TalosVersionRecheckInterval: time.Hour}, artifacts.Dependencies{
+		NewPuller: func(options ...remote.Option) (artifacts.Puller, error) {
+			pullers++
+
+			return remote.NewPuller(options...)
+		},
+		Now: func() time.Time {
+			return now
+		},
+		StoragePath: storagePath,
+	})
+
+	assert.Equal(t, 2, pullers)
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	versions, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+
+	pushImager(t, registryHost, "v1.8.0")
+
+	// versions are not rechecked until the clock advances
+	versions, err = manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+
+	now = now.Add(2 * time.Hour)
+
+	versions, err = manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(storagePath, "v1.7.0", "amd64", "vmlinuz"), path)
+
+	// the storage path of the caller is kept on Close
+	require.NoError(t, manager.Close())
+
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+}
+
+func TestPersistentCacheDir(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	cacheDir := t.TempDir()
+
+	// stale file from an unversioned layout
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "amd64-sha256:stale"), []byte("stale"), 0o644))
+
+	srv := httptest.NewServer(registry.New())
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	options := artifacts.Options{
+		ImageRegistry:         u.Host,
+		InsecureImageRegistry: true,
+		PersistentCacheDir:    cacheDir,
+	}
+
+	pushImage(t, u.Host, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), options)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(cacheDir, "amd64-sha256:stale"))
+	assert.FileExists(t, filepath.Join(cacheDir, "layout.version"))
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Close())
+
+	// artifacts are kept across restarts
+	assert.FileExists(t, path)
+
+	// leftovers of a crash during extraction
+	stagingPaths := []string{
+		filepath.Join(cacheDir, "v1.8.0-tmp"),
+		filepath.Join(cacheDir, "extensions", "amd64-sha256:orphaned-tmp"),
+		filepath.Join(cacheDir, "v1.7.0-evicted-1"),
+	}
+
+	for _, stagingPath := range stagingPaths {
+		require.NoError(t, os.MkdirAll(stagingPath, 0o755))
+	}
+
+	manager, err = artifacts.NewManager(zaptest.NewLogger(t), options)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	assert.FileExists(t, path)
+
+	for _, stagingPath := range stagingPaths {
+		assert.NoDirExists(t, stagingPath)
+	}
+
+	assert.DirExists(t, filepath.Join(cacheDir, "extensions"))
+}
+
+func TestGetTalosVersionsText(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"v1.7.0", "v1.6.4", "v1.7.1"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	text, err := manager.GetTalosVersionsText(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.7.1\nv1.7.0\nv1.6.4\n", text)
+}
+
+func TestCloseConcurrentGet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	var eg errgroup.Group
+
+	for range 8 {
+		eg.Go(func() error {
+			for {
+				_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+				if errors.Is(err, artifacts.ErrManagerClosed) {
+					return nil
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, manager.Close())
+	require.NoError(t, eg.Wait())
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, artifacts.ErrManagerClosed)
+
+	_, err = manager.GetTalosVersions(ctx)
+	assert.ErrorIs(t, err, artifacts.ErrManagerClosed)
+
+	_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{})
+	assert.ErrorIs(t, err, artifacts.ErrManagerClosed)
+}
+
+func TestImagerImageRef(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	amd64Img, err := crane.Image(map[string][]byte{"usr/install/amd64/vmlinuz": []byte("kernel")})
+	require.NoError(t, err)
+
+	arm64Img, err := crane.Image(map[string][]byte{"usr/install/arm64/vmlinuz": []byte("kernel")})
+	require.NoError(t, err)
+
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64Img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: string(artifacts.ArchAmd64)}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64Img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: string(artifacts.ArchArm64)}},
+		},
+	)
+
+	tag, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, index))
+
+	for arch, img := range map[artifacts.Arch]v1.Image{artifacts.ArchAmd64: amd64Img, artifacts.ArchArm64: arm64Img} {
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		ref, err := manager.ImagerImageRef(ctx, "1.7.0", arch)
+		require.NoError(t, err)
+		assert.Equal(t, tag.Context().Digest(digest.String()).String(), ref)
+	}
+
+	_, err = manager.ImagerImageRef(ctx, "1.7.0", "riscv64")
+	assert.ErrorIs(t, err, artifacts.ErrInvalidArch)
+}
+
+func TestGetTalosVersionsSortOrder(t *testing.T) {
+	tags := []string{"v1.7.0-beta.0", "v1.6.4", "v1.7.0", "v1.7.0-alpha.1", "v1.7.0-rc.0", "v1.10.0", "v1.7.1"}
+
+	for _, test := range []struct {
+		name      string
+		ascending bool
+		expected  []string
+	}{
+		{
+			name:     "descending",
+			expected: []string{"1.10.0", "1.7.1", "1.7.0", "1.7.0-rc.0", "1.7.0-beta.0", "1.7.0-alpha.1", "1.6.4"},
+		},
+		{
+			name:      "ascending",
+			ascending: true,
+			expected:  []string{"1.6.4", "1.7.0-alpha.1", "1.7.0-beta.0", "1.7.0-rc.0", "1.7.0", "1.7.1", "1.10.0"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			manager, registryHost := setupManager(t, artifacts.Options{VersionSortAscending: test.ascending})
+
+			for _, tag := range tags {
+				pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+					"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+				})
+			}
+
+			versions, err := manager.GetTalosVersions(ctx)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, xslices.Map(versions, semver.Version.String))
+		})
+	}
+}
+
+func TestGetInstallerImageRefVariant(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	for _, imageName := range []string{artifacts.InstallerImage, artifacts.InstallerBaseImage} {
+		pushImage(t, registryHost, imageName, "v1.7.0", map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte(imageName),
+		})
+	}
+
+	standardRef, err := manager.GetInstallerImageRefVariant(ctx, "1.7.0", artifacts.InstallerVariantStandard)
+	require.NoError(t, err)
+	assert.Contains(t, standardRef, artifacts.InstallerImage+"@sha256:")
+
+	defaultRef, err := manager.GetInstallerImageRefByDigest(ctx, "1.7.0")
+	require.NoError(t, err)
+	assert.Equal(t, standardRef, defaultRef)
+
+	baseRef, err := manager.GetInstallerImageRefVariant(ctx, "1.7.0", artifacts.InstallerVariantBase)
+	require.NoError(t, err)
+	assert.Contains(t, baseRef, artifacts.InstallerBaseImage+"@sha256:")
+
+	_, err = manager.GetInstallerImageRefVariant(ctx, "1.7.0", "slim")
+	assert.Error(t, err)
+}
+
+func TestDuplicateVersionTags(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"1.7", "1.7.0", "v1.7.0", "v1.8.0", "1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	versions, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []semver.Version{semver.MustParse("1.8.0"), semver.MustParse("1.7.0")}, versions)
+
+	// the canonical tag is fetched
+	for _, version := range []string{"1.7.0", "1.8.0"} {
+		path, err := manager.Get(ctx, version, artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kernel v"+version, string(contents))
+	}
+}
+
+func TestExtensionRefRewriter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	mirror := httptest.NewServer(registry.New())
+	t.Cleanup(mirror.Close)
+
+	mirrorURL, err := url.Parse(mirror.URL)
+	require.NoError(t, err)
+
+	var rewritten int
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		ExtensionRefRewriter: func(ref artifacts.ExtensionRef) artifacts.ExtensionRef {
+			rewritten++
+
+			ref.TaggedReference = extensionTag(t, mirrorURL.Host, ref.TaggedReference.RepositoryStr())
+
+			return ref
+		},
+	})
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	// the image is only available in the mirror
+	require.NoError(t, remote.Write(extensionTag(t, mirrorURL.Host, "siderolabs/gvisor"), img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/gvisor")
+
+	path, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag})
+	require.NoError(t, err)
+	assert.DirExists(t, path)
+	assert.Equal(t, "amd64-"+digest.String(), filepath.Base(path))
+
+	// the digest ref shares the cached image
+	digestPath, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()})
+	require.NoError(t, err)
+	assert.Equal(t, path, digestPath)
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	require.NoError(t, manager.ValidateSchematic(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, []artifacts.ExtensionRef{{TaggedReference: tag}}))
+	assert.Positive(t, rewritten)
+}
+
+func TestAllowedVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	_, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{AllowedVersions: []string{"latest"}})
+	require.ErrorContains(t, err, `invalid allowed version "latest"`)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		AllowedVersions: []string{"1.7.0", "v1.6.1"},
+	})
+
+	for _, tag := range []string{"v1.6.0", "v1.6.1", "v1.7.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	versions, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.7.0", "1.6.1"}, xslices.Map(versions, semver.Version.String))
+
+	_, err = manager.Get(ctx, "1.6.1", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	_, err = manager.Get(ctx, "1.6.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.ErrorIs(t, err, artifacts.ErrVersionUnsupported)
+	assert.True(t, xerrors.TagIs[artifacts.InvalidVersionErrorTag](err))
+
+	_, err = manager.GetOfficialExtensions(ctx, "1.6.0")
+	require.ErrorIs(t, err, artifacts.ErrVersionUnsupported)
+}
+
+func TestGetAllKinds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		KindFilenames: map[artifacts.Kind]string{artifacts.KindKernel: "kernel"},
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+		"usr/install/amd64/unknown":      []byte("unknown"),
+		"usr/install/arm64/vmlinuz":      []byte("kernel"),
+	})
+
+	paths, err := manager.GetAllKinds(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+
+	assert.Equal(t, "kernel", filepath.Base(paths[artifacts.KindKernel]))
+	assert.Equal(t, "initramfs.xz", filepath.Base(paths[artifacts.KindInitramfs]))
+
+	contents, err := os.ReadFile(paths[artifacts.KindInitramfs])
+	require.NoError(t, err)
+	assert.Equal(t, "initramfs", string(contents))
+
+	paths, err = manager.GetAllKinds(ctx, "1.7.0", artifacts.ArchArm64)
+	require.NoError(t, err)
+	assert.Len(t, paths, 1)
+}
+
+func TestOnServe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	type served struct {
+		version  string
+		arch     artifacts.Arch
+		kind     artifacts.Kind
+		cacheHit bool
+	}
+
+	var seen []served
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		OnServe: func(version string, arch artifacts.Arch, kind artifacts.Kind, cacheHit bool) {
+			seen = append(seen, served{version: version, arch: arch, kind: kind, cacheHit: cacheHit})
+		},
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	_, err := manager.Get(ctx, "1.7", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs)
+	require.Error(t, err)
+
+	assert.Equal(t, []served{
+		{version: "v1.7.0", arch: artifacts.ArchAmd64, kind: artifacts.KindKernel, cacheHit: false},
+		{version: "v1.7.0", arch: artifacts.ArchAmd64, kind: artifacts.KindKernel, cacheHit: true},
+	}, seen)
+}
+
+func TestInstallerRedirectTarget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	img, err := crane.Image(map[string][]byte{"usr/install/amd64/vmlinuz": []byte("installer")})
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(registryHost+"/"+artifacts.InstallerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	target, err := manager.InstallerRedirectTarget(ctx, "1.7.0")
+	require.NoError(t, err)
+	assert.Equal(t, "http://"+registryHost+"/v2/"+artifacts.InstallerImage+"/manifests/"+digest.String(), target)
+
+	// the target is pullable from the registry
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { resp.Body.Close() }) //nolint:errcheck
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCloseCancelsFetches(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	registryHost, blocked, unblock := setupBlockingRegistry(t)
+	t.Cleanup(unblock)
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         registryHost,
+		InsecureImageRegistry: true,
+	})
+	require.NoError(t, err)
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	requestCtx, requestCancel := context.WithCancel(ctx)
+
+	go func() {
+		<-blocked
+
+		// the request gives up, while the fetch is left in progress
+		requestCancel()
+	}()
+
+	_, err = manager.Get(requestCtx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the fetch is blocked until canceled by Close
+	require.NoError(t, manager.Close())
+
+	assert.Zero(t, manager.Stats().FetchesInFlight)
+}
+
+func TestInvalidateExtensions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	// nothing is cached yet
+	manager.InvalidateExtensions("1.7.0")
+
+	pushImage(t, registryHost, artifacts.ExtensionManifestImage, "v1.7.0", map[string][]byte{
+		"image-digests": []byte("ghcr.io/siderolabs/gvisor:20231214.0" +
+			"@sha256:548b2b121611424f6b1b6cfb72a1669421ffaf2f1560911c324a546c7cee655e\n"),
+	})
+
+	extensions, err := manager.GetOfficialExtensions(ctx, "1.7.0")
+	require.NoError(t, err)
+	require.Len(t, extensions, 1)
+
+	pushImage(t, registryHost, artifacts.ExtensionManifestImage, "v1.7.0", map[string][]byte{
+		"image-digests": []byte("ghcr.io/siderolabs/gvisor:20231214.0" +
+			"@sha256:548b2b121611424f6b1b6cfb72a1669421ffaf2f1560911c324a546c7cee655e\n" +
+			"ghcr.io/siderolabs/nvidia:535.129.03" +
+			"@sha256:1b1a8b1b1a418d6b1b1d2f3e0f8b4b3b0c5a0f9b8e7d6c5b4a39281706f5e4d3\n"),
+	})
+
+	// the cached extensions list is served until invalidated
+	extensions, err = manager.GetOfficialExtensions(ctx, "1.7.0")
+	require.NoError(t, err)
+	assert.Len(t, extensions, 1)
+
+	manager.InvalidateExtensions("1.7.0")
+
+	extensions, err = manager.GetOfficialExtensions(ctx, "1.7.0")
+	require.NoError(t, err)
+	require.Len(t, extensions, 2)
+	assert.Equal(t, "ghcr.io/siderolabs/nvidia:535.129.03", extensions[1].TaggedReference.String())
+}
+
+func TestServedRootRewrite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		ServedRootRewrite: artifacts.PathRewrite{From: storagePath, To: "/srv/artifacts"},
+	}, artifacts.Dependencies{StoragePath: storagePath})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	// the manager returns the local paths
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(storagePath, "v1.7.0", "amd64", "vmlinuz"), path)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(contents))
+
+	paths, err := manager.GetMultiArch(ctx, "1.7.0", artifacts.KindKernel, []artifacts.Arch{artifacts.ArchAmd64})
+	require.NoError(t, err)
+	assert.Equal(t, path, paths[artifacts.ArchAmd64])
+
+	// only the paths handed to the external server are rewritten
+	assert.Equal(t, "/srv/artifacts/v1.7.0/amd64/vmlinuz", manager.ServedPath(path))
+	assert.Equal(t, "/srv/artifacts", manager.ServedPath(storagePath))
+	assert.Equal(t, "/etc/passwd", manager.ServedPath("/etc/passwd"))
+	assert.Equal(t, storagePath+"-other/vmlinuz", manager.ServedPath(storagePath+"-other/vmlinuz"))
+
+	_, err = artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:     registryHost,
+		ServedRootRewrite: artifacts.PathRewrite{From: storagePath, To: "srv/artifacts"},
+	})
+	require.ErrorContains(t, err, "invalid served root rewrite")
+}
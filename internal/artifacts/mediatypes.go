@@ -0,0 +1,51 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// checkManifestMediaType checks that the image manifest is either an OCI or a Docker image manifest.
+func checkManifestMediaType(img v1.Image) error {
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return fmt.Errorf("error reading image media type: %w", err)
+	}
+
+	if !mediaType.IsImage() {
+		return fmt.Errorf("unsupported image manifest media type %q", mediaType)
+	}
+
+	return nil
+}
+
+// checkMediaTypes checks that the image manifest, config and layers have OCI or Docker media types.
+//
+// Both OCI and Docker media types are accepted (and can be mixed), as layers are decompressed the same way on extraction.
+func checkMediaTypes(img v1.Image) error {
+	if err := checkManifestMediaType(img); err != nil {
+		return err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("error reading image manifest: %w", err)
+	}
+
+	if !manifest.Config.MediaType.IsConfig() {
+		return fmt.Errorf("unsupported image config media type %q", manifest.Config.MediaType)
+	}
+
+	for _, layer := range manifest.Layers {
+		if !layer.MediaType.IsLayer() {
+			return fmt.Errorf("unsupported image layer media type %q for layer %s", layer.MediaType, layer.Digest)
+		}
+	}
+
+	return nil
+}
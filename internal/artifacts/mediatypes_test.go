@@ -0,0 +1,100 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestImagerMediaTypes(t *testing.T) {
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "usr/install/amd64/vmlinuz", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len("kernel"))}))
+	_, err := tw.Write([]byte("kernel"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	for _, test := range []struct {
+		name         string
+		manifestType types.MediaType
+		configType   types.MediaType
+		layerType    types.MediaType
+		expectedErr  string
+	}{
+		{
+			name:         "oci",
+			manifestType: types.OCIManifestSchema1,
+			configType:   types.OCIConfigJSON,
+			layerType:    types.OCIUncompressedLayer,
+		},
+		{
+			name:         "docker",
+			manifestType: types.DockerManifestSchema2,
+			configType:   types.DockerConfigJSON,
+			layerType:    types.DockerUncompressedLayer,
+		},
+		{
+			name:         "unknown layer",
+			manifestType: types.OCIManifestSchema1,
+			configType:   types.OCIConfigJSON,
+			layerType:    "application/vnd.example.layer.v1",
+			expectedErr:  `unsupported image layer media type "application/vnd.example.layer.v1"`,
+		},
+		{
+			name:         "unknown config",
+			manifestType: types.OCIManifestSchema1,
+			configType:   "application/vnd.example.config.v1+json",
+			layerType:    types.OCIUncompressedLayer,
+			expectedErr:  `unsupported image config media type "application/vnd.example.config.v1+json"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			manager, registryHost := setupManager(t, artifacts.Options{})
+
+			img := mutate.ConfigMediaType(mutate.MediaType(empty.Image, test.manifestType), test.configType)
+
+			img, err := mutate.AppendLayers(img, static.NewLayer(buf.Bytes(), test.layerType))
+			require.NoError(t, err)
+
+			ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+			require.NoError(t, err)
+			require.NoError(t, remote.Write(ref, img))
+
+			path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+
+			if test.expectedErr != "" {
+				assert.ErrorContains(t, err, test.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "kernel", string(contents))
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"sync"
+)
+
+// defaultInMemoryCacheSize is the default total size of artifacts kept in memory.
+const defaultInMemoryCacheSize = 64 * 1024 * 1024
+
+// memoryCacheKey identifies an extracted imager artifact.
+type memoryCacheKey struct {
+	tag  string
+	arch Arch
+	kind Kind
+}
+
+type memoryCacheEntry struct {
+	data []byte
+	key  memoryCacheKey
+}
+
+// memoryCache is an LRU cache of small artifacts bounded by the total size of the contents.
+//
+// A nil memory cache doesn't store anything.
+type memoryCache struct {
+	mu sync.Mutex
+
+	threshold int64
+	capacity  int64
+	size      int64
+
+	entries map[memoryCacheKey]*list.Element
+	lru     list.List
+}
+
+func newMemoryCache(threshold, capacity int64) *memoryCache {
+	if threshold <= 0 {
+		return nil
+	}
+
+	if capacity <= 0 {
+		capacity = defaultInMemoryCacheSize
+	}
+
+	return &memoryCache{
+		threshold: threshold,
+		capacity:  capacity,
+		entries:   make(map[memoryCacheKey]*list.Element),
+	}
+}
+
+// fits returns true if the artifact of the given size should be kept in memory.
+func (c *memoryCache) fits(size int64) bool {
+	return c != nil && size <= c.threshold && size <= c.capacity
+}
+
+// get returns the cached artifact contents.
+func (c *memoryCache) get(key memoryCacheKey) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return elem.Value.(*memoryCacheEntry).data, true //nolint:forcetypeassert,errcheck
+}
+
+// put stores the artifact contents, evicting the least recently used artifacts to stay within the capacity.
+func (c *memoryCache) put(key memoryCacheKey, data []byte) {
+	if !c.fits(int64(len(data))) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	c.entries[key] = c.lru.PushFront(&memoryCacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+
+	for c.size > c.capacity {
+		c.remove(c.lru.Back())
+	}
+}
+
+// purgeTag removes all artifacts of the Talos version.
+func (c *memoryCache) purgeTag(tag string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if key.tag == tag {
+			c.remove(elem)
+		}
+	}
+}
+
+func (c *memoryCache) remove(elem *list.Element) {
+	entry := elem.Value.(*memoryCacheEntry) //nolint:forcetypeassert,errcheck
+
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+}
+
+// memoryFile is the artifact opened with Open which is served from memory.
+type memoryFile struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer.
+func (memoryFile) Close() error {
+	return nil
+}
+
+var _ io.ReadSeekCloser = memoryFile{}
@@ -0,0 +1,61 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestInMemoryCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		InMemoryThreshold: 16,
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("a kernel which is too large"),
+		"usr/install/amd64/initramfs.xz": []byte("initramfs"),
+	})
+
+	for _, kind := range []artifacts.Kind{artifacts.KindKernel, artifacts.KindInitramfs} {
+		r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, kind)
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+
+	for _, kind := range []artifacts.Kind{artifacts.KindKernel, artifacts.KindInitramfs} {
+		path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, kind)
+		require.NoError(t, err)
+
+		require.NoError(t, os.Remove(path))
+	}
+
+	// small artifacts are served without a disk read
+	r, err := manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs)
+	require.NoError(t, err)
+
+	contents, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "initramfs", string(contents))
+	require.NoError(t, r.Close())
+
+	// large artifacts stay on disk
+	_, err = manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.Error(t, err)
+}
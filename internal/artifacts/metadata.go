@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/siderolabs/gen/xerrors"
+	"gopkg.in/yaml.v3"
+)
+
+// imageMetadataNames are the names of the metadata files which might be embedded into the imager output.
+//
+// YAML is a superset of JSON, so both are parsed as YAML.
+var imageMetadataNames = []string{"versions.yaml", "versions.yml", "versions.json"}
+
+// GetImageMetadata returns the metadata describing the components of the Talos version, parsed from the imager output.
+//
+// The metadata file for the architecture is preferred over the one for all architectures at the root of the imager output.
+// An ErrNotFoundTag error is returned if the imager output doesn't embed the metadata.
+func (m *Manager) GetImageMetadata(ctx context.Context, versionString string, arch Arch) (map[string]any, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return nil, err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = m.ensureImager(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{filepath.Join(m.storagePath, tag, string(arch)), filepath.Join(m.storagePath, tag)} {
+		for _, name := range imageMetadataNames {
+			contents, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+
+				return nil, fmt.Errorf("error reading image metadata: %w", err)
+			}
+
+			var metadata map[string]any
+
+			if err = yaml.Unmarshal(contents, &metadata); err != nil {
+				return nil, fmt.Errorf("error parsing image metadata %q: %w", name, err)
+			}
+
+			return metadata, nil
+		}
+	}
+
+	return nil, xerrors.NewTaggedf[ErrNotFoundTag]("no image metadata found for Talos version %s", tag)
+}
@@ -0,0 +1,46 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetImageMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":       []byte("kernel"),
+		"usr/install/versions.yaml":       []byte("talos: v1.7.0\ncomponents:\n  kernel: 6.6.29\n"),
+		"usr/install/arm64/versions.json": []byte(`{"talos": "v1.7.0", "components": {"kernel": "6.6.29", "u-boot": "2024.04"}}`),
+	})
+	pushImager(t, registryHost, "v1.7.1")
+
+	metadata, err := manager.GetImageMetadata(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"talos":      "v1.7.0",
+		"components": map[string]any{"kernel": "6.6.29"},
+	}, metadata)
+
+	metadata, err = manager.GetImageMetadata(ctx, "1.7.0", artifacts.ArchArm64)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"kernel": "6.6.29", "u-boot": "2024.04"}, metadata["components"])
+
+	_, err = manager.GetImageMetadata(ctx, "1.7.1", artifacts.ArchAmd64)
+	require.Error(t, err)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
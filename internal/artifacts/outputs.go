@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// outputsManifestName is the name of the manifest of outputs the imager might declare in its output.
+const outputsManifestName = "outputs.json"
+
+// outputsManifest is the manifest of files produced by the imager.
+type outputsManifest struct {
+	Files []outputFile `json:"files"`
+}
+
+// outputFile is a file declared in the outputs manifest, path is relative to the imager output, e.g. "amd64/vmlinuz".
+type outputFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// validateOutputs validates the extracted imager output against the outputs manifest.
+//
+// Imager images without the outputs manifest are not validated.
+func validateOutputs(logger *zap.Logger, destination string, checksums map[string]string) error {
+	contents, err := os.ReadFile(filepath.Join(destination, outputsManifestName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Debug("imager doesn't declare outputs, skipping validation")
+
+			return nil
+		}
+
+		return fmt.Errorf("error reading outputs manifest: %w", err)
+	}
+
+	var manifest outputsManifest
+
+	if err = json.Unmarshal(contents, &manifest); err != nil {
+		return fmt.Errorf("error parsing outputs manifest: %w", err)
+	}
+
+	for _, file := range manifest.Files {
+		st, err := os.Stat(filepath.Join(destination, file.Path))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%w: %q is missing", ErrIncompleteExtraction, file.Path)
+			}
+
+			return err
+		}
+
+		if st.Size() != file.Size {
+			return fmt.Errorf("%w: %q is %d bytes, expected %d", ErrIncompleteExtraction, file.Path, st.Size(), file.Size)
+		}
+
+		if file.SHA256 != "" && checksums[file.Path] != file.SHA256 {
+			return fmt.Errorf("%w: checksum mismatch for %q", ErrIncompleteExtraction, file.Path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestValidateImagerOutputs(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		outputs   string
+		expectErr bool
+	}{
+		{
+			name:    "valid",
+			outputs: `{"files": [{"path": "amd64/vmlinuz", "size": 6, "sha256": "6923dd1bc0460082c5d55a831908c24a282860b7f1cd6c2b79cf1bc8857c639c"}]}`,
+		},
+		{
+			name:      "missing",
+			outputs:   `{"files": [{"path": "amd64/vmlinuz", "size": 6}, {"path": "amd64/initramfs.xz", "size": 9}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "truncated",
+			outputs:   `{"files": [{"path": "amd64/vmlinuz", "size": 1024}]}`,
+			expectErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			manager, registryHost := setupManager(t, artifacts.Options{ValidateImagerOutputs: true})
+
+			pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+				"usr/install/amd64/vmlinuz": []byte("kernel"),
+				"usr/install/outputs.json":  []byte(test.outputs),
+			})
+
+			_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			if test.expectErr {
+				assert.ErrorIs(t, err, artifacts.ErrIncompleteExtraction)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"go.uber.org/zap"
+)
+
+type registryOverrideKey struct{}
+
+// WithRegistryOverride returns a context which makes the manager pull source images from the specified registry
+// instead of Options.ImageRegistry.
+//
+// Artifacts pulled from the override registry are cached separately, so they are never shared with other registries.
+// The registry should be listed in Options.RegistryOverrides, an empty registry resets the override.
+func WithRegistryOverride(ctx context.Context, registry string) context.Context {
+	return context.WithValue(ctx, registryOverrideKey{}, registry)
+}
+
+// RegistryOverride returns the registry override from the context, or an empty string.
+func RegistryOverride(ctx context.Context) string {
+	registry, _ := ctx.Value(registryOverrideKey{}).(string)
+
+	return registry
+}
+
+// managerFor returns the manager which serves the request: either the manager itself,
+// or a manager for the registry override from the context.
+//
+// The override managers are created on first use, and share the options with the main manager.
+// There is at most one override manager for each registry in Options.RegistryOverrides.
+//
+// The returned release function must be called when the request is done, see Manager.enter.
+func (m *Manager) managerFor(ctx context.Context) (*Manager, func(), error) {
+	release, err := m.enter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	override, err := m.overrideFor(ctx)
+	if err != nil {
+		release()
+
+		return nil, nil, err
+	}
+
+	return override, release, nil
+}
+
+func (m *Manager) overrideFor(ctx context.Context) (*Manager, error) {
+	registry := RegistryOverride(ctx)
+	if registry == "" || registry == m.options.ImageRegistry || m.parent != nil {
+		return m, nil
+	}
+
+	m.overridesMu.Lock()
+	defer m.overridesMu.Unlock()
+
+	if override, ok := m.overrides[registry]; ok {
+		return override, nil
+	}
+
+	if _, err := name.NewRegistry(registry, name.StrictValidation); err != nil || filepath.Base(registry) != registry {
+		return nil, fmt.Errorf("invalid registry override %q", registry)
+	}
+
+	if !slices.Contains(m.options.RegistryOverrides, registry) {
+		return nil, fmt.Errorf("%w: %q", ErrRegistryOverrideNotAllowed, registry)
+	}
+
+	options := m.options
+	options.ImageRegistry = registry
+
+	deps := m.deps
+
+	storagePath := filepath.Join(m.storagePath, "registries", registry)
+
+	if options.PersistentCacheDir != "" {
+		options.PersistentCacheDir = storagePath
+	} else {
+		if err := os.MkdirAll(storagePath, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory for registry %q: %w", registry, err)
+		}
+
+		deps.StoragePath = storagePath
+	}
+
+	override, err := NewManagerWithDeps(m.logger.With(zap.String("registry", registry)), options, deps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manager for registry %q: %w", registry, err)
+	}
+
+	override.parent = m
+	// the storage is created above, so it's removed on Close (unless it's in the persistent cache)
+	override.ownedStorage = true
+
+	if m.overrides == nil {
+		m.overrides = map[string]*Manager{}
+	}
+
+	m.overrides[registry] = override
+
+	return override, nil
+}
+
+// closeOverrides closes the registry override managers.
+func (m *Manager) closeOverrides() error {
+	m.overridesMu.Lock()
+	defer m.overridesMu.Unlock()
+
+	var errs []error
+
+	for _, override := range m.overrides {
+		errs = append(errs, override.Close())
+	}
+
+	m.overrides = nil
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestRegistryOverride(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	tenantSrv := httptest.NewServer(registry.New())
+	t.Cleanup(tenantSrv.Close)
+
+	tenantURL, err := url.Parse(tenantSrv.URL)
+	require.NoError(t, err)
+
+	tenantHost := tenantURL.Host
+
+	otherSrv := httptest.NewServer(registry.New())
+	t.Cleanup(otherSrv.Close)
+
+	otherURL, err := url.Parse(otherSrv.URL)
+	require.NoError(t, err)
+
+	manager, registryHost := setupManager(t, artifacts.Options{RegistryOverrides: []string{tenantHost}})
+
+	for host, kernel := range map[string]string{registryHost: "default kernel", tenantHost: "tenant kernel"} {
+		pushImage(t, host, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte(kernel),
+		})
+	}
+
+	defaultPath, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	tenantPath, err := manager.Get(artifacts.WithRegistryOverride(ctx, tenantHost), "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, defaultPath, tenantPath)
+
+	for path, expected := range map[string]string{defaultPath: "default kernel", tenantPath: "tenant kernel"} {
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(contents))
+	}
+
+	// the content-addressed identifiers are resolved with the same override
+	tenantDigest, err := manager.DigestPath(artifacts.WithRegistryOverride(ctx, tenantHost), "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	resolvedPath, err := manager.ResolveDigestPath(artifacts.WithRegistryOverride(ctx, tenantHost), tenantDigest)
+	require.NoError(t, err)
+	assert.Equal(t, tenantPath, resolvedPath)
+
+	_, err = manager.ResolveDigestPath(ctx, tenantDigest)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	_, err = manager.Get(artifacts.WithRegistryOverride(ctx, "../escape"), "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorContains(t, err, "invalid registry override")
+
+	// only the allowed registries get an override manager
+	_, err = manager.Get(artifacts.WithRegistryOverride(ctx, otherURL.Host), "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.ErrorIs(t, err, artifacts.ErrRegistryOverrideNotAllowed)
+}
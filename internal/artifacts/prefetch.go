@@ -0,0 +1,229 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/blang/semver/v4"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sys/unix"
+)
+
+// prefetchLatestPatch asynchronously fetches the latest patch release of the same minor line as the version,
+// if it's not extracted yet.
+//
+// Only a single prefetch runs at a time, and prefetches are skipped while another one is running,
+// so that they don't compete with the requests.
+func (m *Manager) prefetchLatestPatch(versionString string) {
+	select {
+	case m.prefetchSem <- struct{}{}:
+	default:
+		return
+	}
+
+	if !m.startPrefetch(func() {
+		defer func() { <-m.prefetchSem }()
+
+		tag, ok := m.latestPatchTag(versionString)
+		if !ok {
+			return
+		}
+
+		if _, err := os.Stat(filepath.Join(m.storagePath, tag)); err == nil {
+			return
+		}
+
+		m.logger.Info("prefetching the latest patch release", zap.String("tag", tag))
+
+		if _, err := m.ensureImager(m.prefetchCtx, tag); err != nil && !errors.Is(err, context.Canceled) {
+			m.logger.Warn("failed to prefetch the latest patch release", zap.String("tag", tag), zap.Error(err))
+		}
+	}) {
+		<-m.prefetchSem
+	}
+}
+
+// startPrefetch runs the prefetch in the background, so that Close waits for it.
+//
+// The prefetch is not started once the manager is closed, as Close might be already waiting for the prefetches.
+func (m *Manager) startPrefetch(prefetch func()) bool {
+	m.lifecycleMu.Lock()
+	defer m.lifecycleMu.Unlock()
+
+	if m.closed {
+		return false
+	}
+
+	m.prefetchWg.Add(1)
+
+	go func() {
+		defer m.prefetchWg.Done()
+
+		prefetch()
+	}()
+
+	return true
+}
+
+// latestPatchTag returns the imager tag of the latest patch release newer than the version in the same minor line.
+func (m *Manager) latestPatchTag(versionString string) (string, bool) {
+	version, err := semver.ParseTolerant(versionString)
+	if err != nil {
+		return "", false
+	}
+
+	m.talosVersionsMu.Lock()
+	defer m.talosVersionsMu.Unlock()
+
+	var latest *semver.Version
+
+	for i, candidate := range m.talosVersions {
+		if candidate.Major != version.Major || candidate.Minor != version.Minor || len(candidate.Pre) > 0 || candidate.LTE(version) {
+			continue
+		}
+
+		if latest == nil || candidate.GT(*latest) {
+			latest = &m.talosVersions[i]
+		}
+	}
+
+	if latest == nil {
+		return "", false
+	}
+
+	if tag, ok := m.talosVersionTags[latest.String()]; ok {
+		return tag, true
+	}
+
+	return "v" + latest.String(), true
+}
+
+// newVersionTags returns the imager tags of the versions which are not in the previous versions.
+func newVersionTags(previous, versions []semver.Version, tags map[string]string) []string {
+	var newTags []string
+
+	for _, version := range versions {
+		if slices.ContainsFunc(previous, version.Equals) {
+			continue
+		}
+
+		tag, ok := tags[version.String()]
+		if !ok {
+			tag = "v" + version.String()
+		}
+
+		newTags = append(newTags, tag)
+	}
+
+	return newTags
+}
+
+// prefetchNewVersions asynchronously fetches the artifacts of the new Talos versions selected with Options.PrefetchNewVersions.
+//
+// At most PrefetchSelector.Concurrency versions are fetched at a time.
+func (m *Manager) prefetchNewVersions(tags []string) {
+	selector := m.options.PrefetchNewVersions
+	if selector == nil || len(tags) == 0 {
+		return
+	}
+
+	m.startPrefetch(func() {
+		var eg errgroup.Group
+
+		eg.SetLimit(max(selector.Concurrency, 1))
+
+		for _, tag := range tags {
+			eg.Go(func() error {
+				m.prefetchVersion(tag, selector)
+
+				return nil
+			})
+		}
+
+		eg.Wait() //nolint:errcheck
+	})
+}
+
+// prefetchVersion fetches the selected artifacts of the Talos version, unless it doesn't fit the free space.
+func (m *Manager) prefetchVersion(tag string, selector *PrefetchSelector) {
+	if m.prefetchCtx.Err() != nil {
+		return
+	}
+
+	logger := m.logger.With(zap.String("tag", tag))
+
+	if selector.MinFreeBytes > 0 {
+		if err := m.ensurePrefetchSpace(tag, selector.MinFreeBytes); err != nil {
+			if errors.Is(err, ErrInsufficientDiskSpace) {
+				logger.Info("skipping prefetch of the new Talos version, not enough free space", zap.Error(err))
+			} else {
+				logger.Warn("failed to check free space to prefetch the new Talos version", zap.Error(err))
+			}
+
+			return
+		}
+	}
+
+	logger.Info("prefetching the new Talos version")
+
+	if _, err := m.ensureImager(m.prefetchCtx, tag); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logger.Warn("failed to prefetch the new Talos version", zap.Error(err))
+		}
+
+		return
+	}
+
+	arches := selector.Arches
+	if len(arches) == 0 {
+		arches = []Arch{ArchAmd64, ArchArm64}
+	}
+
+	for _, arch := range arches {
+		for _, kind := range selector.Kinds {
+			if !m.kindSupported(kind) {
+				continue
+			}
+
+			if _, err := m.artifactPath(m.prefetchCtx, tag, arch, kind); err != nil {
+				logger.Debug("prefetched artifact is not available", zap.String("arch", string(arch)), zap.String("kind", string(kind)), zap.Error(err))
+			}
+		}
+	}
+}
+
+// ensurePrefetchSpace ensures that the imager image of the Talos version fits the free space of the storage,
+// keeping minFreeBytes free, as ensureFreeSpace does before any fetch.
+func (m *Manager) ensurePrefetchSpace(tag string, minFreeBytes int64) error {
+	_, img, err := m.imagerImage(m.prefetchCtx, tag, ArchArm64)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("error reading imager image manifest: %w", err)
+	}
+
+	return m.ensureFreeSpace(tag, minFreeBytes+layersSize(manifest))
+}
+
+// freeSpace returns the space available to unprivileged users on the filesystem of the path.
+func freeSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("error checking free space: %w", err)
+	}
+
+	return int64(stat.Bavail) * stat.Bsize, nil //nolint:gosec
+}
@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestPrefetchLatestPatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{PrefetchLatestPatch: true}, artifacts.Dependencies{StoragePath: storagePath})
+
+	for _, tag := range []string{"v1.7.5", "v1.8.2", "v1.8.3", "v1.8.4-beta.0", "v1.9.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	_, err := manager.Get(ctx, "1.8.2", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	assert.EventuallyWithT(t, func(collect *assert.CollectT) {
+		assert.DirExists(collect, filepath.Join(storagePath, "v1.8.3"))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the latest patch release has nothing to prefetch
+	_, err = manager.Get(ctx, "1.9.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	for _, tag := range []string{"v1.7.5", "v1.8.4-beta.0"} {
+		assert.NoDirExists(t, filepath.Join(storagePath, tag))
+	}
+}
+
+func TestPrefetchNewVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		PrefetchNewVersions: &artifacts.PrefetchSelector{
+			Arches:      []artifacts.Arch{artifacts.ArchAmd64},
+			Kinds:       []artifacts.Kind{artifacts.KindKernel},
+			Concurrency: 2,
+		},
+	}, artifacts.Dependencies{StoragePath: storagePath})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel v1.7.0"),
+	})
+
+	// the versions available on the first listing are not prefetched
+	_, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+
+	for _, tag := range []string{"v1.7.1", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	_, err = manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+
+	for _, tag := range []string{"v1.7.1", "v1.8.0"} {
+		assert.EventuallyWithT(t, func(collect *assert.CollectT) {
+			_, err := os.Stat(filepath.Join(storagePath, tag, "amd64", "vmlinuz"))
+			assert.NoError(collect, err)
+		}, 5*time.Second, 10*time.Millisecond, tag)
+	}
+
+	_, err = os.Stat(filepath.Join(storagePath, "v1.7.0"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPrefetchNewVersionsFreeSpace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{
+		PrefetchNewVersions: &artifacts.PrefetchSelector{
+			Kinds:        []artifacts.Kind{artifacts.KindKernel},
+			MinFreeBytes: 1 << 20,
+		},
+	}, artifacts.Dependencies{
+		StoragePath: storagePath,
+		FreeSpace:   func(string) (int64, error) { return 1 << 20, nil },
+	})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel v1.7.0"),
+	})
+
+	_, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.8.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel v1.8.0"),
+	})
+
+	_, err = manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+
+	// the imager image doesn't fit while keeping MinFreeBytes free, and there is nothing to evict
+	assert.Never(t, func() bool {
+		_, err := os.Stat(filepath.Join(storagePath, "v1.8.0"))
+
+		return err == nil
+	}, 500*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestStartPrefetchClosed(t *testing.T) {
+	m, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Close())
+
+	// the prefetches reported after Close (e.g. by the fetches left behind by the requests) are not started
+	assert.False(t, m.StartPrefetch(func() { t.Error("prefetch started after Close") }))
+}
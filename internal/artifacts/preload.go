@@ -0,0 +1,58 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// preloadConcurrency is the number of extension images fetched concurrently by PreloadExtensions.
+const preloadConcurrency = 4
+
+// PreloadExtensions fetches the extension images into the cache, e.g. for the extensions of popular schematics.
+//
+// Extension images are fetched concurrently, and concurrent requests for the same image are deduplicated
+// as with GetExtensionImage. A failure to fetch an image doesn't abort the batch, all errors are returned joined.
+func (m *Manager) PreloadExtensions(ctx context.Context, arch Arch, refs []ExtensionRef) error {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return err
+	}
+
+	var (
+		eg     errgroup.Group
+		errsMu sync.Mutex
+		errs   []error
+	)
+
+	eg.SetLimit(preloadConcurrency)
+
+	for _, ref := range refs {
+		eg.Go(func() error {
+			if _, err := m.GetExtensionImage(ctx, arch, ref); err != nil {
+				errsMu.Lock()
+				errs = append(errs, fmt.Errorf("failed to preload %s: %w", ref.TaggedReference, err))
+				errsMu.Unlock()
+			}
+
+			return nil
+		})
+	}
+
+	eg.Wait() //nolint:errcheck // errors are collected separately
+
+	return errors.Join(errs...)
+}
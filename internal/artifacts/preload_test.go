@@ -0,0 +1,67 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestPreloadExtensions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	refs := make([]artifacts.ExtensionRef, 0, 3)
+
+	for _, repository := range []string{"siderolabs/gvisor", "siderolabs/iscsi-tools"} {
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+
+		tag := extensionTag(t, registryHost, repository)
+		require.NoError(t, remote.Write(tag, img))
+
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		refs = append(refs, artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()})
+	}
+
+	refs = append(refs, artifacts.ExtensionRef{
+		TaggedReference: extensionTag(t, registryHost, "siderolabs/missing"),
+		Digest:          "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	})
+
+	events := manager.Events()
+
+	err := manager.PreloadExtensions(ctx, artifacts.ArchAmd64, refs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "siderolabs/missing")
+
+	// preloaded images are served from the cache
+	for _, ref := range refs[:2] {
+		_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+		require.NoError(t, err)
+	}
+
+	var cacheHits int
+
+	for len(events) > 0 {
+		if ev := <-events; ev.Type == artifacts.EventCacheHit {
+			cacheHits++
+		}
+	}
+
+	assert.Equal(t, 2, cacheHits)
+}
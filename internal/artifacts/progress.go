@@ -0,0 +1,247 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+)
+
+// ProgressPhase is the phase of the fetch.
+type ProgressPhase string
+
+// Fetch phases.
+const (
+	// ProgressPhaseDownloading is reported while the image layers are downloaded (and extracted on the fly).
+	ProgressPhaseDownloading ProgressPhase = "downloading"
+	// ProgressPhaseExtracting is reported once the layers are downloaded, while the extraction is completed.
+	ProgressPhaseExtracting ProgressPhase = "extracting"
+)
+
+// Progress is the progress of the fetch.
+type Progress struct {
+	Phase ProgressPhase
+	// Downloaded is the number of (compressed) bytes of the image layers downloaded so far.
+	Downloaded int64
+	// Total is the (compressed) size of the image layers.
+	Total int64
+}
+
+// GetWithProgress is like Get, but it reports the progress of the fetch to the channel.
+//
+// Every request waiting for the same fetch receives the progress updates. The updates are dropped
+// if the channel is not ready to receive, the channel is not closed. No updates are sent if the artifact
+// is already extracted.
+func (m *Manager) GetWithProgress(ctx context.Context, versionString string, arch Arch, kind Kind, progress chan<- Progress) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return "", err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	tracker, releaseTracker := m.acquireProgress(tag)
+	defer releaseTracker()
+
+	tracker.subscribe(progress)
+	defer tracker.unsubscribe(progress)
+
+	return m.Get(ctx, versionString, arch, kind)
+}
+
+// progressTracker delivers the progress of a fetch to multiple subscribers without blocking the fetch.
+type progressTracker struct {
+	mu          sync.Mutex
+	subscribers map[chan<- Progress]struct{}
+	refs        int
+}
+
+func (t *progressTracker) subscribe(ch chan<- Progress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.subscribers[ch] = struct{}{}
+}
+
+func (t *progressTracker) unsubscribe(ch chan<- Progress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.subscribers, ch)
+}
+
+func (t *progressTracker) report(progress Progress) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- progress:
+		default: // drop the update, subscriber is too slow
+		}
+	}
+}
+
+// acquireProgress returns the progress tracker for the fetch key, creating it if needed.
+//
+// The tracker is shared by the fetch and the requests waiting for it, it is removed once released by all of them.
+func (m *Manager) acquireProgress(key string) (*progressTracker, func()) {
+	m.progressMu.Lock()
+	defer m.progressMu.Unlock()
+
+	if m.progress == nil {
+		m.progress = map[string]*progressTracker{}
+	}
+
+	tracker, ok := m.progress[key]
+	if !ok {
+		tracker = &progressTracker{subscribers: map[chan<- Progress]struct{}{}}
+		m.progress[key] = tracker
+	}
+
+	tracker.refs++
+
+	return tracker, func() {
+		m.progressMu.Lock()
+		defer m.progressMu.Unlock()
+
+		tracker.refs--
+
+		if tracker.refs == 0 {
+			delete(m.progress, key)
+		}
+	}
+}
+
+type progressKey struct{}
+
+// withProgress attaches the progress tracker to the fetch context.
+func withProgress(ctx context.Context, tracker *progressTracker) context.Context {
+	return context.WithValue(ctx, progressKey{}, tracker)
+}
+
+// progressFromContext returns the progress tracker of the fetch, if any.
+func progressFromContext(ctx context.Context) *progressTracker {
+	tracker, _ := ctx.Value(progressKey{}).(*progressTracker)
+
+	return tracker
+}
+
+// progressImage wraps the image layers to report the download progress.
+type progressImage struct {
+	v1.Image
+
+	layers []v1.Layer
+}
+
+func (img *progressImage) Layers() ([]v1.Layer, error) {
+	return img.layers, nil
+}
+
+// imageWithProgress wraps the image to report the download progress to the tracker.
+func imageWithProgress(img v1.Image, tracker *progressTracker) (v1.Image, error) {
+	if tracker == nil {
+		return img, nil
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("error reading image layers: %w", err)
+	}
+
+	counter := &progressCounter{tracker: tracker}
+
+	wrapped := make([]v1.Layer, 0, len(layers))
+
+	for _, layer := range layers {
+		size, err := layer.Size()
+		if err != nil {
+			return nil, fmt.Errorf("error reading layer size: %w", err)
+		}
+
+		counter.total += size
+
+		// the uncompressed contents are derived from the counted compressed stream
+		wrappedLayer, err := partial.CompressedToLayer(&progressLayer{Layer: layer, counter: counter})
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped = append(wrapped, wrappedLayer)
+	}
+
+	return &progressImage{Image: img, layers: wrapped}, nil
+}
+
+// progressCounter counts the downloaded bytes of all image layers.
+type progressCounter struct {
+	tracker    *progressTracker
+	mu         sync.Mutex
+	downloaded int64
+	total      int64
+}
+
+func (c *progressCounter) add(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.downloaded += int64(n)
+
+	phase := ProgressPhaseDownloading
+	if c.downloaded >= c.total {
+		phase = ProgressPhaseExtracting
+	}
+
+	c.tracker.report(Progress{Phase: phase, Downloaded: c.downloaded, Total: c.total})
+}
+
+// progressLayer counts the bytes read from the compressed layer.
+type progressLayer struct {
+	v1.Layer
+
+	counter *progressCounter
+}
+
+func (l *progressLayer) Compressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+
+	return &progressReader{ReadCloser: rc, counter: l.counter}, nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+
+	counter *progressCounter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.counter.add(n)
+	}
+
+	return n, err
+}
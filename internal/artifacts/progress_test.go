@@ -0,0 +1,145 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetWithProgress(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	img, err := crane.Image(map[string][]byte{
+		"usr/install/amd64/vmlinuz": bytes.Repeat([]byte("kernel"), 1024*1024),
+	})
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	layerSize, err := layers[0].Size()
+	require.NoError(t, err)
+
+	registryHandler := registry.New()
+	hung := make(chan struct{}, 1)
+	resume := make(chan struct{})
+
+	// send half of the layer, and wait to send the rest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, layerDigest.String()) {
+			registryHandler.ServeHTTP(w, r)
+
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		registryHandler.ServeHTTP(rec, r)
+
+		w.Header().Set("Content-Length", strconv.Itoa(rec.Body.Len()))
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes()[:rec.Body.Len()/2]) //nolint:errcheck
+		w.(http.Flusher).Flush()
+
+		hung <- struct{}{}
+
+		<-resume
+
+		w.Write(rec.Body.Bytes()[rec.Body.Len()/2:]) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(u.Host+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         u.Host,
+		InsecureImageRegistry: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	var eg errgroup.Group
+
+	progress := []chan artifacts.Progress{make(chan artifacts.Progress, 1024), make(chan artifacts.Progress, 1024)}
+
+	get := func(ch chan artifacts.Progress) {
+		eg.Go(func() error {
+			_, err := manager.GetWithProgress(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, ch)
+
+			return err
+		})
+	}
+
+	get(progress[0])
+
+	select {
+	case <-hung:
+	case <-ctx.Done():
+		t.Fatal("fetch didn't start")
+	}
+
+	// the second request waits for the same fetch
+	get(progress[1])
+
+	time.Sleep(50 * time.Millisecond)
+
+	close(resume)
+
+	require.NoError(t, eg.Wait())
+
+	for i, ch := range progress {
+		close(ch)
+
+		var updates []artifacts.Progress
+
+		for update := range ch {
+			updates = append(updates, update)
+		}
+
+		require.NotEmpty(t, updates, "request %d", i)
+
+		assert.Equal(t, artifacts.Progress{
+			Phase:      artifacts.ProgressPhaseExtracting,
+			Downloaded: layerSize,
+			Total:      layerSize,
+		}, updates[len(updates)-1], "request %d", i)
+
+		if i == 0 {
+			// the first request has seen the download in progress
+			assert.Equal(t, artifacts.ProgressPhaseDownloading, updates[0].Phase)
+			assert.Less(t, updates[0].Downloaded, layerSize)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/siderolabs/gen/xerrors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// reconcileConcurrency is the number of imager images fetched concurrently by Reconcile.
+const reconcileConcurrency = 2
+
+// ArtifactSpec identifies an artifact which should be cached.
+type ArtifactSpec struct {
+	Version string
+	Arch    Arch
+	Kind    Kind
+}
+
+// ReconcileResult describes the changes made by Reconcile.
+type ReconcileResult struct {
+	// Fetched are the Talos version tags which were extracted.
+	Fetched []string
+	// Evicted are the Talos version tags which were evicted.
+	Evicted []string
+}
+
+// Reconcile brings the cache to the desired set of artifacts: missing Talos versions are fetched,
+// and the extracted versions which are not desired are evicted.
+//
+// The imager image is extracted for all architectures and kinds at once, so the cache is reconciled by Talos version,
+// and architectures and kinds are only checked to be available. Invalid specs fail before any change is made,
+// while a failure to fetch a version doesn't abort the reconciliation, all errors are returned joined.
+func (m *Manager) Reconcile(ctx context.Context, desired []ArtifactSpec) (ReconcileResult, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return ReconcileResult{}, err
+	}
+
+	defer release()
+
+	desiredTags := make(map[string][]ArtifactSpec, len(desired))
+
+	for _, spec := range desired {
+		tag, err := m.parseTag(ctx, spec.Version)
+		if err != nil {
+			return ReconcileResult{}, err
+		}
+
+		if _, ok := m.pullers[spec.Arch]; !ok {
+			return ReconcileResult{}, xerrors.NewTaggedf[ErrNotFoundTag]("architecture %q is not supported", spec.Arch)
+		}
+
+		if !m.kindSupported(spec.Kind) {
+			return ReconcileResult{}, fmt.Errorf("artifact kind %q is not supported", spec.Kind)
+		}
+
+		desiredTags[tag] = append(desiredTags[tag], spec)
+	}
+
+	extracted, err := m.extractedTags()
+	if err != nil {
+		return ReconcileResult{}, err
+	}
+
+	var (
+		result ReconcileResult
+		eg     errgroup.Group
+		mu     sync.Mutex
+		errs   []error
+	)
+
+	eg.SetLimit(reconcileConcurrency)
+
+	for tag, specs := range desiredTags {
+		eg.Go(func() error {
+			fetched := !slices.Contains(extracted, tag)
+
+			_, err := m.ensureImager(ctx, tag)
+			if err == nil {
+				err = m.checkSpecs(tag, specs)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to reconcile %s: %w", tag, err))
+			} else if fetched {
+				result.Fetched = append(result.Fetched, tag)
+			}
+
+			return nil
+		})
+	}
+
+	eg.Wait() //nolint:errcheck // errors are collected separately
+
+	for _, tag := range extracted {
+		if _, ok := desiredTags[tag]; ok {
+			continue
+		}
+
+		if err = m.evictExtracted(tag); err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		result.Evicted = append(result.Evicted, tag)
+	}
+
+	slices.Sort(result.Fetched)
+	slices.Sort(result.Evicted)
+
+	m.logger.Info("reconciled the cache", zap.Strings("fetched", result.Fetched), zap.Strings("evicted", result.Evicted))
+
+	return result, errors.Join(errs...)
+}
+
+// checkSpecs checks that the extracted Talos version has the artifacts for the specs.
+func (m *Manager) checkSpecs(tag string, specs []ArtifactSpec) error {
+	for _, spec := range specs {
+		if _, err := os.Stat(filepath.Join(m.storagePath, tag, string(spec.Arch), filepath.FromSlash(m.kindPath(spec.Kind)))); err != nil {
+			return xerrors.NewTaggedf[ErrNotFoundTag]("artifact %s/%s is not available", spec.Arch, spec.Kind)
+		}
+	}
+
+	return nil
+}
+
+// extractedTags returns the Talos version tags which are extracted to the storage.
+func (m *Manager) extractedTags() ([]string, error) {
+	entries, err := os.ReadDir(m.storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage directory: %w", err)
+	}
+
+	var tags []string
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), provenanceSuffix) {
+			continue
+		}
+
+		tag := strings.TrimSuffix(entry.Name(), provenanceSuffix)
+
+		if _, err = os.Stat(filepath.Join(m.storagePath, tag)); err != nil {
+			continue // not extracted
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// evictExtracted evicts the extracted Talos version along with its provenance and checksums.
+func (m *Manager) evictExtracted(tag string) error {
+	// the artifacts go first, so that the Talos version is never seen as extracted without the checksums
+	if err := m.evict(tag); err != nil {
+		return err
+	}
+
+	for _, suffix := range []string{provenanceSuffix, checksumSuffix} {
+		if err := os.Remove(filepath.Join(m.storagePath, tag+suffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing %q: %w", tag+suffix, err)
+		}
+	}
+
+	m.events.publish(EventEviction, tag, nil)
+
+	return nil
+}
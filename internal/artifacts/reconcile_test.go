@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestReconcile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{}, artifacts.Dependencies{StoragePath: storagePath})
+
+	for _, tag := range []string{"v1.6.0", "v1.7.0", "v1.7.1"} {
+		pushImager(t, registryHost, tag)
+	}
+
+	_, err := manager.Get(ctx, "1.6.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	result, err := manager.Reconcile(ctx, []artifacts.ArtifactSpec{
+		{Version: "1.7.0", Arch: artifacts.ArchAmd64, Kind: artifacts.KindKernel},
+		{Version: "1.7.1", Arch: artifacts.ArchAmd64, Kind: artifacts.KindKernel},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, artifacts.ReconcileResult{
+		Fetched: []string{"v1.7.1"},
+		Evicted: []string{"v1.6.0"},
+	}, result)
+
+	versions, err := manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []semver.Version{semver.MustParse("1.7.1"), semver.MustParse("1.7.0")}, versions)
+	assert.NoFileExists(t, filepath.Join(storagePath, "v1.6.0.digest"))
+
+	// reconciling again is a no-op
+	result, err = manager.Reconcile(ctx, []artifacts.ArtifactSpec{
+		{Version: "1.7.0", Arch: artifacts.ArchAmd64, Kind: artifacts.KindKernel},
+		{Version: "1.7.1", Arch: artifacts.ArchAmd64, Kind: artifacts.KindKernel},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Fetched)
+	assert.Empty(t, result.Evicted)
+
+	// missing artifact is reported, but the version is kept
+	result, err = manager.Reconcile(ctx, []artifacts.ArtifactSpec{
+		{Version: "1.7.1", Arch: artifacts.ArchArm64, Kind: artifacts.KindKernel},
+	})
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err), err)
+	assert.Equal(t, []string{"v1.7.0"}, result.Evicted)
+
+	// invalid spec makes no changes
+	_, err = manager.Reconcile(ctx, []artifacts.ArtifactSpec{
+		{Version: "1.5.0", Arch: artifacts.ArchAmd64, Kind: artifacts.KindKernel},
+	})
+	require.Error(t, err)
+
+	versions, err = manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []semver.Version{semver.MustParse("1.7.1")}, versions)
+}
@@ -0,0 +1,58 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/siderolabs/gen/xslices"
+)
+
+// CheckRequiredExtensions checks that the official extensions of the schematic include Options.RequiredExtensions.
+//
+// The extension names are the repositories of the official extensions, e.g. "siderolabs/gvisor". A required extension
+// which is not available for the Talos version can't be included, so it fails the check as well.
+// All missing extensions are returned joined, each wrapping ErrMissingRequiredExtension.
+func (m *Manager) CheckRequiredExtensions(ctx context.Context, versionString string, extensionNames []string) error {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	if len(m.options.RequiredExtensions) == 0 {
+		return nil
+	}
+
+	extensions, err := m.GetOfficialExtensions(ctx, versionString)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, required := range m.options.RequiredExtensions {
+		if !slices.ContainsFunc(extensions, func(ref ExtensionRef) bool { return ref.TaggedReference.RepositoryStr() == required }) {
+			errs = append(errs, fmt.Errorf("%w: %q is not available for Talos version %s", ErrMissingRequiredExtension, required, versionString))
+
+			continue
+		}
+
+		if !slices.Contains(extensionNames, required) {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrMissingRequiredExtension, required))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// extensionNames returns the repositories of the extension refs, as in Options.RequiredExtensions.
+func extensionNames(refs []ExtensionRef) []string {
+	return xslices.Map(refs, func(ref ExtensionRef) string { return ref.TaggedReference.RepositoryStr() })
+}
@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestRequiredExtensions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		RequiredExtensions: []string{"siderolabs/gvisor"},
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+	pushImager(t, registryHost, "v1.8.0")
+	pushImage(t, registryHost, artifacts.ExtensionManifestImage, "v1.7.0", map[string][]byte{
+		"image-digests": []byte("ghcr.io/siderolabs/gvisor:20231214.0" +
+			"@sha256:548b2b121611424f6b1b6cfb72a1669421ffaf2f1560911c324a546c7cee655e\n" +
+			"ghcr.io/siderolabs/nvidia:535.129.03" +
+			"@sha256:1b1a8b1b1a418d6b1b1d2f3e0f8b4b3b0c5a0f9b8e7d6c5b4a39281706f5e4d3\n"),
+	})
+	pushImage(t, registryHost, artifacts.ExtensionManifestImage, "v1.8.0", map[string][]byte{
+		"image-digests": []byte("ghcr.io/siderolabs/nvidia:535.129.03" +
+			"@sha256:1b1a8b1b1a418d6b1b1d2f3e0f8b4b3b0c5a0f9b8e7d6c5b4a39281706f5e4d3\n"),
+	})
+
+	require.NoError(t, manager.CheckRequiredExtensions(ctx, "1.7.0", []string{"siderolabs/nvidia", "siderolabs/gvisor"}))
+
+	err := manager.CheckRequiredExtensions(ctx, "1.7.0", []string{"siderolabs/nvidia"})
+	require.ErrorIs(t, err, artifacts.ErrMissingRequiredExtension)
+	assert.ErrorContains(t, err, `"siderolabs/gvisor"`)
+
+	// the extension can't be included if it's not available for the version
+	err = manager.CheckRequiredExtensions(ctx, "1.8.0", []string{"siderolabs/nvidia", "siderolabs/gvisor"})
+	require.ErrorIs(t, err, artifacts.ErrMissingRequiredExtension)
+	assert.ErrorContains(t, err, "not available")
+
+	_, err = manager.GetSchematicArtifacts(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, nil)
+	require.ErrorIs(t, err, artifacts.ErrMissingRequiredExtension)
+}
@@ -0,0 +1,108 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/siderolabs/gen/xerrors"
+)
+
+// ResolveExtensionForArch finds the official extension for the Talos version by name (e.g. "siderolabs/gvisor"),
+// and checks that the extension image is available for the architecture.
+//
+// Multi-arch extension images must have a manifest for the architecture, while single-arch images
+// must be built for it.
+func (m *Manager) ResolveExtensionForArch(ctx context.Context, versionString, extensionName string, arch Arch) (ExtensionRef, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return ExtensionRef{}, err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return ExtensionRef{}, err
+	}
+
+	extensions, err := m.GetOfficialExtensions(ctx, versionString)
+	if err != nil {
+		return ExtensionRef{}, err
+	}
+
+	for _, ref := range extensions {
+		if ref.TaggedReference.RepositoryStr() != extensionName {
+			continue
+		}
+
+		if err = m.checkExtensionArch(ctx, ref, arch); err != nil {
+			return ExtensionRef{}, err
+		}
+
+		return ref, nil
+	}
+
+	return ExtensionRef{}, xerrors.NewTaggedf[ErrNotFoundTag]("extension %q is not available for Talos version %s", extensionName, versionString)
+}
+
+// checkExtensionArch checks that the extension image is available for the architecture.
+func (m *Manager) checkExtensionArch(ctx context.Context, ref ExtensionRef, arch Arch) error {
+	imageRef, desc, img, err := m.extensionImage(ctx, ref, arch)
+	if err != nil {
+		return err
+	}
+
+	if desc.MediaType.IsIndex() {
+		return nil
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("error reading extension image config: %w", err)
+	}
+
+	if config.Architecture != "" && config.Architecture != string(arch) {
+		return xerrors.NewTaggedf[ErrNotFoundTag]("extension image %s is built for %s, not %s", imageRef, config.Architecture, arch)
+	}
+
+	return nil
+}
+
+// extensionImage gets the extension image (with the digest set) for the architecture, without pulling the layers.
+func (m *Manager) extensionImage(ctx context.Context, ref ExtensionRef, arch Arch) (name.Digest, *remote.Descriptor, v1.Image, error) {
+	imageRef := m.extensionRepository(ref).Digest(ref.Digest)
+
+	var desc *remote.Descriptor
+
+	if err := m.guardRegistry(RegistryOpPull, imageRef.RegistryStr(), func() error {
+		var err error
+
+		desc, err = m.pullers[arch].Get(ctx, imageRef)
+
+		return err
+	}); err != nil {
+		var transportError *transport.Error
+
+		if errors.As(err, &transportError) && transportError.StatusCode == http.StatusNotFound {
+			return imageRef, nil, nil, xerrors.NewTaggedf[ErrNotFoundTag]("extension image %s is not found", imageRef)
+		}
+
+		return imageRef, nil, nil, fmt.Errorf("error getting extension image %s: %w", imageRef, err)
+	}
+
+	img, err := imageForArch(imageRef, desc, arch)
+	if err != nil {
+		return imageRef, nil, nil, err
+	}
+
+	return imageRef, desc, img, nil
+}
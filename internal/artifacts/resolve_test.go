@@ -0,0 +1,84 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestResolveExtensionForArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	index := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add: img,
+		Descriptor: v1.Descriptor{
+			Platform: &v1.Platform{
+				OS:           "linux",
+				Architecture: string(artifacts.ArchAmd64),
+			},
+		},
+	})
+
+	multiArchTag := extensionTag(t, registryHost, "siderolabs/multi-arch")
+	require.NoError(t, remote.WriteIndex(multiArchTag, index))
+
+	indexDigest, err := index.Digest()
+	require.NoError(t, err)
+
+	singleArchImg, err := mutate.ConfigFile(empty.Image, &v1.ConfigFile{Architecture: string(artifacts.ArchArm64), OS: "linux"})
+	require.NoError(t, err)
+
+	singleArchTag := extensionTag(t, registryHost, "siderolabs/single-arch")
+	require.NoError(t, remote.Write(singleArchTag, singleArchImg))
+
+	imgDigest, err := singleArchImg.Digest()
+	require.NoError(t, err)
+
+	pushImage(t, registryHost, artifacts.ExtensionManifestImage, "v1.7.0", map[string][]byte{
+		"image-digests": []byte("ghcr.io/siderolabs/multi-arch:v1.0.0@" + indexDigest.String() + "\n" +
+			"ghcr.io/siderolabs/single-arch:v1.0.0@" + imgDigest.String() + "\n"),
+	})
+
+	ref, err := manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/multi-arch", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	assert.Equal(t, indexDigest.String(), ref.Digest)
+
+	_, err = manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/multi-arch", artifacts.ArchArm64)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	ref, err = manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/single-arch", artifacts.ArchArm64)
+	require.NoError(t, err)
+	assert.Equal(t, imgDigest.String(), ref.Digest)
+
+	_, err = manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/single-arch", artifacts.ArchAmd64)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	_, err = manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/missing", artifacts.ArchAmd64)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	_, err = manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/single-arch", "riscv64")
+	assert.ErrorIs(t, err, artifacts.ErrInvalidArch)
+}
@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/blang/semver/v4"
+	"go.uber.org/zap"
+)
+
+// applyRetention evicts the extracted Talos versions beyond Options.RetainPatchesPerMinor newest patches of each minor release.
+func (m *Manager) applyRetention() {
+	if m.options.RetainPatchesPerMinor <= 0 {
+		return
+	}
+
+	m.retentionMu.Lock()
+	defer m.retentionMu.Unlock()
+
+	tags, err := m.extractedTags()
+	if err != nil {
+		m.logger.Error("failed to apply retention", zap.Error(err))
+
+		return
+	}
+
+	tagParser := m.options.TagParser
+	if tagParser == nil {
+		tagParser = parseTagTolerant
+	}
+
+	type extractedVersion struct {
+		version semver.Version
+		tag     string
+	}
+
+	minors := map[string][]extractedVersion{}
+
+	for _, tag := range tags {
+		version, ok := tagParser(tag)
+		if !ok {
+			continue
+		}
+
+		minor := fmt.Sprintf("%d.%d", version.Major, version.Minor)
+		minors[minor] = append(minors[minor], extractedVersion{version: version, tag: tag})
+	}
+
+	for _, versions := range minors {
+		if len(versions) <= m.options.RetainPatchesPerMinor {
+			continue
+		}
+
+		// newest first
+		slices.SortFunc(versions, func(a, b extractedVersion) int {
+			return b.version.Compare(a.version)
+		})
+
+		for _, extracted := range versions[m.options.RetainPatchesPerMinor:] {
+			if m.fetchInProgress(extracted.tag) || m.readers.active(extracted.tag) {
+				m.logger.Debug("skipping retention for Talos version in use", zap.String("tag", extracted.tag))
+
+				continue
+			}
+
+			m.logger.Info("evicting Talos version by retention policy", zap.String("tag", extracted.tag))
+
+			if err = m.evictExtracted(extracted.tag); err != nil {
+				m.logger.Error("failed to evict Talos version", zap.String("tag", extracted.tag), zap.Error(err))
+			}
+		}
+	}
+}
+
+// fetchInProgress returns true if the fetch with the key is in progress.
+func (m *Manager) fetchInProgress(key string) bool {
+	m.fetchesMu.Lock()
+	defer m.fetchesMu.Unlock()
+
+	_, ok := m.fetches[key]
+
+	return ok
+}
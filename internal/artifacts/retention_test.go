@@ -0,0 +1,53 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xslices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestRetainPatchesPerMinor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		RetainPatchesPerMinor: 1,
+	})
+
+	for _, tag := range []string{"v1.6.0", "v1.6.1", "v1.7.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	r, err := manager.Open(ctx, "1.6.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	_, err = manager.Get(ctx, "1.6.1", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	// the version with open readers is kept
+	versions, err := manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.6.1", "1.6.0"}, xslices.Map(versions, semver.Version.String))
+
+	require.NoError(t, r.Close())
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	versions, err = manager.CachedVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.7.0", "1.6.1"}, xslices.Map(versions, semver.Version.String))
+}
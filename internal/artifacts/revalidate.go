@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RevalidateCache checks the extracted Talos versions against the registry, and returns the tags
+// which were re-published since they were extracted.
+//
+// Only the imager image digests are compared, nothing is pulled or re-extracted. Stale versions are
+// re-extracted on access with PullPolicyAlways. Errors for individual tags don't abort the check.
+func (m *Manager) RevalidateCache(ctx context.Context) ([]string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	entries, err := os.ReadDir(m.storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage directory: %w", err)
+	}
+
+	var (
+		stale []string
+		errs  []error
+	)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), provenanceSuffix) {
+			continue
+		}
+
+		tag := strings.TrimSuffix(entry.Name(), provenanceSuffix)
+
+		if _, err = os.Stat(filepath.Join(m.storagePath, tag)); err != nil {
+			continue // not extracted
+		}
+
+		changed, err := m.imagerChanged(ctx, tag)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			errs = append(errs, fmt.Errorf("failed to revalidate %s: %w", tag, err))
+
+			continue
+		}
+
+		if changed {
+			stale = append(stale, tag)
+		}
+	}
+
+	slices.Sort(stale)
+
+	return stale, errors.Join(errs...)
+}
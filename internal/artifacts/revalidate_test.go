@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestRevalidateCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"v1.7.0", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+
+		_, err := manager.Get(ctx, tag, artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+	}
+
+	stale, err := manager.RevalidateCache(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+
+	// re-published release
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.8.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("rebuilt kernel"),
+	})
+
+	stale, err = manager.RevalidateCache(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.8.0"}, stale)
+}
@@ -0,0 +1,230 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/siderolabs/gen/xerrors"
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/regtransport"
+)
+
+// sbomSuffix is the suffix of the OCI layout which stores SBOM artifacts of an image.
+const sbomSuffix = "-sbom"
+
+// defaultSBOMNotAvailableTTL is the default of Options.SBOMNotAvailableTTL.
+const defaultSBOMNotAvailableTTL = time.Hour
+
+// sbomArtifactTypes are the artifact types of SBOM and attestation referrers.
+var sbomArtifactTypes = []string{
+	"application/spdx+json",
+	"application/vnd.cyclonedx+json",
+	"application/vnd.in-toto+json",
+}
+
+// fetchSBOM fetches SBOM artifacts referring to the image, and exports them to the storage as OCI.
+//
+// If the image has no SBOM artifacts, nothing is stored. Partially exported SBOMs are cleaned up on failure.
+func (m *Manager) fetchSBOM(ctx context.Context, digestRef name.Digest, destPath string) error {
+	// set a timeout for fetching, the fetch context isn't bound to the request, as we want fetch operation to finish
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+
+	exported, err := m.exportSBOM(ctx, digestRef, destPath+tmpSuffix)
+	if err != nil {
+		m.removePartial(destPath + tmpSuffix)
+
+		return err
+	}
+
+	if !exported {
+		return nil
+	}
+
+	return os.Rename(destPath+tmpSuffix, destPath)
+}
+
+// exportSBOM exports SBOM artifacts referring to the image to the OCI layout at the path, and returns false if there are none.
+func (m *Manager) exportSBOM(ctx context.Context, digestRef name.Digest, path string) (bool, error) {
+	remoteOptions := append(slices.Clone(m.remoteOptions), remote.WithContext(ctx))
+
+	referrers, err := remote.Referrers(digestRef, remoteOptions...)
+	if err != nil {
+		if regtransport.IsStatusCodeError(err, http.StatusNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("error listing referrers: %w", err)
+	}
+
+	referrersManifest, err := referrers.IndexManifest()
+	if err != nil {
+		return false, fmt.Errorf("error reading referrers: %w", err)
+	}
+
+	var l layout.Path
+
+	for _, desc := range referrersManifest.Manifests {
+		if !slices.Contains(sbomArtifactTypes, desc.ArtifactType) {
+			continue
+		}
+
+		if l == "" {
+			if err = os.RemoveAll(path); err != nil {
+				return false, fmt.Errorf("error removing the directory %q: %w", path, err)
+			}
+
+			l, err = layout.Write(path, empty.Index)
+			if err != nil {
+				return false, fmt.Errorf("error creating layout: %w", err)
+			}
+		}
+
+		sbomRef := digestRef.Context().Digest(desc.Digest.String())
+
+		m.logger.Info("exporting the SBOM", zap.Stringer("image", digestRef), zap.Stringer("sbom", sbomRef))
+
+		img, err := remote.Image(sbomRef, remoteOptions...)
+		if err != nil {
+			return false, fmt.Errorf("error pulling SBOM %s: %w", sbomRef, err)
+		}
+
+		if err = l.AppendImage(img); err != nil {
+			return false, fmt.Errorf("error exporting SBOM %s: %w", sbomRef, err)
+		}
+	}
+
+	return l != "", nil
+}
+
+// GetSBOM returns the path to the OCI layout with SBOM artifacts attached to the imager image of the Talos version.
+//
+// The SBOMs attached to the image for the architecture are preferred over the ones attached to the image index.
+// ErrSBOMNotAvailable is returned if the Talos version has no SBOM, the result is cached for Options.SBOMNotAvailableTTL.
+// See parseTag for the accepted version formats.
+func (m *Manager) GetSBOM(ctx context.Context, versionString string, arch Arch) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	if err = validateArch(arch); err != nil {
+		return "", err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	sbomPath := filepath.Join(m.storagePath, string(arch)+"-imager-"+tag+sbomSuffix)
+
+	// check if already fetched
+	if _, err = os.Stat(sbomPath); err == nil {
+		m.events.publish(EventCacheHit, sbomPath, nil)
+
+		return sbomPath, nil
+	}
+
+	if m.sbomNotAvailableCached(sbomPath) {
+		return "", xerrors.NewTaggedf[ErrNotFoundTag]("%w: Talos version %s", ErrSBOMNotAvailable, tag)
+	}
+
+	resultCh := m.fetchOnce(FetchGroupSBOM, sbomPath, func(ctx context.Context) error {
+		return m.fetchImagerSBOM(ctx, tag, arch, sbomPath)
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case result := <-resultCh:
+		if result.Err != nil {
+			return "", result.Err
+		}
+	}
+
+	if _, err = os.Stat(sbomPath); err != nil {
+		m.cacheSBOMNotAvailable(sbomPath)
+
+		return "", xerrors.NewTaggedf[ErrNotFoundTag]("%w: Talos version %s", ErrSBOMNotAvailable, tag)
+	}
+
+	return sbomPath, nil
+}
+
+// fetchImagerSBOM fetches SBOM artifacts attached to the imager image for the architecture, or to the image index.
+func (m *Manager) fetchImagerSBOM(ctx context.Context, tag string, arch Arch, sbomPath string) error {
+	digestRef, img, err := m.imagerImage(ctx, tag, arch)
+	if err != nil {
+		return err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return fmt.Errorf("error getting imager image digest: %w", err)
+	}
+
+	platformRef := digestRef.Context().Digest(digest.String())
+
+	if err = m.fetchSBOM(ctx, platformRef, sbomPath); err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(sbomPath); err == nil || platformRef.String() == digestRef.String() {
+		return nil
+	}
+
+	return m.fetchSBOM(ctx, digestRef, sbomPath)
+}
+
+// sbomNotAvailableCached returns true if the SBOM at the path was recently found to be not available.
+func (m *Manager) sbomNotAvailableCached(sbomPath string) bool {
+	m.sbomNotAvailableMu.Lock()
+	defer m.sbomNotAvailableMu.Unlock()
+
+	expires, ok := m.sbomNotAvailable[sbomPath]
+	if !ok {
+		return false
+	}
+
+	if m.now().After(expires) {
+		delete(m.sbomNotAvailable, sbomPath)
+
+		return false
+	}
+
+	return true
+}
+
+// cacheSBOMNotAvailable caches the "no SBOM" result for the SBOM at the path.
+func (m *Manager) cacheSBOMNotAvailable(sbomPath string) {
+	ttl := m.options.SBOMNotAvailableTTL
+	if ttl <= 0 {
+		ttl = defaultSBOMNotAvailableTTL
+	}
+
+	m.sbomNotAvailableMu.Lock()
+	defer m.sbomNotAvailableMu.Unlock()
+
+	if m.sbomNotAvailable == nil {
+		m.sbomNotAvailable = map[string]time.Time{}
+	}
+
+	m.sbomNotAvailable[sbomPath] = m.now().Add(ttl)
+}
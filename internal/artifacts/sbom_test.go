@@ -0,0 +1,240 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetExtensionSBOM(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{FetchExtensionSBOMs: true})
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/with-sbom")
+	require.NoError(t, remote.Write(tag, img))
+
+	desc, err := partial.Descriptor(img)
+	require.NoError(t, err)
+
+	sbom, err := random.Image(128, 1)
+	require.NoError(t, err)
+
+	sbom = mutate.ConfigMediaType(sbom, "application/spdx+json")
+	sbom = mutate.MediaType(sbom, types.OCIManifestSchema1)
+
+	sbom, ok := mutate.Subject(sbom, *desc).(v1.Image)
+	require.True(t, ok)
+
+	sbomDigest, err := sbom.Digest()
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(tag.Context().Digest(sbomDigest.String()), sbom))
+
+	sbomPath, err := manager.GetExtensionSBOM(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag, Digest: desc.Digest.String()})
+	require.NoError(t, err)
+
+	index, err := layout.ImageIndexFromPath(sbomPath)
+	require.NoError(t, err)
+
+	indexManifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, indexManifest.Manifests, 1)
+	assert.Equal(t, sbomDigest, indexManifest.Manifests[0].Digest)
+
+	// no SBOM attached
+	img, err = random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag = extensionTag(t, registryHost, "siderolabs/without-sbom")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	_, err = manager.GetExtensionSBOM(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()})
+	require.Error(t, err)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	// SBOM which fails to export, as its layers are missing
+	img, err = random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag = extensionTag(t, registryHost, "siderolabs/broken-sbom")
+	require.NoError(t, remote.Write(tag, img))
+
+	desc, err = partial.Descriptor(img)
+	require.NoError(t, err)
+
+	for _, size := range []int64{128, 256} {
+		sbom, err = random.Image(size, 1)
+		require.NoError(t, err)
+
+		sbom = mutate.ConfigMediaType(sbom, "application/spdx+json")
+		sbom = mutate.MediaType(sbom, types.OCIManifestSchema1)
+
+		sbom, ok = mutate.Subject(sbom, *desc).(v1.Image)
+		require.True(t, ok)
+
+		sbomDigest, err = sbom.Digest()
+		require.NoError(t, err)
+
+		require.NoError(t, remote.Write(tag.Context().Digest(sbomDigest.String()), sbom))
+	}
+
+	layers, err := sbom.Layers()
+	require.NoError(t, err)
+
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, "http://"+registryHost+"/v2/siderolabs/broken-sbom/blobs/"+layerDigest.String(), nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: desc.Digest.String()}
+
+	ociPath, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+	require.NoError(t, err)
+
+	// the partially exported SBOMs are cleaned up
+	assert.NoDirExists(t, ociPath+"-sbom-tmp")
+
+	_, err = manager.GetExtensionSBOM(ctx, artifacts.ArchAmd64, ref)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
+
+func TestGetSBOM(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	for _, tag := range []string{"v1.7.0", "v1.7.1"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+
+	desc, err := remote.Head(ref)
+	require.NoError(t, err)
+
+	sbom, err := random.Image(128, 1)
+	require.NoError(t, err)
+
+	sbom = mutate.ConfigMediaType(sbom, "application/spdx+json")
+	sbom = mutate.MediaType(sbom, types.OCIManifestSchema1)
+
+	sbom, ok := mutate.Subject(sbom, *desc).(v1.Image)
+	require.True(t, ok)
+
+	sbomDigest, err := sbom.Digest()
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(ref.Context().Digest(sbomDigest.String()), sbom))
+
+	sbomPath, err := manager.GetSBOM(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+
+	index, err := layout.ImageIndexFromPath(sbomPath)
+	require.NoError(t, err)
+
+	indexManifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, indexManifest.Manifests, 1)
+	assert.Equal(t, sbomDigest, indexManifest.Manifests[0].Digest)
+
+	cachedPath, err := manager.GetSBOM(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+	assert.Equal(t, sbomPath, cachedPath)
+
+	_, err = manager.GetSBOM(ctx, "1.7.1", artifacts.ArchAmd64)
+	require.ErrorIs(t, err, artifacts.ErrSBOMNotAvailable)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
+
+func TestGetSBOMNotAvailableTTL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	now := time.Now()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{SBOMNotAvailableTTL: time.Hour}, artifacts.Dependencies{
+		Now: func() time.Time {
+			return now
+		},
+	})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	_, err := manager.GetSBOM(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.ErrorIs(t, err, artifacts.ErrSBOMNotAvailable)
+
+	ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+	require.NoError(t, err)
+
+	desc, err := remote.Head(ref)
+	require.NoError(t, err)
+
+	sbom, err := random.Image(128, 1)
+	require.NoError(t, err)
+
+	sbom = mutate.ConfigMediaType(sbom, "application/spdx+json")
+	sbom = mutate.MediaType(sbom, types.OCIManifestSchema1)
+
+	sbom, ok := mutate.Subject(sbom, *desc).(v1.Image)
+	require.True(t, ok)
+
+	sbomDigest, err := sbom.Digest()
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(ref.Context().Digest(sbomDigest.String()), sbom))
+
+	// the SBOM is not looked up again until the TTL expires
+	_, err = manager.GetSBOM(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.ErrorIs(t, err, artifacts.ErrSBOMNotAvailable)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	now = now.Add(2 * time.Hour)
+
+	sbomPath, err := manager.GetSBOM(ctx, "1.7.0", artifacts.ArchAmd64)
+	require.NoError(t, err)
+
+	index, err := layout.ImageIndexFromPath(sbomPath)
+	require.NoError(t, err)
+
+	indexManifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, indexManifest.Manifests, 1)
+	assert.Equal(t, sbomDigest, indexManifest.Manifests[0].Digest)
+}
@@ -8,13 +8,18 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 
+	"github.com/siderolabs/gen/xslices"
 	"github.com/siderolabs/talos/pkg/imager/quirks"
 	"github.com/siderolabs/talos/pkg/machinery/extensions"
+	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 
 	"github.com/siderolabs/image-factory/pkg/constants"
@@ -23,6 +28,13 @@ import (
 
 // GetSchematicExtension returns a path to the tarball with "virtual" extension matching a specified schematic.
 func (m *Manager) GetSchematicExtension(ctx context.Context, versiontag string, schematic *schematic.Schematic) (string, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
 	schematicID, err := schematic.ID()
 	if err != nil {
 		return "", err
@@ -39,8 +51,8 @@ func (m *Manager) GetSchematicExtension(ctx context.Context, versiontag string,
 		}
 	}
 
-	resultCh := m.sf.DoChan(schematicID, func() (any, error) {
-		return nil, m.buildSchematicExtension(schematicID, extensionPath, schematicInfo)
+	resultCh := m.fetchOnce(FetchGroupSchematics, schematicID, func(context.Context) error {
+		return m.buildSchematicExtension(schematicID, extensionPath, schematicInfo)
 	})
 
 	select {
@@ -55,6 +67,107 @@ func (m *Manager) GetSchematicExtension(ctx context.Context, versiontag string,
 	}
 }
 
+// SchematicResult is the set of artifacts to build an image for a schematic.
+type SchematicResult struct {
+	// Path is the path to the Talos version artifact.
+	Path string
+	// ImagerDigest is the digest of the imager image the artifact was extracted from.
+	ImagerDigest string
+	// ExtensionPaths are the paths to the OCI layouts of the extension images, in the order of the refs.
+	ExtensionPaths []string
+}
+
+// GetSchematicArtifacts returns the Talos version artifact along with the extension images for the schematic.
+//
+// The imager image and the extension images are fetched concurrently, the first error aborts the whole set.
+// The extensions must include Options.RequiredExtensions.
+func (m *Manager) GetSchematicArtifacts(ctx context.Context, versionString string, arch Arch, kind Kind, refs []ExtensionRef) (SchematicResult, error) {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return SchematicResult{}, err
+	}
+
+	defer release()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return SchematicResult{}, err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return SchematicResult{}, err
+	}
+
+	if err = m.CheckRequiredExtensions(ctx, versionString, extensionNames(refs)); err != nil {
+		return SchematicResult{}, err
+	}
+
+	result := SchematicResult{
+		ExtensionPaths: make([]string, len(refs)),
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		_, err := m.ensureImager(egCtx, tag)
+
+		return err
+	})
+
+	for i, ref := range refs {
+		eg.Go(func() error {
+			path, err := m.GetExtensionImage(egCtx, arch, ref)
+			if err != nil {
+				return fmt.Errorf("failed to get extension %s: %w", ref.TaggedReference, err)
+			}
+
+			result.ExtensionPaths[i] = path
+
+			return nil
+		})
+	}
+
+	if err = eg.Wait(); err != nil {
+		return SchematicResult{}, err
+	}
+
+	provenance, err := os.ReadFile(filepath.Join(m.storagePath, tag+provenanceSuffix))
+	if err != nil {
+		return SchematicResult{}, fmt.Errorf("error reading provenance: %w", err)
+	}
+
+	result.ImagerDigest = string(provenance)
+
+	result.Path, err = m.artifactPath(ctx, tag, arch, kind)
+	if err != nil {
+		return SchematicResult{}, err
+	}
+
+	return result, nil
+}
+
+// SchematicHash returns a stable hash of the extension set and extra customization data.
+//
+// The hash doesn't depend on the order of the extensions.
+func SchematicHash(refs []ExtensionRef, extra []byte) string {
+	digests := xslices.Map(refs, func(ref ExtensionRef) string {
+		return ref.Digest
+	})
+
+	slices.Sort(digests)
+
+	hash := sha256.New()
+
+	for _, digest := range digests {
+		hash.Write([]byte(digest))
+		hash.Write([]byte{'\n'})
+	}
+
+	hash.Write(extra)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 // schematicExtension builds a "virtual" extension matching a specified schematic.
 func schematicExtension(schematicID string, schematicInfo []byte) (io.Reader, error) {
 	manifest := extensions.Manifest{
@@ -136,6 +249,10 @@ func (m *Manager) buildSchematicExtension(schematicID, extensionPath string, sch
 		return fmt.Errorf("failed to build schematic layer: %w", err)
 	}
 
+	if err = os.MkdirAll(m.schematicsPath, 0o700); err != nil {
+		return fmt.Errorf("failed to create schematics directory %q: %w", m.schematicsPath, err)
+	}
+
 	f, err := os.Create(extensionPath + ".tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create extension tarball: %w", err)
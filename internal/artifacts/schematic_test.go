@@ -0,0 +1,85 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestGetSchematicArtifacts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	imagerDigest, err := crane.Digest(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", crane.Insecure)
+	require.NoError(t, err)
+
+	var refs []artifacts.ExtensionRef
+
+	for _, repository := range []string{"siderolabs/gvisor", "siderolabs/iscsi-tools"} {
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+
+		tag := extensionTag(t, registryHost, repository)
+		require.NoError(t, remote.Write(tag, img))
+
+		refs = append(refs, artifacts.ExtensionRef{TaggedReference: tag})
+	}
+
+	result, err := manager.GetSchematicArtifacts(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, refs)
+	require.NoError(t, err)
+
+	assert.FileExists(t, result.Path)
+	assert.Equal(t, imagerDigest, result.ImagerDigest)
+	require.Len(t, result.ExtensionPaths, len(refs))
+
+	for i, ref := range refs {
+		path, err := manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+		require.NoError(t, err)
+		assert.Equal(t, path, result.ExtensionPaths[i])
+	}
+
+	// any missing extension fails the whole set
+	_, err = manager.GetSchematicArtifacts(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel,
+		append(refs, artifacts.ExtensionRef{TaggedReference: extensionTag(t, registryHost, "siderolabs/missing")}))
+	assert.ErrorContains(t, err, "siderolabs/missing")
+}
+
+func TestSchematicHash(t *testing.T) {
+	t.Parallel()
+
+	refs := []artifacts.ExtensionRef{
+		{Digest: "sha256:2222222222222222222222222222222222222222222222222222222222222222"},
+		{Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+		{Digest: "sha256:3333333333333333333333333333333333333333333333333333333333333333"},
+	}
+
+	reordered := []artifacts.ExtensionRef{refs[2], refs[0], refs[1]}
+
+	hash := artifacts.SchematicHash(refs, []byte("extra"))
+
+	// stable across runs
+	assert.Equal(t, "7d6875dd0dba1f1549eaceb3c7c4d6c4fcb307f2197bf48e39b0659a1e599ed2", hash)
+
+	// order-independent
+	assert.Equal(t, hash, artifacts.SchematicHash(reordered, []byte("extra")))
+
+	// depends on the extra data and the extension set
+	assert.NotEqual(t, hash, artifacts.SchematicHash(refs, nil))
+	assert.NotEqual(t, hash, artifacts.SchematicHash(refs[:2], []byte("extra")))
+}
@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"go.uber.org/zap"
+)
+
+// verifySignature verifies the image signature according to the signature policy.
+func (m *Manager) verifySignature(ctx context.Context, logger *zap.Logger, digestRef name.Digest) error {
+	if m.options.SignaturePolicy == "" {
+		return nil
+	}
+
+	logger.Info("verifying image signature")
+
+	_, bundleVerified, err := cosign.VerifyImageSignatures(ctx, digestRef, &m.options.ImageVerifyOptions)
+	if err == nil {
+		logger.Info("image signature verified", zap.Bool("bundle_verified", bundleVerified))
+
+		return nil
+	}
+
+	if isInvalidSignature(err) || m.options.SignaturePolicy == SignaturePolicyEnforce {
+		return fmt.Errorf("failed to verify image signature for %s: %w", digestRef.Name(), err)
+	}
+
+	logger.Warn("image signature verification is inconclusive, proceeding", zap.Error(err))
+
+	return nil
+}
+
+// verifyExtensionSignature enforces Options.RequireExtensionSignatures for the extension image.
+//
+// Definite verification results are cached by the image digest, while inconclusive ones are retried on the next fetch.
+func (m *Manager) verifyExtensionSignature(ctx context.Context, ref ExtensionRef, digestRef name.Digest) error {
+	if !m.options.RequireExtensionSignatures || slices.Contains(m.options.ExtensionSignatureExceptions, ref.TaggedReference.RegistryStr()) {
+		return nil
+	}
+
+	m.extensionSignaturesMu.Lock()
+	cachedErr, ok := m.extensionSignatures[digestRef.DigestStr()]
+	m.extensionSignaturesMu.Unlock()
+
+	if ok {
+		return cachedErr
+	}
+
+	m.logger.Info("verifying extension image signature", zap.Stringer("image", digestRef))
+
+	_, _, err := cosign.VerifyImageSignatures(ctx, digestRef, &m.options.ImageVerifyOptions)
+	if err == nil || isInvalidSignature(err) {
+		if err != nil {
+			err = fmt.Errorf("%w: %s: %w", ErrExtensionUnsigned, ref.TaggedReference, err)
+		}
+
+		m.extensionSignaturesMu.Lock()
+
+		if m.extensionSignatures == nil {
+			m.extensionSignatures = map[string]error{}
+		}
+
+		m.extensionSignatures[digestRef.DigestStr()] = err
+		m.extensionSignaturesMu.Unlock()
+
+		return err
+	}
+
+	return fmt.Errorf("failed to verify extension image signature for %s: %w", ref.TaggedReference, err)
+}
+
+// isInvalidSignature returns true if the error is a definite verification failure, e.g. the signature is missing or doesn't match.
+//
+// Other errors (e.g. the transparency log or the registry is unreachable) mean that the signature couldn't be verified.
+func isInvalidSignature(err error) bool {
+	var (
+		verificationFailure *cosign.VerificationFailure
+		noMatchingSignature *cosign.ErrNoMatchingSignatures
+		noSignaturesFound   *cosign.ErrNoSignaturesFound
+	)
+
+	return errors.As(err, &verificationFailure) || errors.As(err, &noMatchingSignature) || errors.As(err, &noSignaturesFound)
+}
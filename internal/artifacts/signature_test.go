@@ -0,0 +1,178 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestSignaturePolicy(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	verifier, err := signature.LoadECDSAVerifier(&privateKey.PublicKey, crypto.SHA256)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name      string
+		policy    artifacts.SignaturePolicy
+		verifier  signature.Verifier
+		expectErr bool
+	}{
+		{
+			name:      "enforce unsigned",
+			policy:    artifacts.SignaturePolicyEnforce,
+			verifier:  verifier,
+			expectErr: true,
+		},
+		{
+			name:      "warn only unsigned",
+			policy:    artifacts.SignaturePolicyWarnOnly,
+			verifier:  verifier,
+			expectErr: true,
+		},
+		{
+			// no trust roots are configured, so the signature can't be verified
+			name:      "enforce verification error",
+			policy:    artifacts.SignaturePolicyEnforce,
+			expectErr: true,
+		},
+		{
+			name:   "warn only verification error",
+			policy: artifacts.SignaturePolicyWarnOnly,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			manager, registryHost := setupManager(t, artifacts.Options{
+				SignaturePolicy: test.policy,
+				ImageVerifyOptions: cosign.CheckOpts{
+					SigVerifier: test.verifier,
+					IgnoreTlog:  true,
+				},
+			})
+
+			pushImager(t, registryHost, "v1.7.0")
+
+			_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			if test.expectErr {
+				assert.ErrorContains(t, err, "failed to verify image signature")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRequireExtensionSignatures(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	verifier, err := signature.LoadECDSAVerifier(&privateKey.PublicKey, crypto.SHA256)
+	require.NoError(t, err)
+
+	registryHandler := registry.New()
+
+	var signatureLookups atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sig") {
+			signatureLookups.Add(1)
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, u.Host, "siderolabs/gvisor")
+	require.NoError(t, remote.Write(tag, img))
+
+	for _, test := range []struct {
+		name       string
+		exceptions []string
+		expectErr  bool
+	}{
+		{
+			name:      "required",
+			expectErr: true,
+		},
+		{
+			name:       "exception",
+			exceptions: []string{u.Host},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+				ImageRegistry:         u.Host,
+				InsecureImageRegistry: true,
+				ImageVerifyOptions: cosign.CheckOpts{
+					SigVerifier: verifier,
+					IgnoreTlog:  true,
+				},
+				RequireExtensionSignatures:   true,
+				ExtensionSignatureExceptions: test.exceptions,
+			})
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, manager.Close())
+			})
+
+			signatureLookups.Store(0)
+
+			_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag})
+
+			if !test.expectErr {
+				require.NoError(t, err)
+				assert.Zero(t, signatureLookups.Load())
+
+				return
+			}
+
+			require.ErrorIs(t, err, artifacts.ErrExtensionUnsigned)
+
+			lookups := signatureLookups.Load()
+			assert.Positive(t, lookups)
+
+			// the verification result is cached by digest
+			_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: tag})
+			require.ErrorIs(t, err, artifacts.ErrExtensionUnsigned)
+			assert.Equal(t, lookups, signatureLookups.Load())
+		})
+	}
+}
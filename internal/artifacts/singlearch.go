@@ -0,0 +1,181 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"go.uber.org/zap"
+)
+
+// imageArch returns the architecture of the image from its config, empty if it's not a supported architecture.
+func imageArch(img v1.Image) (Arch, error) {
+	config, err := img.ConfigFile()
+	if err != nil {
+		return "", fmt.Errorf("error reading image config: %w", err)
+	}
+
+	if arch := Arch(config.Architecture); validateArch(arch) == nil {
+		return arch, nil
+	}
+
+	return "", nil
+}
+
+// normalizeSingleArch moves the output of a single-arch imager, which is not nested under the architecture
+// directory, into the expected <arch> layout, and returns the checksums keyed by the moved paths.
+//
+// The output is kept as is if it has any architecture directory, or if the architecture is not known.
+// If the architecture has its own storage root in archDestinations, the output is moved there, and linked
+// into the destination as the nested output is.
+func normalizeSingleArch(logger *zap.Logger, destination string, archDestinations map[Arch]string, arch Arch, checksums map[string]string) (map[string]string, error) {
+	if arch == "" {
+		return checksums, nil
+	}
+
+	entries, err := os.ReadDir(destination)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return checksums, nil
+		}
+
+		return nil, fmt.Errorf("error reading imager output: %w", err)
+	}
+
+	var flat []string
+
+	for _, entry := range entries {
+		switch name := entry.Name(); Arch(name) {
+		case ArchAmd64, ArchArm64:
+			return checksums, nil
+		default:
+			if name != outputsManifestName {
+				flat = append(flat, name)
+			}
+		}
+	}
+
+	if len(flat) == 0 {
+		return checksums, nil
+	}
+
+	logger.Info("normalizing single-arch imager output", zap.String("arch", string(arch)))
+
+	archPath := filepath.Join(destination, string(arch))
+
+	archDestination, linked := archDestinations[arch]
+	if linked {
+		archPath = filepath.Join(archDestination, string(arch))
+	}
+
+	if err = os.MkdirAll(archPath, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating directory %q: %w", archPath, err)
+	}
+
+	for _, name := range flat {
+		if err = moveTree(filepath.Join(destination, name), filepath.Join(archPath, name)); err != nil {
+			return nil, fmt.Errorf("error normalizing imager output: %w", err)
+		}
+	}
+
+	if linked {
+		if err = os.Symlink(archPath, filepath.Join(destination, string(arch))); err != nil {
+			return nil, fmt.Errorf("error linking %s storage path: %w", arch, err)
+		}
+	}
+
+	normalized := make(map[string]string, len(checksums))
+
+	for relPath, checksum := range checksums {
+		if relPath != outputsManifestName {
+			relPath = string(arch) + "/" + relPath
+		}
+
+		normalized[relPath] = checksum
+	}
+
+	return normalized, nil
+}
+
+// singleArchOutput returns true if the imager image is built for the architecture, so the imager output
+// might not be nested under the architecture directory, and the path relative to usr/install/ is the artifact path.
+func singleArchOutput(imgArch, arch Arch, relPath string) bool {
+	return imgArch != "" && imgArch == arch && relPath != outputsManifestName
+}
+
+// moveTree renames the file or the directory tree, copying it if the destination is on another filesystem,
+// e.g. in the storage root of the architecture.
+func moveTree(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, relPath)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case d.Type()&fs.ModeSymlink != 0:
+			linkname, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(linkname, target)
+		default:
+			return copyFile(p, target)
+		}
+	}); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(src)
+}
+
+// copyFile copies the regular file keeping its permissions.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close() //nolint:errcheck
+
+	st, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, st.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close() //nolint:errcheck
+
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestSingleArchImagerOutput(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	pushSingleArch := func(t *testing.T, registryHost string) {
+		img, err := crane.Image(map[string][]byte{
+			"usr/install/vmlinuz":      []byte("kernel"),
+			"usr/install/initramfs.xz": []byte("initramfs"),
+		})
+		require.NoError(t, err)
+
+		configFile, err := img.ConfigFile()
+		require.NoError(t, err)
+
+		configFile.Architecture = string(artifacts.ArchArm64)
+		configFile.OS = "linux"
+
+		img, err = mutate.ConfigFile(img, configFile)
+		require.NoError(t, err)
+
+		ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+		require.NoError(t, err)
+
+		require.NoError(t, remote.Write(ref, img))
+	}
+
+	t.Run("get", func(t *testing.T) {
+		manager, registryHost := setupManager(t, artifacts.Options{})
+
+		pushSingleArch(t, registryHost)
+
+		path, err := manager.Get(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel)
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kernel", string(contents))
+
+		_, err = manager.DigestPath(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindInitramfs)
+		require.NoError(t, err)
+
+		_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+		require.Error(t, err)
+	})
+
+	t.Run("storage path per arch", func(t *testing.T) {
+		arm64Root := t.TempDir()
+
+		manager, registryHost := setupManager(t, artifacts.Options{
+			StoragePathPerArch: map[artifacts.Arch]string{artifacts.ArchArm64: arm64Root},
+		})
+
+		pushSingleArch(t, registryHost)
+
+		path, err := manager.Get(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel)
+		require.NoError(t, err)
+
+		resolved, err := filepath.EvalSymlinks(path)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resolved, arm64Root), resolved)
+
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kernel", string(contents))
+	})
+
+	t.Run("get files", func(t *testing.T) {
+		manager, registryHost := setupManager(t, artifacts.Options{})
+
+		pushSingleArch(t, registryHost)
+
+		files, err := manager.GetFiles(ctx, "1.7.0", artifacts.ArchArm64, []string{"vmlinuz"})
+		require.NoError(t, err)
+		require.Contains(t, files, "vmlinuz")
+
+		contents, err := os.ReadFile(files["vmlinuz"])
+		require.NoError(t, err)
+		assert.Equal(t, "kernel", string(contents))
+
+		_, err = manager.GetFiles(ctx, "1.7.0", artifacts.ArchAmd64, []string{"vmlinuz"})
+		assert.ErrorIs(t, err, artifacts.ErrNoMatch)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		storagePath := t.TempDir()
+
+		manager, registryHost := setupManagerWithDeps(t, artifacts.Options{StreamUncachedArtifacts: true}, artifacts.Dependencies{StoragePath: storagePath})
+
+		pushSingleArch(t, registryHost)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel, &buf))
+		assert.Equal(t, "kernel", buf.String())
+
+		// streamed without extracting the Talos version
+		assert.NoDirExists(t, filepath.Join(storagePath, "v1.7.0"))
+	})
+}
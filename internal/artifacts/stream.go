@@ -0,0 +1,187 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/siderolabs/gen/xerrors"
+	"go.uber.org/zap"
+)
+
+// StreamArtifact writes the artifact for the given version, arch and kind to w without storing it.
+//
+// If the Talos version is already extracted, the stored artifact is copied. Otherwise, the Talos version is extracted
+// as with Get, unless Options.StreamUncachedArtifacts is set: then the artifact is streamed from the imager image
+// as it is pulled, so it's meant for rarely requested artifacts which are not worth caching. The pull is bound
+// to the context, and it's not retried, as the artifact might be partially written to w. The artifacts which
+// are not found in the imager image are looked up in the extracted Talos version.
+// The artifact is compressed as configured with Options.KindCompression.
+func (m *Manager) StreamArtifact(ctx context.Context, versionString string, arch Arch, kind Kind, w io.Writer) error {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	if err = m.validateArtifact(arch, kind); err != nil {
+		return err
+	}
+
+	tag, err := m.parseTag(ctx, versionString)
+	if err != nil {
+		return err
+	}
+
+	if !m.needsCompression(kind) {
+		return m.streamArtifact(ctx, tag, arch, kind, w)
+	}
+
+	compressor, err := newCompressor(w, m.compression(kind))
+	if err != nil {
+		return err
+	}
+
+	if err = m.streamArtifact(ctx, tag, arch, kind, compressor); err != nil {
+		return err
+	}
+
+	return compressor.Close()
+}
+
+func (m *Manager) streamArtifact(ctx context.Context, tag string, arch Arch, kind Kind, w io.Writer) error {
+	f, err := m.openExtracted(tag, arch, kind)
+	if err == nil {
+		defer f.Close() //nolint:errcheck
+
+		m.events.publish(EventCacheHit, tag, nil)
+
+		_, err = io.Copy(w, f)
+
+		return err
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if m.options.StreamUncachedArtifacts && m.options.PostExtractHook == nil && m.options.UpstreamCache == nil {
+		err = m.streamFromImager(ctx, tag, arch, kind, w)
+
+		// nothing is written if the artifact is not found, so it's looked up in the extracted Talos version
+		if !xerrors.TagIs[ErrNotFoundTag](err) {
+			return err
+		}
+	}
+
+	_, releaseReader, err := m.acquireImager(ctx, tag)
+	if err != nil {
+		return err
+	}
+
+	defer releaseReader()
+
+	if f, err = m.openExtracted(tag, arch, kind); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return xerrors.NewTaggedf[ErrNotFoundTag]("artifact %q is not found for %s", m.kindPath(kind), arch)
+		}
+
+		return err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+// openExtracted opens the uncompressed extracted artifact.
+func (m *Manager) openExtracted(tag string, arch Arch, kind Kind) (*os.File, error) {
+	return os.Open(filepath.Join(m.storagePath, tag, string(arch), filepath.FromSlash(m.kindPath(kind))))
+}
+
+// streamFromImager streams the artifact from the imager image, without extracting the Talos version.
+func (m *Manager) streamFromImager(ctx context.Context, tag string, arch Arch, kind Kind, w io.Writer) error {
+	digestRef, err := m.resolveTag(ctx, ArchArm64, m.repository(ImagerImage).Tag(tag))
+	if err != nil {
+		return err
+	}
+
+	logger := m.logger.With(zap.Stringer("image", digestRef))
+
+	if err = m.verifySignature(ctx, logger, digestRef); err != nil {
+		return err
+	}
+
+	return m.pullImage(ctx, logger, digestRef, ArchArm64, func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		imgArch, err := imageArch(img)
+		if err != nil {
+			return err
+		}
+
+		names := []string{"usr/install/" + string(arch) + "/" + m.kindPath(kind)}
+
+		// the output of a single-arch imager might not be nested under the architecture directory
+		if singleArchOutput(imgArch, arch, m.kindPath(kind)) {
+			names = append(names, "usr/install/"+m.kindPath(kind))
+		}
+
+		return imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
+			return streamTarEntry(logger, r, names, m.options.MaxArtifactSize, w)
+		})(ctx, logger, img)
+	})
+}
+
+// streamTarEntry copies the first regular file with any of the names from the tarball to w.
+//
+// The rest of the tarball is consumed, so that the image export is not blocked.
+func streamTarEntry(logger *zap.Logger, r io.Reader, names []string, maxSize int64, w io.Writer) error {
+	tr := tar.NewReader(r)
+
+	found := false
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return fmt.Errorf("error reading tar header: %w", err)
+		}
+
+		if found || hdr.Typeflag != tar.TypeReg || !slices.Contains(names, hdr.Name) {
+			continue
+		}
+
+		if maxSize > 0 && hdr.Size > maxSize {
+			return fmt.Errorf("%w: %q is %d bytes", ErrArtifactTooLarge, hdr.Name, hdr.Size)
+		}
+
+		if _, err = io.Copy(w, tr); err != nil {
+			return fmt.Errorf("error streaming %q: %w", hdr.Name, err)
+		}
+
+		logger.Info("streamed the artifact", zap.String("artifact", hdr.Name), zap.Int64("size", hdr.Size))
+
+		found = true
+	}
+
+	if !found {
+		return xerrors.NewTaggedf[ErrNotFoundTag]("artifact %q is not found in the imager image", names[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestStreamArtifact(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{StreamUncachedArtifacts: true}, artifacts.Dependencies{StoragePath: storagePath})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz":      []byte("amd64 kernel"),
+		"usr/install/amd64/initramfs.xz": []byte("amd64 initramfs"),
+	})
+
+	var buf bytes.Buffer
+
+	require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, &buf))
+	assert.Equal(t, "amd64 kernel", buf.String())
+
+	// nothing is stored
+	assert.NoDirExists(t, filepath.Join(storagePath, "v1.7.0"))
+
+	err := manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel, io.Discard)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+
+	// extracted artifacts are served from the storage
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs)
+	require.NoError(t, err)
+
+	buf.Reset()
+
+	require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindInitramfs, &buf))
+	assert.Equal(t, "amd64 initramfs", buf.String())
+}
+
+func TestStreamArtifactDisabled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	storagePath := t.TempDir()
+
+	manager, registryHost := setupManagerWithDeps(t, artifacts.Options{}, artifacts.Dependencies{StoragePath: storagePath})
+
+	pushImage(t, registryHost, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("amd64 kernel"),
+	})
+
+	var buf bytes.Buffer
+
+	require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, &buf))
+	assert.Equal(t, "amd64 kernel", buf.String())
+
+	// the Talos version is extracted
+	assert.FileExists(t, filepath.Join(storagePath, "v1.7.0", "amd64", "vmlinuz"))
+
+	err := manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel, io.Discard)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
+
+func TestStreamArtifactPipeline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	t.Run("post extract hook", func(t *testing.T) {
+		manager, registryHost := setupManager(t, artifacts.Options{
+			StreamUncachedArtifacts: true,
+			PostExtractHook: func(_ context.Context, _ string, _ artifacts.Arch, _ artifacts.Kind, path string) error {
+				contents, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+
+				return os.WriteFile(path, append(contents, []byte(" signed")...), 0o644)
+			},
+		})
+
+		pushImager(t, registryHost, "v1.7.0")
+
+		// the artifact is not streamed, as it would skip the hook
+		var buf bytes.Buffer
+
+		require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, &buf))
+		assert.Equal(t, "kernel signed", buf.String())
+	})
+
+	t.Run("single-arch imager", func(t *testing.T) {
+		manager, registryHost := setupManager(t, artifacts.Options{StreamUncachedArtifacts: true})
+
+		img, err := crane.Image(map[string][]byte{
+			"usr/install/vmlinuz": []byte("kernel"),
+		})
+		require.NoError(t, err)
+
+		configFile, err := img.ConfigFile()
+		require.NoError(t, err)
+
+		configFile.Architecture = string(artifacts.ArchArm64)
+		configFile.OS = "linux"
+
+		img, err = mutate.ConfigFile(img, configFile)
+		require.NoError(t, err)
+
+		ref, err := name.NewTag(registryHost+"/"+artifacts.ImagerImage+":v1.7.0", name.Insecure)
+		require.NoError(t, err)
+
+		require.NoError(t, remote.Write(ref, img))
+
+		// the artifact is found once the imager output is normalized
+		var buf bytes.Buffer
+
+		require.NoError(t, manager.StreamArtifact(ctx, "1.7.0", artifacts.ArchArm64, artifacts.KindKernel, &buf))
+		assert.Equal(t, "kernel", buf.String())
+	})
+}
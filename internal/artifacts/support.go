@@ -0,0 +1,40 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"github.com/blang/semver/v4"
+)
+
+// SupportStatus is the support status of the Talos version.
+type SupportStatus string
+
+// Support statuses.
+const (
+	SupportStatusUnknown   SupportStatus = "unknown"
+	SupportStatusSupported SupportStatus = "supported"
+	// SupportStatusDeprecated is set for versions which are still supported, but are approaching the end of life.
+	SupportStatusDeprecated SupportStatus = "deprecated"
+	SupportStatusEOL        SupportStatus = "eol"
+)
+
+// SupportPolicy maps the Talos version to its support status.
+type SupportPolicy func(version semver.Version) SupportStatus
+
+// VersionSupportStatus returns the support status of the Talos version according to Options.SupportPolicy.
+//
+// See parseTag for the accepted version formats. If no policy is configured, SupportStatusUnknown is returned.
+func (m *Manager) VersionSupportStatus(versionString string) (SupportStatus, error) {
+	tag, err := m.normalizeVersion(versionString)
+	if err != nil {
+		return SupportStatusUnknown, err
+	}
+
+	if m.options.SupportPolicy == nil {
+		return SupportStatusUnknown, nil
+	}
+
+	return m.options.SupportPolicy(semver.MustParse(tag[1:])), nil
+}
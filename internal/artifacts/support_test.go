@@ -0,0 +1,47 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestVersionSupportStatus(t *testing.T) {
+	manager, _ := setupManager(t, artifacts.Options{})
+
+	status, err := manager.VersionSupportStatus("1.7.0")
+	require.NoError(t, err)
+	assert.Equal(t, artifacts.SupportStatusUnknown, status)
+
+	manager, _ = setupManager(t, artifacts.Options{
+		MinVersion: semver.MustParse("1.2.0"),
+		SupportPolicy: func(version semver.Version) artifacts.SupportStatus {
+			if version.LT(semver.MustParse("1.6.0")) {
+				return artifacts.SupportStatusEOL
+			}
+
+			return artifacts.SupportStatusSupported
+		},
+	})
+
+	for versionString, expected := range map[string]artifacts.SupportStatus{
+		"1.5.5":  artifacts.SupportStatusEOL,
+		"v1.7.0": artifacts.SupportStatusSupported,
+	} {
+		status, err = manager.VersionSupportStatus(versionString)
+		require.NoError(t, err)
+		assert.Equal(t, expected, status, versionString)
+	}
+
+	_, err = manager.VersionSupportStatus("1.1.0")
+	assert.True(t, xerrors.TagIs[artifacts.InvalidVersionErrorTag](err))
+}
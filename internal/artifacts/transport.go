@@ -0,0 +1,122 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Supported dial networks for the registry transport.
+const (
+	DialNetworkAny  = "tcp"
+	DialNetworkIPv4 = "tcp4"
+	DialNetworkIPv6 = "tcp6"
+)
+
+// Default connection pool settings of the registry transport.
+const (
+	defaultTransportMaxIdleConns        = 100
+	defaultTransportMaxIdleConnsPerHost = 50
+	defaultTransportIdleConnTimeout     = 90 * time.Second
+)
+
+// newTransport builds the HTTP transport used by the registry pullers.
+//
+// The transport is based on the go-containerregistry default one, but the dialer
+// is forced to use the specified network, and the connection pool is configured from the options.
+func newTransport(options Options) (*http.Transport, error) {
+	network := options.DialNetwork
+
+	switch network {
+	case "":
+		network = DialNetworkAny
+	case DialNetworkAny, DialNetworkIPv4, DialNetworkIPv6:
+	default:
+		return nil, fmt.Errorf("unsupported dial network %q", network)
+	}
+
+	defaultTransport, ok := remote.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default transport type %T", remote.DefaultTransport)
+	}
+
+	transport := defaultTransport.Clone()
+
+	transport.MaxIdleConns = valueOrDefault(options.TransportMaxIdleConns, defaultTransportMaxIdleConns)
+	transport.MaxIdleConnsPerHost = valueOrDefault(options.TransportMaxIdleConnsPerHost, defaultTransportMaxIdleConnsPerHost)
+	transport.MaxConnsPerHost = options.TransportMaxConnsPerHost
+	transport.IdleConnTimeout = valueOrDefault(options.TransportIdleConnTimeout, defaultTransportIdleConnTimeout)
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return transport, nil
+}
+
+// withRedirectTransport wraps the registry transport to send the requests redirected to another host
+// with the redirect proxy and CAs, if configured.
+func withRedirectTransport(options Options, transport *http.Transport) (http.RoundTripper, error) {
+	if options.RedirectProxyURL == "" && options.RedirectRootCAs == nil {
+		return transport, nil
+	}
+
+	redirect := transport.Clone()
+
+	if options.RedirectProxyURL != "" {
+		proxyURL, err := url.Parse(options.RedirectProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect proxy URL: %w", err)
+		}
+
+		redirect.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if options.RedirectRootCAs != nil {
+		if redirect.TLSClientConfig == nil {
+			redirect.TLSClientConfig = &tls.Config{} //nolint:gosec
+		}
+
+		redirect.TLSClientConfig.RootCAs = options.RedirectRootCAs
+	}
+
+	return &redirectTransport{registry: transport, redirect: redirect}, nil
+}
+
+// redirectTransport sends the requests redirected to another host with a separate transport.
+type redirectTransport struct {
+	registry, redirect http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the response is only set for the requests created by the HTTP client following a redirect
+	if req.Response != nil && req.Response.Request != nil && req.Response.Request.URL.Host != req.URL.Host {
+		return t.redirect.RoundTrip(req)
+	}
+
+	return t.registry.RoundTrip(req)
+}
+
+func valueOrDefault[T int | time.Duration](value, defaultValue T) T {
+	if value == 0 {
+		return defaultValue
+	}
+
+	return value
+}
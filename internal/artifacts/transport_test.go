@@ -0,0 +1,142 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestRedirectRootCAs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	reg := registry.New()
+
+	var redirected atomic.Int64
+
+	blobSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected.Add(1)
+
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(blobSrv.Close)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			http.Redirect(w, r, blobSrv.URL+r.URL.Path, http.StatusTemporaryRedirect)
+
+			return
+		}
+
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	pushImage(t, u.Host, artifacts.ImagerImage, "v1.7.0", map[string][]byte{
+		"usr/install/amd64/vmlinuz": []byte("kernel"),
+	})
+
+	blobTransport, ok := blobSrv.Client().Transport.(*http.Transport)
+	require.True(t, ok)
+
+	for _, test := range []struct {
+		name      string
+		rootCAs   *x509.CertPool
+		expectErr bool
+	}{
+		{name: "system CAs", expectErr: true},
+		{name: "redirect CAs", rootCAs: blobTransport.TLSClientConfig.RootCAs},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+				ImageRegistry:         u.Host,
+				InsecureImageRegistry: true,
+				RedirectRootCAs:       test.rootCAs,
+			})
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, manager.Close())
+			})
+
+			redirected.Store(0)
+
+			path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+			if test.expectErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Positive(t, redirected.Load())
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "kernel", string(contents))
+		})
+	}
+}
+
+func BenchmarkTransportConnectionReuse(b *testing.B) {
+	var newConns atomic.Int64
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("blob")) //nolint:errcheck
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	srv.StartTLS()
+	b.Cleanup(srv.Close)
+
+	transport, err := artifacts.NewTransport(artifacts.Options{})
+	require.NoError(b, err)
+
+	transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig //nolint:forcetypeassert
+
+	client := &http.Client{Transport: transport}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				b.Error(err)
+
+				return
+			}
+
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck
+			resp.Body.Close()              //nolint:errcheck
+		}
+	})
+
+	b.ReportMetric(float64(newConns.Load()), "conns")
+}
@@ -0,0 +1,327 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"go.uber.org/zap"
+)
+
+// UpstreamCache is a shared cache tier consulted before the registry, e.g. another image-factory or an object store.
+//
+// The contents are opaque tar streams, keyed by the image digest.
+type UpstreamCache interface {
+	// Fetch returns the contents for the key, false if the key is not cached.
+	Fetch(ctx context.Context, key string) (io.ReadCloser, bool, error)
+	// Store populates the cache with the contents for the key.
+	//
+	// The contents are streamed while the image is extracted, the cache should not be populated if reading r fails.
+	Store(ctx context.Context, key string, r io.Reader) error
+}
+
+// upstreamImagerKey is the upstream cache key of the imager image, the contents are the exported image filesystem.
+func upstreamImagerKey(digestRef name.Digest) string {
+	return "imager/" + digestRef.DigestStr()
+}
+
+// upstreamExtensionKey is the upstream cache key of the extension image, the contents are the image OCI layout.
+func upstreamExtensionKey(arch Arch, digest string) string {
+	return "extension/" + string(arch) + "-" + digest
+}
+
+// fetchUpstream returns the contents for the key from Options.UpstreamCache.
+//
+// Upstream cache errors are handled as a miss, so that the image is pulled from the registry instead.
+func (m *Manager) fetchUpstream(ctx context.Context, logger *zap.Logger, key string) (io.ReadCloser, bool) {
+	rc, ok, err := m.options.UpstreamCache.Fetch(ctx, key)
+	if err != nil {
+		logger.Warn("failed to fetch from upstream cache", zap.String("key", key), zap.Error(err))
+
+		return nil, false
+	}
+
+	if !ok {
+		logger.Debug("upstream cache miss", zap.String("key", key))
+
+		return nil, false
+	}
+
+	logger.Info("fetching from upstream cache", zap.String("key", key))
+
+	return rc, true
+}
+
+// storeUpstream populates Options.UpstreamCache with the contents for the key, failures are only logged.
+func (m *Manager) storeUpstream(ctx context.Context, logger *zap.Logger, key string, r io.Reader) {
+	if err := m.options.UpstreamCache.Store(ctx, key, r); err != nil {
+		logger.Warn("failed to populate upstream cache", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// upstreamExportHandler is imageExportHandler consulting Options.UpstreamCache before the image layers.
+//
+// On hit, the exported filesystem from the upstream cache is passed to the export handler. On miss, the image export
+// is streamed to the upstream cache while handled, and the stream fails if the export handler fails.
+func (m *Manager) upstreamExportHandler(key string, exportHandler func(logger *zap.Logger, r io.Reader) error) imageHandler {
+	if m.options.UpstreamCache == nil {
+		return imageExportHandler(exportHandler)
+	}
+
+	return func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		if rc, ok := m.fetchUpstream(ctx, logger, key); ok {
+			defer rc.Close() //nolint:errcheck
+
+			if err := exportHandler(logger, rc); err != nil {
+				return fmt.Errorf("error extracting from upstream cache: %w", err)
+			}
+
+			return nil
+		}
+
+		r, w := io.Pipe()
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			m.storeUpstream(ctx, logger, key, r)
+
+			r.Close() //nolint:errcheck // stops the stream if the contents weren't read fully
+		}()
+
+		err := imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
+			tee := io.TeeReader(r, &bestEffortWriter{w: w})
+
+			if err := exportHandler(logger, tee); err != nil {
+				return err
+			}
+
+			// the export handler might stop at the end of the tar archive, pass the rest of the export as well
+			_, err := io.Copy(io.Discard, tee)
+
+			return err
+		})(ctx, logger, img)
+
+		w.CloseWithError(err) //nolint:errcheck
+		<-done
+
+		return err
+	}
+}
+
+// bestEffortWriter stops writing to the underlying writer after the first failure, without failing the writes.
+//
+// The upstream cache failures shouldn't fail the extraction.
+type bestEffortWriter struct {
+	w      io.Writer
+	failed bool
+}
+
+// Write implements io.Writer.
+func (bw *bestEffortWriter) Write(p []byte) (int, error) {
+	if !bw.failed {
+		if _, err := bw.w.Write(p); err != nil {
+			bw.failed = true
+		}
+	}
+
+	return len(p), nil
+}
+
+// upstreamLayoutHandler consults Options.UpstreamCache for the OCI layout at the path before the OCI handler.
+//
+// On hit, the layout is extracted from the upstream cache, and verified against the image digest; the image is pulled
+// with the OCI handler if the layout doesn't match. On miss, the layout written by the OCI handler is stored
+// to the upstream cache.
+func (m *Manager) upstreamLayoutHandler(key, path string, ociHandler imageHandler) imageHandler {
+	if m.options.UpstreamCache == nil {
+		return ociHandler
+	}
+
+	return func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		if rc, ok := m.fetchUpstream(ctx, logger, key); ok {
+			err := upstreamLayout(rc, path, img)
+
+			rc.Close() //nolint:errcheck
+
+			if err == nil {
+				return nil
+			}
+
+			logger.Warn("invalid layout in upstream cache, pulling the image", zap.String("key", key), zap.Error(err))
+		}
+
+		if err := ociHandler(ctx, logger, img); err != nil {
+			return err
+		}
+
+		r, w := io.Pipe()
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			w.CloseWithError(tarDirectory(w, path)) //nolint:errcheck
+		}()
+
+		m.storeUpstream(ctx, logger, key, r)
+
+		r.Close() //nolint:errcheck // stops the writer if the contents weren't read fully
+		<-done
+
+		return nil
+	}
+}
+
+// upstreamLayout extracts the OCI layout from the upstream cache to the path, and verifies it matches the image.
+func upstreamLayout(r io.Reader, path string, img v1.Image) error {
+	if err := untarDirectory(r, path); err != nil {
+		return fmt.Errorf("error extracting from upstream cache: %w", err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+
+	layoutDigest, err := verifyLayout(path)
+	if err != nil {
+		return err
+	}
+
+	if layoutDigest != digest {
+		return fmt.Errorf("%w: layout has image %s, expected %s", ErrExtensionCorrupt, layoutDigest, digest)
+	}
+
+	return nil
+}
+
+// tarDirectory writes the regular files and directories under the path as tar.
+func tarDirectory(w io.Writer, path string) error {
+	tw := tar.NewWriter(w)
+
+	if err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == path || !(d.IsDir() || d.Type().IsRegular()) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = filepath.ToSlash(relPath)
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close() //nolint:errcheck
+
+		_, err = io.Copy(tw, f)
+
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// untarDirectory extracts the regular files and directories from the tar to the path, replacing its contents.
+func untarDirectory(r io.Reader, path string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("error removing the directory %q: %w", path, err)
+	}
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("error reading tar header: %w", err)
+		}
+
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("invalid path %q", hdr.Name)
+		}
+
+		destPath := filepath.Join(path, filepath.FromSlash(hdr.Name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(destPath, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+				return err
+			}
+
+			if err = writeFile(destPath, tr); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported tar entry %q", hdr.Name)
+		}
+	}
+}
+
+// writeFile writes the contents to a new file at the path.
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close() //nolint:errcheck
+
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,194 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+type mapUpstreamCache struct {
+	contents map[string][]byte
+	hits     int
+}
+
+func (c *mapUpstreamCache) Fetch(_ context.Context, key string) (io.ReadCloser, bool, error) {
+	contents, ok := c.contents[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	c.hits++
+
+	return io.NopCloser(bytes.NewReader(contents)), true, nil
+}
+
+func (c *mapUpstreamCache) Store(_ context.Context, key string, r io.Reader) error {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	c.contents[key] = contents
+
+	return nil
+}
+
+func TestUpstreamCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	upstream := &mapUpstreamCache{contents: map[string][]byte{}}
+
+	manager, registryHost := setupManager(t, artifacts.Options{UpstreamCache: upstream})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/upstream")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	// the first node populates the upstream cache
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	_, err = manager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+	require.NoError(t, err)
+
+	assert.Zero(t, upstream.hits)
+	require.Len(t, upstream.contents, 2)
+
+	// replace the imager contents to make sure they are served from the upstream cache
+	for key := range upstream.contents {
+		if !strings.HasPrefix(key, "imager/") {
+			continue
+		}
+
+		var buf bytes.Buffer
+
+		tw := tar.NewWriter(&buf)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "usr/install/amd64/vmlinuz", Mode: 0o644, Size: int64(len("upstream kernel"))}))
+		_, err = tw.Write([]byte("upstream kernel"))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+
+		upstream.contents[key] = buf.Bytes()
+	}
+
+	// the other node is served from the upstream cache
+	otherManager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         registryHost,
+		InsecureImageRegistry: true,
+		UpstreamCache:         upstream,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, otherManager.Close())
+	})
+
+	path, err := otherManager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "upstream kernel", string(contents))
+
+	_, err = otherManager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+	require.NoError(t, err)
+
+	require.NoError(t, otherManager.VerifyExtensionTarball(ctx, artifacts.ArchAmd64, ref))
+
+	assert.Equal(t, 2, upstream.hits)
+}
+
+func TestUpstreamCacheCorruptLayout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	_, registryHost := setupManager(t, artifacts.Options{})
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	tag := extensionTag(t, registryHost, "siderolabs/upstream")
+	require.NoError(t, remote.Write(tag, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref := artifacts.ExtensionRef{TaggedReference: tag, Digest: digest.String()}
+
+	// the upstream cache has the layout of another image for the extension digest
+	otherImg, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	otherTag := extensionTag(t, registryHost, "siderolabs/other")
+	require.NoError(t, remote.Write(otherTag, otherImg))
+
+	otherDigest, err := otherImg.Digest()
+	require.NoError(t, err)
+
+	upstream := &mapUpstreamCache{contents: map[string][]byte{}}
+
+	otherManager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         registryHost,
+		InsecureImageRegistry: true,
+		UpstreamCache:         upstream,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, otherManager.Close())
+	})
+
+	_, err = otherManager.GetExtensionImage(ctx, artifacts.ArchAmd64, artifacts.ExtensionRef{TaggedReference: otherTag, Digest: otherDigest.String()})
+	require.NoError(t, err)
+	require.Len(t, upstream.contents, 1)
+
+	for key, contents := range upstream.contents {
+		upstream.contents[strings.Replace(key, otherDigest.String(), digest.String(), 1)] = contents
+	}
+
+	upstreamManager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:         registryHost,
+		InsecureImageRegistry: true,
+		UpstreamCache:         upstream,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, upstreamManager.Close())
+	})
+
+	// the layout doesn't match the image digest, so the image is pulled from the registry
+	_, err = upstreamManager.GetExtensionImage(ctx, artifacts.ArchAmd64, ref)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, upstream.hits)
+
+	require.NoError(t, upstreamManager.VerifyExtensionTarball(ctx, artifacts.ArchAmd64, ref))
+}
@@ -0,0 +1,100 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/siderolabs/gen/xerrors"
+	"go.uber.org/zap"
+)
+
+// kinds is the list of all artifact kinds.
+var kinds = []Kind{KindKernel, KindInitramfs, KindSystemdBoot, KindSystemdStub, KindDTB, KindUBoot, KindRPiFirmware}
+
+// validateArch checks the architecture before it is used as a path segment.
+func validateArch(arch Arch) error {
+	if arch != ArchAmd64 && arch != ArchArm64 {
+		return fmt.Errorf("%w: %q", ErrInvalidArch, arch)
+	}
+
+	return nil
+}
+
+// validateArtifact checks the architecture and the artifact kind before they are used as path segments.
+func (m *Manager) validateArtifact(arch Arch, kind Kind) error {
+	if err := validateArch(arch); err != nil {
+		return err
+	}
+
+	if !m.kindSupported(kind) {
+		return fmt.Errorf("%w: %q", ErrInvalidKind, kind)
+	}
+
+	return nil
+}
+
+// ValidateSchematic checks up front that the build inputs are valid, without fetching the artifacts.
+//
+// The Talos version must be available, the architecture and the artifact kind supported, and each extension image
+// must exist for the architecture and pass the signature policy. The extensions must include Options.RequiredExtensions.
+// All validation errors are returned joined.
+func (m *Manager) ValidateSchematic(ctx context.Context, versionString string, arch Arch, kind Kind, refs []ExtensionRef) error {
+	m, release, err := m.managerFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	var errs []error
+
+	if _, err = m.parseTag(ctx, versionString); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, ok := m.pullers[arch]; !ok {
+		// the architecture is required to check the extensions
+		return errors.Join(append(errs, xerrors.NewTaggedf[ErrNotFoundTag]("architecture %q is not supported", arch))...)
+	}
+
+	if !m.kindSupported(kind) {
+		errs = append(errs, fmt.Errorf("artifact kind %q is not supported", kind))
+	}
+
+	if err = m.CheckRequiredExtensions(ctx, versionString, extensionNames(refs)); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, ref := range refs {
+		if err = m.validateExtension(ctx, arch, ref); err != nil {
+			errs = append(errs, fmt.Errorf("extension %s: %w", ref.TaggedReference, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateExtension checks that the extension image exists for the architecture, and passes the signature policy.
+func (m *Manager) validateExtension(ctx context.Context, arch Arch, ref ExtensionRef) error {
+	if ref.Digest == "" {
+		digestRef, err := m.resolveTag(ctx, arch, m.extensionRepository(ref).Tag(ref.TaggedReference.TagStr()))
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", ref.TaggedReference, err)
+		}
+
+		ref.Digest = digestRef.DigestStr()
+	}
+
+	if err := m.checkExtensionArch(ctx, ref, arch); err != nil {
+		return err
+	}
+
+	imageRef := m.extensionRepository(ref).Digest(ref.Digest)
+
+	return m.verifySignature(ctx, m.logger.With(zap.Stringer("image", imageRef)), imageRef)
+}
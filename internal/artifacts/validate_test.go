@@ -0,0 +1,192 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestValidateSchematic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	index := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add:        img,
+		Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: string(artifacts.ArchAmd64)}},
+	})
+
+	tag := extensionTag(t, registryHost, "siderolabs/amd64-only")
+	require.NoError(t, remote.WriteIndex(tag, index))
+
+	// extension referenced by tag
+	validRef := artifacts.ExtensionRef{TaggedReference: tag}
+	missingRef := artifacts.ExtensionRef{TaggedReference: extensionTag(t, registryHost, "siderolabs/missing")}
+
+	require.NoError(t, manager.ValidateSchematic(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel, []artifacts.ExtensionRef{validRef}))
+
+	for _, test := range []struct {
+		name    string
+		version string
+		arch    artifacts.Arch
+		kind    artifacts.Kind
+		refs    []artifacts.ExtensionRef
+		errors  []string
+	}{
+		{
+			name:    "unsupported version",
+			version: "1.8.0",
+			arch:    artifacts.ArchAmd64,
+			kind:    artifacts.KindKernel,
+			errors:  []string{"version 1.8.0 is not available"},
+		},
+		{
+			name:    "unsupported arch",
+			version: "1.7.0",
+			arch:    "riscv64",
+			kind:    artifacts.KindKernel,
+			errors:  []string{`architecture "riscv64" is not supported`},
+		},
+		{
+			name:    "unsupported kind",
+			version: "1.7.0",
+			arch:    artifacts.ArchAmd64,
+			kind:    "bzImage",
+			errors:  []string{`artifact kind "bzImage" is not supported`},
+		},
+		{
+			name:    "extensions",
+			version: "1.7.0",
+			arch:    artifacts.ArchArm64,
+			kind:    artifacts.KindKernel,
+			refs:    []artifacts.ExtensionRef{validRef, missingRef},
+			errors:  []string{"siderolabs/amd64-only", "is not available for platform linux/arm64", "siderolabs/missing"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := manager.ValidateSchematic(ctx, test.version, test.arch, test.kind, test.refs)
+			require.Error(t, err)
+
+			for _, expected := range test.errors {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestInvalidArtifactParameters(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	_, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, "../../etc")
+	require.ErrorIs(t, err, artifacts.ErrInvalidKind)
+
+	_, err = manager.Open(ctx, "1.7.0", artifacts.ArchAmd64, "../../etc")
+	require.ErrorIs(t, err, artifacts.ErrInvalidKind)
+
+	_, err = manager.Get(ctx, "1.7.0", "../../etc", artifacts.KindKernel)
+	require.ErrorIs(t, err, artifacts.ErrInvalidArch)
+
+	_, err = manager.GetFiles(ctx, "1.7.0", "..", []string{"*"})
+	require.ErrorIs(t, err, artifacts.ErrInvalidArch)
+
+	_, err = manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+}
+
+func TestInvalidArch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{})
+
+	pushImager(t, registryHost, "v1.7.0")
+
+	const arch artifacts.Arch = "../riscv64"
+
+	ref := artifacts.ExtensionRef{
+		TaggedReference: extensionTag(t, registryHost, "siderolabs/gvisor"),
+		Digest:          "sha256:548b2b121611424f6b1b6cfb72a1669421ffaf2f1560911c324a546c7cee655e",
+	}
+
+	for _, test := range []struct {
+		name string
+		call func() error
+	}{
+		{"Get", func() error { _, err := manager.Get(ctx, "1.7.0", arch, artifacts.KindKernel); return err }},
+		{"Open", func() error { _, err := manager.Open(ctx, "1.7.0", arch, artifacts.KindKernel); return err }},
+		{"Acquire", func() error { _, _, err := manager.Acquire(ctx, "1.7.0", arch, artifacts.KindKernel); return err }},
+		{"Stat", func() error { _, err := manager.Stat(ctx, "1.7.0", arch, artifacts.KindKernel); return err }},
+		{"GetChunks", func() error { _, err := manager.GetChunks(ctx, "1.7.0", arch, artifacts.KindKernel, 1024); return err }},
+		{"DigestPath", func() error { _, err := manager.DigestPath(ctx, "1.7.0", arch, artifacts.KindKernel); return err }},
+		{"BootMetadata", func() error { _, err := manager.BootMetadata(ctx, "1.7.0", arch); return err }},
+		{"GetMultiArch", func() error {
+			_, err := manager.GetMultiArch(ctx, "1.7.0", artifacts.KindKernel, []artifacts.Arch{artifacts.ArchAmd64, arch})
+
+			return err
+		}},
+		{"GetAllKinds", func() error { _, err := manager.GetAllKinds(ctx, "1.7.0", arch); return err }},
+		{"GetFiles", func() error { _, err := manager.GetFiles(ctx, "1.7.0", arch, []string{"*"}); return err }},
+		{"StreamArtifact", func() error { return manager.StreamArtifact(ctx, "1.7.0", arch, artifacts.KindKernel, io.Discard) }},
+		{"GetImageMetadata", func() error { _, err := manager.GetImageMetadata(ctx, "1.7.0", arch); return err }},
+		{"GetSBOM", func() error { _, err := manager.GetSBOM(ctx, "1.7.0", arch); return err }},
+		{"ImagerImageRef", func() error { _, err := manager.ImagerImageRef(ctx, "1.7.0", arch); return err }},
+		{"ImagerBuildInfo", func() error { _, err := manager.ImagerBuildInfo(ctx, "1.7.0", arch); return err }},
+		{"EstimateFootprint", func() error {
+			_, err := manager.EstimateFootprint(ctx, "1.7.0", arch, artifacts.KindKernel, nil)
+
+			return err
+		}},
+		{"GetInstallerImage", func() error { _, err := manager.GetInstallerImage(ctx, arch, "1.7.0"); return err }},
+		{"GetExtensionImage", func() error { _, err := manager.GetExtensionImage(ctx, arch, ref); return err }},
+		{"GetExtensionSBOM", func() error { _, err := manager.GetExtensionSBOM(ctx, arch, ref); return err }},
+		{"GetOverlayImage", func() error {
+			_, err := manager.GetOverlayImage(ctx, arch, artifacts.OverlayRef{Digest: ref.Digest})
+
+			return err
+		}},
+		{"PreloadExtensions", func() error { return manager.PreloadExtensions(ctx, arch, []artifacts.ExtensionRef{ref}) }},
+		{"VerifyExtensionTarball", func() error { return manager.VerifyExtensionTarball(ctx, arch, ref) }},
+		{"ResolveExtensionForArch", func() error {
+			_, err := manager.ResolveExtensionForArch(ctx, "1.7.0", "siderolabs/gvisor", arch)
+
+			return err
+		}},
+		{"GetSchematicArtifacts", func() error {
+			_, err := manager.GetSchematicArtifacts(ctx, "1.7.0", arch, artifacts.KindKernel, []artifacts.ExtensionRef{ref})
+
+			return err
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.ErrorIs(t, test.call(), artifacts.ErrInvalidArch)
+		})
+	}
+
+	assert.False(t, manager.ExtensionCached(arch, ref))
+}
@@ -13,7 +13,6 @@ import (
 	"io"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -22,28 +21,112 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func (m *Manager) fetchTalosVersions() (any, error) {
+// parseTagTolerant is the default tag parser, which accepts tags like "v1.7.0" or "1.7".
+func parseTagTolerant(tag string) (semver.Version, bool) {
+	version, err := semver.ParseTolerant(tag)
+
+	return version, err == nil
+}
+
+// ResolveLatestPatch is the VersionResolver which expands partial versions like "1.8", "v1.8" or "1.8.x"
+// to the latest available patch release of the minor line.
+//
+// Fully specified versions are not resolved.
+func ResolveLatestPatch(versionString string, available []semver.Version) (semver.Version, bool) {
+	parts := strings.Split(strings.TrimPrefix(versionString, "v"), ".")
+
+	switch {
+	case len(parts) == 2:
+	case len(parts) == 3 && (parts[2] == "x" || parts[2] == "X" || parts[2] == "*"):
+	default:
+		return semver.Version{}, false
+	}
+
+	minor, err := semver.ParseTolerant(parts[0] + "." + parts[1])
+	if err != nil {
+		return semver.Version{}, false
+	}
+
+	var (
+		latest semver.Version
+		found  bool
+	)
+
+	for _, version := range available {
+		if version.Major != minor.Major || version.Minor != minor.Minor || len(version.Pre) > 0 {
+			continue
+		}
+
+		if !found || version.GT(latest) {
+			latest, found = version, true
+		}
+	}
+
+	return latest, found
+}
+
+// compareVersionsDescending orders the versions newest first.
+func compareVersionsDescending(a, b semver.Version) int {
+	return b.Compare(a)
+}
+
+// sortVersions sorts the versions newest first, or oldest first with Options.VersionSortAscending.
+func (m *Manager) sortVersions(versions []semver.Version) {
+	if m.options.VersionSortAscending {
+		slices.SortStableFunc(versions, semver.Version.Compare)
+	} else {
+		slices.SortStableFunc(versions, compareVersionsDescending)
+	}
+}
+
+func (m *Manager) fetchTalosVersions(ctx context.Context) (any, error) {
 	m.logger.Info("fetching available Talos versions")
 
-	ctx, cancel := context.WithTimeout(context.Background(), FetchTimeout)
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
 	defer cancel()
 
-	repository := m.imageRegistry.Repo(ImagerImage)
+	repository := m.repository(ImagerImage)
 
-	candidates, err := m.pullers[ArchArm64].List(ctx, repository)
-	if err != nil {
+	var candidates []string
+
+	if err := m.guardRegistry(RegistryOpList, repository.RegistryStr(), func() error {
+		var err error
+
+		candidates, err = m.pullers[ArchArm64].List(ctx, repository)
+
+		return err
+	}); err != nil {
 		return nil, fmt.Errorf("failed to list Talos versions: %w", err)
 	}
 
+	tagParser := m.options.TagParser
+	if tagParser == nil {
+		tagParser = parseTagTolerant
+	}
+
 	var versions []semver.Version //nolint:prealloc
 
+	tags := make(map[string]string, len(candidates))
+
 	for _, candidate := range candidates {
-		version, err := semver.ParseTolerant(candidate)
-		if err != nil {
+		version, ok := tagParser(candidate)
+		if !ok {
 			continue // ignore invalid versions
 		}
 
+		// several tags might map to the same version (e.g. "v1.8.0" and "1.8"), prefer the canonical one
+		if tag, ok := tags[version.String()]; ok {
+			if candidate == "v"+version.String() {
+				tags[version.String()] = candidate
+			}
+
+			m.logger.Debug("duplicate Talos version tag", zap.String("tag", candidate), zap.String("duplicate_of", tag))
+
+			continue
+		}
+
 		versions = append(versions, version)
+		tags[version.String()] = candidate
 	}
 
 	// allow non-prerelease versions, and allow pre-release for the "latest" release (maxVersion)
@@ -52,6 +135,14 @@ func (m *Manager) fetchTalosVersions() (any, error) {
 			return false // ignore versions below minimum
 		}
 
+		if !m.options.MaxVersion.Equals(semver.Version{}) && version.GT(m.options.MaxVersion) {
+			return false // ignore versions above maximum
+		}
+
+		if !m.versionAllowed(version) {
+			return false // ignore versions not in the allowlist
+		}
+
 		if len(version.Pre) > 0 && strings.Count(version.Pre[0].VersionStr, "-") > 1 {
 			return false // ignore hash pre-releases
 		}
@@ -59,12 +150,20 @@ func (m *Manager) fetchTalosVersions() (any, error) {
 		return true
 	})
 
-	slices.SortFunc(versions, semver.Version.Compare)
+	m.sortVersions(versions)
 
 	m.talosVersionsMu.Lock()
-	m.talosVersions, m.talosVersionsTimestamp = versions, time.Now()
+	previous, refreshed := m.talosVersions, !m.talosVersionsTimestamp.IsZero()
+	m.talosVersions, m.talosVersionsTimestamp = versions, m.now()
+	m.talosVersionTags = tags
+	m.talosVersionsGzip = nil
+	m.talosVersionsText = ""
 	m.talosVersionsMu.Unlock()
 
+	if refreshed {
+		m.prefetchNewVersions(newVersionTags(previous, versions, tags))
+	}
+
 	return nil, nil //nolint:nilnil
 }
 
@@ -100,10 +199,10 @@ type overlaysDescription struct {
 	Digest string `yaml:"digest"`
 }
 
-func (m *Manager) fetchOfficialExtensions(tag string) error {
+func (m *Manager) fetchOfficialExtensions(ctx context.Context, tag string) error {
 	var extensions []ExtensionRef
 
-	if err := m.fetchImageByTag(ExtensionManifestImage, tag, ArchArm64, imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
+	if err := m.fetchImageByTag(ctx, ExtensionManifestImage, tag, ArchArm64, imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
 		var extractErr error
 
 		extensions, extractErr = extractExtensionList(r)
@@ -130,10 +229,10 @@ func (m *Manager) fetchOfficialExtensions(tag string) error {
 	return nil
 }
 
-func (m *Manager) fetchOfficialOverlays(tag string) error {
+func (m *Manager) fetchOfficialOverlays(ctx context.Context, tag string) error {
 	var overlays []OverlayRef
 
-	if err := m.fetchImageByTag(OverlayManifestImage, tag, ArchAmd64, imageExportHandler(func(_ *zap.Logger, r io.Reader) error {
+	if err := m.fetchImageByTag(ctx, OverlayManifestImage, tag, ArchAmd64, imageExportHandler(func(_ *zap.Logger, r io.Reader) error {
 		var extractErr error
 
 		overlays, extractErr = extractOverlayList(r)
@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestTagParser(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		TagParser: func(tag string) (semver.Version, bool) {
+			versionStr, ok := strings.CutPrefix(tag, "fork-")
+			if !ok {
+				return semver.Version{}, false
+			}
+
+			version, err := semver.Parse(versionStr)
+
+			return version, err == nil
+		},
+	})
+
+	for _, tag := range []string{"fork-1.7.0", "v1.8.0"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	versions, err := manager.GetTalosVersions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []semver.Version{semver.MustParse("1.7.0")}, versions)
+
+	path, err := manager.Get(ctx, "v1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel fork-1.7.0", string(contents))
+
+	_, err = manager.Get(ctx, "1.8.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	assert.True(t, xerrors.TagIs[artifacts.ErrNotFoundTag](err))
+}
+
+func TestResolveLatestPatch(t *testing.T) {
+	available := []semver.Version{
+		semver.MustParse("1.8.1"),
+		semver.MustParse("1.8.0"),
+		semver.MustParse("1.7.6"),
+		semver.MustParse("1.7.10"),
+		semver.MustParse("1.7.11-alpha.0"),
+	}
+
+	for _, test := range []struct {
+		versionString string
+		expected      string
+	}{
+		{versionString: "1.7", expected: "1.7.10"},
+		{versionString: "v1.7", expected: "1.7.10"},
+		{versionString: "1.7.x", expected: "1.7.10"},
+		{versionString: "v1.8.*", expected: "1.8.1"},
+		{versionString: "1.7.6"},
+		{versionString: "1.9"},
+		{versionString: "1.x"},
+	} {
+		t.Run(test.versionString, func(t *testing.T) {
+			version, ok := artifacts.ResolveLatestPatch(test.versionString, available)
+
+			if test.expected == "" {
+				assert.False(t, ok)
+
+				return
+			}
+
+			require.True(t, ok)
+			assert.Equal(t, test.expected, version.String())
+		})
+	}
+}
+
+func TestVersionResolver(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	manager, registryHost := setupManager(t, artifacts.Options{
+		VersionResolver: artifacts.ResolveLatestPatch,
+	})
+
+	for _, tag := range []string{"v1.7.0", "v1.7.1"} {
+		pushImage(t, registryHost, artifacts.ImagerImage, tag, map[string][]byte{
+			"usr/install/amd64/vmlinuz": []byte("kernel " + tag),
+		})
+	}
+
+	for _, versionString := range []string{"1.7", "v1.7.x"} {
+		path, err := manager.Get(ctx, versionString, artifacts.ArchAmd64, artifacts.KindKernel)
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kernel v1.7.1", string(contents))
+
+		// the front-ends normalize the version the same way
+		tag, err := manager.NormalizeVersion(ctx, versionString)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.7.1", tag)
+	}
+
+	path, err := manager.Get(ctx, "1.7.0", artifacts.ArchAmd64, artifacts.KindKernel)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel v1.7.0", string(contents))
+}
@@ -8,6 +8,8 @@ package asset
 import (
 	"context"
 	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -135,24 +137,36 @@ func NewBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager, options
 	}, nil
 }
 
-func (b *Builder) getBuildAsset(ctx context.Context, versionString, arch string, kind artifacts.Kind, out *profile.FileAsset) error {
-	var err error
+// getBuildAsset holds the artifact in the storage until the release is called, so that it's not removed during the build.
+func (b *Builder) getBuildAsset(ctx context.Context, versionString, arch string, kind artifacts.Kind, out *profile.FileAsset, releases *[]func()) error {
+	path, release, err := b.artifactsManager.Acquire(ctx, versionString, artifacts.Arch(arch), kind)
+	if err != nil {
+		return err
+	}
 
-	out.Path, err = b.artifactsManager.Get(ctx, versionString, artifacts.Arch(arch), kind)
+	out.Path = path
+	*releases = append(*releases, release)
 
-	return err
+	return nil
 }
 
 // Build the asset.
 //
 // First, check if the asset has already been built and cached then use the cached version.
 // If the asset hasn't been built yet, build it and cache it honoring the concurrency limit, and push it to the cache.
+//
+// The assets built with a registry override (see artifacts.WithRegistryOverride) are cached separately for each registry.
 func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString string) (BootAsset, error) {
 	profileHash, err := factoryprofile.Hash(prof)
 	if err != nil {
 		return nil, err
 	}
 
+	registry := artifacts.RegistryOverride(ctx)
+	if registry != "" {
+		profileHash = registryHash(profileHash, registry)
+	}
+
 	asset, err := b.cache.Get(ctx, profileHash)
 	if err == nil {
 		b.metricAssetsCached.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Inc()
@@ -167,7 +181,7 @@ func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString
 
 	// nothing in cache, so build the asset, but make sure we do it only once
 	ch := b.sf.DoChan(profileHash, func() (any, error) { //nolint:contextcheck
-		return b.buildAndCache(profileHash, prof, versionString)
+		return b.buildAndCache(profileHash, prof, versionString, registry)
 	})
 
 	select {
@@ -190,10 +204,17 @@ func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString
 	}
 }
 
+// registryHash mixes the registry override into the profile hash.
+func registryHash(profileHash, registry string) string {
+	hash := sha256.Sum256([]byte(profileHash + "\x00" + registry))
+
+	return hex.EncodeToString(hash[:])
+}
+
 // buildAndCache builds the asset and pushes it to the cache.
-func (b *Builder) buildAndCache(profileHash string, prof profile.Profile, versionString string) (BootAsset, error) {
+func (b *Builder) buildAndCache(profileHash string, prof profile.Profile, versionString, registry string) (BootAsset, error) {
 	// detach the context to make sure the asset is built no matter if the request is canceled
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	ctx, cancel := context.WithTimeout(artifacts.WithRegistryOverride(context.Background(), registry), 20*time.Minute)
 	defer cancel()
 
 	asset, err := b.build(ctx, prof, versionString)
@@ -232,34 +253,42 @@ func (b *Builder) build(ctx context.Context, prof profile.Profile, versionString
 	b.logger.Info("building image asset", zap.Any("profile", prof), zap.String("version", versionString), zap.Duration("concurrency_latency", concurrencyLatency))
 	b.metricConcurrencyLatency.Observe(concurrencyLatency.Seconds())
 
-	if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindKernel, &prof.Input.Kernel); err != nil {
+	var releases []func()
+
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+
+	if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindKernel, &prof.Input.Kernel, &releases); err != nil {
 		return nil, fmt.Errorf("failed to get kernel: %w", err)
 	}
 
-	if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindInitramfs, &prof.Input.Initramfs); err != nil {
+	if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindInitramfs, &prof.Input.Initramfs, &releases); err != nil {
 		return nil, fmt.Errorf("failed to get initramfs: %w", err)
 	}
 
 	if prof.SecureBootEnabled() {
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdBoot, &prof.Input.SDBoot); err != nil {
+		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdBoot, &prof.Input.SDBoot, &releases); err != nil {
 			return nil, fmt.Errorf("failed to get systemd-boot: %w", err)
 		}
 
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdStub, &prof.Input.SDStub); err != nil {
+		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdStub, &prof.Input.SDStub, &releases); err != nil {
 			return nil, fmt.Errorf("failed to get systemd-stub: %w", err)
 		}
 	}
 
 	if prof.Arch == string(artifacts.ArchArm64) && !quirks.New(versionString).SupportsOverlay() {
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindDTB, &prof.Input.DTB); err != nil {
+		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindDTB, &prof.Input.DTB, &releases); err != nil {
 			return nil, fmt.Errorf("failed to get dtb: %w", err)
 		}
 
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindUBoot, &prof.Input.UBoot); err != nil {
+		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindUBoot, &prof.Input.UBoot, &releases); err != nil {
 			return nil, fmt.Errorf("failed to get u-boot: %w", err)
 		}
 
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindRPiFirmware, &prof.Input.RPiFirmware); err != nil {
+		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindRPiFirmware, &prof.Input.RPiFirmware, &releases); err != nil {
 			return nil, fmt.Errorf("failed to get rpi firmware: %w", err)
 		}
 	}
@@ -54,6 +54,7 @@ type Frontend struct {
 type Options struct {
 	ExternalURL    *url.URL
 	ExternalPXEURL *url.URL
+	PublicBaseURL  *url.URL
 
 	InstallerInternalRepository name.Repository
 	InstallerExternalRepository name.Repository
@@ -61,6 +62,8 @@ type Options struct {
 	CacheSigningKey crypto.PrivateKey
 
 	RemoteOptions []remote.Option
+
+	RegistryOverrideHeader string
 }
 
 // NewFrontend creates a new HTTP frontend.
@@ -159,6 +162,11 @@ func (f *Frontend) wrapper(h func(ctx context.Context, w http.ResponseWriter, r
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		ctx := r.Context()
 
+		// the registry override is selected per tenant by the proxy in front of the factory
+		if registry := r.Header.Get(f.options.RegistryOverrideHeader); f.options.RegistryOverrideHeader != "" && registry != "" {
+			ctx = artifacts.WithRegistryOverride(ctx, registry)
+		}
+
 		err := h(ctx, w, r, p)
 
 		f.logger.Info("request", zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
@@ -169,8 +177,11 @@ func (f *Frontend) wrapper(h func(ctx context.Context, w http.ResponseWriter, r
 		case xerrors.TagIs[storage.ErrNotFoundTag](err):
 			http.Error(w, err.Error(), http.StatusNotFound)
 		case xerrors.TagIs[profile.InvalidErrorTag](err),
-			xerrors.TagIs[schematicpkg.InvalidErrorTag](err):
+			xerrors.TagIs[schematicpkg.InvalidErrorTag](err),
+			xerrors.TagIs[artifacts.InvalidVersionErrorTag](err):
 			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, artifacts.ErrRegistryOverrideNotAllowed):
+			http.Error(w, err.Error(), http.StatusForbidden)
 		case errors.Is(err, context.Canceled):
 			// client closed connection
 		default:
@@ -7,8 +7,9 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/blang/semver/v4"
@@ -21,7 +22,38 @@ import (
 )
 
 // handleVersions handles list of Talos versions available.
-func (f *Frontend) handleVersions(ctx context.Context, w http.ResponseWriter, _ *http.Request, _ httprouter.Params) error {
+//
+// With "?format=text", the list is returned as plain text, one version per line.
+func (f *Frontend) handleVersions(ctx context.Context, w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	if r.URL.Query().Get("format") == "text" {
+		versionsText, err := f.artifactsManager.GetTalosVersionsText(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		_, err = io.WriteString(w, versionsText)
+
+		return err
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if acceptsGzip(r.Header.Values("Accept-Encoding")) {
+		versionsGzip, err := f.artifactsManager.GetTalosVersionsGzip(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(versionsGzip)))
+
+		_, err = w.Write(versionsGzip)
+
+		return err
+	}
+
 	versions, err := f.artifactsManager.GetTalosVersions(ctx)
 	if err != nil {
 		return err
@@ -34,19 +66,56 @@ func (f *Frontend) handleVersions(ctx context.Context, w http.ResponseWriter, _
 	)
 }
 
-// handleOfficialExtensions handles list of available official extensions per Talos version.
-func (f *Frontend) handleOfficialExtensions(ctx context.Context, w http.ResponseWriter, _ *http.Request, p httprouter.Params) error {
-	versionTag := p.ByName("version")
-	if !strings.HasPrefix(versionTag, "v") {
-		versionTag = "v" + versionTag
+// acceptsGzip checks whether the Accept-Encoding header values allow the gzip content coding.
+//
+// The coding is allowed if it's listed (or matched by "*") with a non-zero quality value, e.g. "gzip;q=0" disallows it.
+func acceptsGzip(values []string) bool {
+	var gzipQuality, wildcardQuality float64 = -1, -1
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(element, ";")
+
+			quality := 1.0
+
+			for _, param := range strings.Split(params, ";") {
+				key, val, ok := strings.Cut(param, "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+					continue
+				}
+
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+				if err != nil {
+					parsed = 0
+				}
+
+				quality = parsed
+			}
+
+			switch strings.ToLower(strings.TrimSpace(coding)) {
+			case "gzip", "x-gzip":
+				gzipQuality = quality
+			case "*":
+				wildcardQuality = quality
+			}
+		}
+	}
+
+	if gzipQuality >= 0 {
+		return gzipQuality > 0
 	}
 
-	version, err := semver.Parse(versionTag[1:])
+	return wildcardQuality > 0
+}
+
+// handleOfficialExtensions handles list of available official extensions per Talos version.
+func (f *Frontend) handleOfficialExtensions(ctx context.Context, w http.ResponseWriter, _ *http.Request, p httprouter.Params) error {
+	versionTag, err := f.artifactsManager.NormalizeVersion(ctx, p.ByName("version"))
 	if err != nil {
-		return fmt.Errorf("error parsing version: %w", err)
+		return err
 	}
 
-	extensions, err := f.artifactsManager.GetOfficialExtensions(ctx, version.String())
+	extensions, err := f.artifactsManager.GetOfficialExtensions(ctx, versionTag)
 	if err != nil {
 		return err
 	}
@@ -66,21 +135,16 @@ func (f *Frontend) handleOfficialExtensions(ctx context.Context, w http.Response
 
 // handleOfficialOverlays handles list of available official overlays per Talos version.
 func (f *Frontend) handleOfficialOverlays(ctx context.Context, w http.ResponseWriter, _ *http.Request, p httprouter.Params) error {
-	versionTag := p.ByName("version")
-	if !strings.HasPrefix(versionTag, "v") {
-		versionTag = "v" + versionTag
-	}
-
-	version, err := semver.Parse(versionTag[1:])
+	versionTag, err := f.artifactsManager.NormalizeVersion(ctx, p.ByName("version"))
 	if err != nil {
-		return fmt.Errorf("error parsing version: %w", err)
+		return err
 	}
 
-	if !quirks.New(version.String()).SupportsOverlay() {
+	if !quirks.New(versionTag).SupportsOverlay() {
 		return json.NewEncoder(w).Encode([]client.OverlayInfo{})
 	}
 
-	overlays, err := f.artifactsManager.GetOfficialOverlays(ctx, version.String())
+	overlays, err := f.artifactsManager.GetOfficialOverlays(ctx, versionTag)
 	if err != nil {
 		return err
 	}
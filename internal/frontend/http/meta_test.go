@@ -0,0 +1,35 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		values   []string
+		expected bool
+	}{
+		{name: "none"},
+		{name: "gzip", values: []string{"gzip"}, expected: true},
+		{name: "list", values: []string{"deflate, gzip, br"}, expected: true},
+		{name: "multiple headers", values: []string{"br", "GZIP"}, expected: true},
+		{name: "quality", values: []string{"gzip;q=0.5"}, expected: true},
+		{name: "disallowed", values: []string{"gzip;q=0, identity"}},
+		{name: "disallowed with spaces", values: []string{"gzip ; q = 0.000"}},
+		{name: "wildcard", values: []string{"*"}, expected: true},
+		{name: "wildcard disallowed", values: []string{"*;q=0"}},
+		{name: "wildcard with gzip disallowed", values: []string{"*, gzip;q=0"}},
+		{name: "prefix", values: []string{"gzipped"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, acceptsGzip(test.values))
+		})
+	}
+}
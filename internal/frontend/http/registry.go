@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
@@ -87,7 +89,11 @@ func (f *Frontend) handleBlob(ctx context.Context, w http.ResponseWriter, _ *htt
 	redirectURL.Scheme = f.options.InstallerExternalRepository.Scheme()
 	redirectURL.Host = f.options.InstallerExternalRepository.Registry.Name()
 
-	location := redirectURL.JoinPath("v2", f.options.InstallerExternalRepository.RepositoryStr(), img.Name(), schematicID, "blobs", digest).String()
+	location := redirectURL.JoinPath(slices.Concat(
+		[]string{"v2", f.options.InstallerExternalRepository.RepositoryStr()},
+		installerPath(ctx, img.Name(), schematicID),
+		[]string{"blobs", digest},
+	)...).String()
 
 	f.logger.Info("redirecting blob", zap.String("location", location))
 
@@ -97,13 +103,33 @@ func (f *Frontend) handleBlob(ctx context.Context, w http.ResponseWriter, _ *htt
 	return nil
 }
 
-func (f *Frontend) redirectToExternalRegistry(w http.ResponseWriter, imageName, schematicID, tagOrDigest string) error {
+// installerPath returns the path of the installer image under the installer repositories.
+//
+// The installer images built with a registry override are kept separately for each registry.
+func installerPath(ctx context.Context, imageName, schematicID string) []string {
+	if registry := artifacts.RegistryOverride(ctx); registry != "" {
+		return []string{"registries", strings.ReplaceAll(registry, ":", "-"), imageName, schematicID}
+	}
+
+	return []string{imageName, schematicID}
+}
+
+// installerRepository returns the repository of the installer image under the installer repository.
+func installerRepository(ctx context.Context, repository name.Repository, imageName, schematicID string) name.Repository {
+	return repository.Repo(append([]string{repository.RepositoryStr()}, installerPath(ctx, imageName, schematicID)...)...)
+}
+
+func (f *Frontend) redirectToExternalRegistry(ctx context.Context, w http.ResponseWriter, imageName, schematicID, tagOrDigest string) error {
 	var redirectURL url.URL
 
 	redirectURL.Scheme = f.options.InstallerExternalRepository.Scheme()
 	redirectURL.Host = f.options.InstallerExternalRepository.Registry.Name()
 
-	location := redirectURL.JoinPath("v2", f.options.InstallerExternalRepository.RepositoryStr(), imageName, schematicID, "manifests", tagOrDigest).String()
+	location := redirectURL.JoinPath(slices.Concat(
+		[]string{"v2", f.options.InstallerExternalRepository.RepositoryStr()},
+		installerPath(ctx, imageName, schematicID),
+		[]string{"manifests", tagOrDigest},
+	)...).String()
 
 	f.logger.Info("redirecting manifest", zap.String("location", location))
 
@@ -133,14 +159,10 @@ func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _
 
 	// if the tag is the digest, or it doesn't look like the version, we just redirect to the external registry
 	if strings.HasPrefix(versionTag, "sha256:") || !strings.HasPrefix(versionTag, "v") {
-		return f.redirectToExternalRegistry(w, img.Name(), schematicID, versionTag)
+		return f.redirectToExternalRegistry(ctx, w, img.Name(), schematicID, versionTag)
 	}
 
-	imageRepository := f.options.InstallerInternalRepository.Repo(
-		f.options.InstallerInternalRepository.RepositoryStr(),
-		img.Name(),
-		schematicID,
-	)
+	imageRepository := installerRepository(ctx, f.options.InstallerInternalRepository, img.Name(), schematicID)
 
 	// check if the asset has already been built
 	f.logger.Info("heading installer image",
@@ -170,7 +192,7 @@ func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _
 		)
 		if signatureErr == nil {
 			// redirect to the external registry, but use the digest directly to avoid tag changes
-			return f.redirectToExternalRegistry(w, img.Name(), schematicID, extDesc.Digest.String())
+			return f.redirectToExternalRegistry(ctx, w, img.Name(), schematicID, extDesc.Digest.String())
 		}
 
 		// log the signature verification error, but continue to build the image
@@ -194,11 +216,12 @@ func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _
 	}
 
 	// build installer images for each architecture, combine them into a single index and push it
-	key := fmt.Sprintf("%s-%s-%s", img.Name(), schematicID, versionTag)
+	registry := artifacts.RegistryOverride(ctx)
+	key := fmt.Sprintf("%s-%s-%s-%s", img.Name(), schematicID, versionTag, registry)
 
 	resultCh := f.sf.DoChan(key, func() (any, error) { //nolint:contextcheck
 		// we use here detached context to make sure image is built no matter if the request is canceled
-		return f.buildInstallImage(context.Background(), img, schematic, version, schematicID, versionTag)
+		return f.buildInstallImage(artifacts.WithRegistryOverride(context.Background(), registry), img, schematic, version, schematicID, versionTag)
 	})
 
 	var res singleflight.Result
@@ -219,7 +242,7 @@ func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _
 	}
 
 	// now we can redirect to the external registry
-	return f.redirectToExternalRegistry(w, img.Name(), schematicID, manifestHash.String())
+	return f.redirectToExternalRegistry(ctx, w, img.Name(), schematicID, manifestHash.String())
 }
 
 func (f *Frontend) buildInstallImage(ctx context.Context, img requestedImage, schematic *schematic.Schematic, version semver.Version, schematicID, versionTag string) (v1.Hash, error) {
@@ -267,11 +290,7 @@ func (f *Frontend) buildInstallImage(ctx context.Context, img requestedImage, sc
 
 	f.logger.Info("pushing installer image", zap.String("image", img.Name()), zap.String("schematic", schematicID), zap.String("version", versionTag))
 
-	installerRepo := f.options.InstallerInternalRepository.Repo(
-		f.options.InstallerInternalRepository.RepositoryStr(),
-		img.Name(),
-		schematicID,
-	)
+	installerRepo := installerRepository(ctx, f.options.InstallerInternalRepository, img.Name(), schematicID)
 
 	if err := f.pusher.Push(
 		ctx,
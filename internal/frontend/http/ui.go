@@ -45,9 +45,11 @@ func (f *Frontend) handleUI(_ context.Context, w http.ResponseWriter, r *http.Re
 	}
 
 	return templates.ExecuteTemplate(w, "index.html", struct {
-		Version string
+		Version  string
+		BasePath string
 	}{
-		Version: version.Tag,
+		Version:  version.Tag,
+		BasePath: f.basePath(),
 	})
 }
 
@@ -62,12 +64,15 @@ func (f *Frontend) handleUIVersions(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
+	// newest first, regardless of the configured sort order
 	versions = slices.Clone(versions)
-	slices.Reverse(versions)
+	slices.SortStableFunc(versions, func(a, b semver.Version) int { return b.Compare(a) })
 
 	return templates.ExecuteTemplate(w, "versions.html", struct {
+		BasePath string
 		Versions []semver.Version
 	}{
+		BasePath: f.basePath(),
 		Versions: versions,
 	})
 }
@@ -103,9 +108,11 @@ func (f *Frontend) handleUISchematicConfig(ctx context.Context, w http.ResponseW
 	}
 
 	return templates.ExecuteTemplate(w, "schematic-config.html", struct {
+		BasePath   string
 		Extensions []artifacts.ExtensionRef
 		Overlays   []artifacts.OverlayRef
 	}{
+		BasePath:   f.basePath(),
 		Extensions: extensions,
 		Overlays:   overlays,
 	})
@@ -187,6 +194,7 @@ func (f *Frontend) handleUISchematics(ctx context.Context, w http.ResponseWriter
 	version := "v" + versionParam
 
 	return templates.ExecuteTemplate(w, "schematic.html", struct {
+		BasePath  string
 		Version   string
 		Schematic string
 		Marshaled string
@@ -198,10 +206,11 @@ func (f *Frontend) handleUISchematics(ctx context.Context, w http.ResponseWriter
 
 		Architectures []string
 	}{
+		BasePath:                 f.basePath(),
 		Version:                  version,
 		Schematic:                schematicID,
 		Marshaled:                string(marshaled),
-		ImageBaseURL:             f.options.ExternalURL.JoinPath("image", schematicID, version),
+		ImageBaseURL:             f.publicURL().JoinPath("image", schematicID, version),
 		PXEBaseURL:               f.options.ExternalPXEURL.JoinPath("pxe", schematicID, version),
 		InstallerImage:           fmt.Sprintf("%s/installer/%s:%s", f.options.ExternalURL.Host, schematicID, version),
 		SecureBootInstallerImage: fmt.Sprintf("%s/installer-secureboot/%s:%s", f.options.ExternalURL.Host, schematicID, version),
@@ -211,3 +220,18 @@ func (f *Frontend) handleUISchematics(ctx context.Context, w http.ResponseWriter
 		},
 	})
 }
+
+// basePath returns the path prefix of the public URL (without the trailing slash),
+// so that the links in the UI work when the frontend is served behind a reverse proxy under a path prefix.
+func (f *Frontend) basePath() string {
+	return strings.TrimSuffix(f.publicURL().Path, "/")
+}
+
+// publicURL returns the base URL of the user-facing links: Options.PublicBaseURL, or the external URL if it's not set.
+func (f *Frontend) publicURL() *url.URL {
+	if f.options.PublicBaseURL != nil {
+		return f.options.PublicBaseURL
+	}
+
+	return f.options.ExternalURL
+}
@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicBaseURL(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		externalURL   string
+		publicBaseURL string
+
+		expectedBasePath string
+		expectedImageURL string
+	}{
+		{
+			name:             "external URL",
+			externalURL:      "https://factory.example.com/",
+			expectedBasePath: "",
+			expectedImageURL: "https://factory.example.com/image/schematic/v1.7.0",
+		},
+		{
+			name:             "external URL with path prefix",
+			externalURL:      "https://example.com/factory/",
+			expectedBasePath: "/factory",
+			expectedImageURL: "https://example.com/factory/image/schematic/v1.7.0",
+		},
+		{
+			name:             "public base URL",
+			externalURL:      "https://factory.internal/",
+			publicBaseURL:    "https://example.com/factory",
+			expectedBasePath: "/factory",
+			expectedImageURL: "https://example.com/factory/image/schematic/v1.7.0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				f   Frontend
+				err error
+			)
+
+			f.options.ExternalURL, err = url.Parse(test.externalURL)
+			require.NoError(t, err)
+
+			if test.publicBaseURL != "" {
+				f.options.PublicBaseURL, err = url.Parse(test.publicBaseURL)
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, test.expectedBasePath, f.basePath())
+			assert.Equal(t, test.expectedImageURL, f.publicURL().JoinPath("image", "schematic", "v1.7.0").String())
+		})
+	}
+}
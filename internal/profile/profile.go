@@ -251,6 +251,7 @@ type ArtifactProducer interface {
 	GetExtensionImage(context.Context, artifacts.Arch, artifacts.ExtensionRef) (string, error)
 	GetOverlayImage(context.Context, artifacts.Arch, artifacts.OverlayRef) (string, error)
 	GetInstallerImage(context.Context, artifacts.Arch, string) (string, error)
+	CheckRequiredExtensions(context.Context, string, []string) error
 }
 
 // EnhanceFromSchematic enhances the profile with the schematic.
@@ -293,6 +294,14 @@ func EnhanceFromSchematic(
 	}
 
 	if prof.Output.Kind != profile.OutKindCmdline && prof.Output.Kind != profile.OutKindKernel {
+		if err := artifactProducer.CheckRequiredExtensions(ctx, versionTag, schematic.Customization.SystemExtensions.OfficialExtensions); err != nil {
+			if errors.Is(err, artifacts.ErrMissingRequiredExtension) {
+				return prof, xerrors.NewTagged[InvalidErrorTag](err)
+			}
+
+			return prof, fmt.Errorf("error checking required extensions: %w", err)
+		}
+
 		if len(schematic.Customization.SystemExtensions.OfficialExtensions) > 0 {
 			availableExtensions, err := artifactProducer.GetOfficialExtensions(ctx, versionTag)
 			if err != nil {
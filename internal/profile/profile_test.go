@@ -319,6 +319,10 @@ func (mockArtifactProducer) GetInstallerImage(_ context.Context, arch artifacts.
 	return fmt.Sprintf("installer-%s-%s.oci", arch, tag), nil
 }
 
+func (mockArtifactProducer) CheckRequiredExtensions(context.Context, string, []string) error {
+	return nil
+}
+
 //nolint:maintidx
 func TestEnhanceFromSchematic(t *testing.T) {
 	t.Parallel()